@@ -0,0 +1,22 @@
+package api
+
+import (
+	_ "embed"
+	"io/fs"
+
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// Spec is the OpenAPI specification of the Network Administration API.
+//
+//go:embed openapi.yml
+var Spec []byte
+
+// SwaggerUI is a Swagger UI page rendering the specification served at /openapi.yml. It loads Swagger UI from
+// SwaggerUIAssets served at /docs/, so that it works without access to the internet.
+//
+//go:embed swagger-ui.html
+var SwaggerUI []byte
+
+// SwaggerUIAssets are the files of the swagger-ui-dist package, embedded by github.com/swaggo/files/v2.
+var SwaggerUIAssets fs.FS = swaggerfiles.FS
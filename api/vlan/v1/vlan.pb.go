@@ -0,0 +1,725 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/vlan/v1/vlan.proto
+
+// The VLAN service mirrors the /api/v1/vlans REST endpoints.
+
+package vlanv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED EventType = 0
+	EventType_EVENT_TYPE_CREATED     EventType = 1
+	EventType_EVENT_TYPE_UPDATED     EventType = 2
+	EventType_EVENT_TYPE_DELETED     EventType = 3
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_CREATED",
+		2: "EVENT_TYPE_UPDATED",
+		3: "EVENT_TYPE_DELETED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED": 0,
+		"EVENT_TYPE_CREATED":     1,
+		"EVENT_TYPE_UPDATED":     2,
+		"EVENT_TYPE_DELETED":     3,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_vlan_v1_vlan_proto_enumTypes[0].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_api_vlan_v1_vlan_proto_enumTypes[0]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{0}
+}
+
+type VLAN struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// UUID
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// 1..4094
+	Vid  uint32 `protobuf:"varint,2,opt,name=vid,proto3" json:"vid,omitempty"`
+	Name string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// CIDR, e.g. 192.168.0.0/24
+	Subnet string `protobuf:"bytes,4,opt,name=subnet,proto3" json:"subnet,omitempty"`
+	// IP address within the subnet
+	Gateway string `protobuf:"bytes,5,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	Status  string `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	// Component that owns the VLAN, ignored in requests.
+	ManagedBy string `protobuf:"bytes,7,opt,name=managed_by,json=managedBy,proto3" json:"managed_by,omitempty"`
+	// Name of the VRF, empty for the global routing table.
+	Vrf  string   `protobuf:"bytes,8,opt,name=vrf,proto3" json:"vrf,omitempty"`
+	Tags []string `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Values of custom fields: numbers for int fields, booleans for bool fields and strings otherwise.
+	Fields        *structpb.Struct `protobuf:"bytes,10,opt,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VLAN) Reset() {
+	*x = VLAN{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VLAN) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VLAN) ProtoMessage() {}
+
+func (x *VLAN) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VLAN.ProtoReflect.Descriptor instead.
+func (*VLAN) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *VLAN) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *VLAN) GetVid() uint32 {
+	if x != nil {
+		return x.Vid
+	}
+	return 0
+}
+
+func (x *VLAN) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *VLAN) GetSubnet() string {
+	if x != nil {
+		return x.Subnet
+	}
+	return ""
+}
+
+func (x *VLAN) GetGateway() string {
+	if x != nil {
+		return x.Gateway
+	}
+	return ""
+}
+
+func (x *VLAN) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *VLAN) GetManagedBy() string {
+	if x != nil {
+		return x.ManagedBy
+	}
+	return ""
+}
+
+func (x *VLAN) GetVrf() string {
+	if x != nil {
+		return x.Vrf
+	}
+	return ""
+}
+
+func (x *VLAN) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *VLAN) GetFields() *structpb.Struct {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type ListVLANsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVLANsRequest) Reset() {
+	*x = ListVLANsRequest{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVLANsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVLANsRequest) ProtoMessage() {}
+
+func (x *ListVLANsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVLANsRequest.ProtoReflect.Descriptor instead.
+func (*ListVLANsRequest) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{1}
+}
+
+type ListVLANsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vlans         []*VLAN                `protobuf:"bytes,1,rep,name=vlans,proto3" json:"vlans,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVLANsResponse) Reset() {
+	*x = ListVLANsResponse{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVLANsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVLANsResponse) ProtoMessage() {}
+
+func (x *ListVLANsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVLANsResponse.ProtoReflect.Descriptor instead.
+func (*ListVLANsResponse) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListVLANsResponse) GetVlans() []*VLAN {
+	if x != nil {
+		return x.Vlans
+	}
+	return nil
+}
+
+type GetVLANRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVLANRequest) Reset() {
+	*x = GetVLANRequest{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVLANRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVLANRequest) ProtoMessage() {}
+
+func (x *GetVLANRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVLANRequest.ProtoReflect.Descriptor instead.
+func (*GetVLANRequest) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetVLANRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CreateVLANRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vlan          *VLAN                  `protobuf:"bytes,1,opt,name=vlan,proto3" json:"vlan,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateVLANRequest) Reset() {
+	*x = CreateVLANRequest{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateVLANRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateVLANRequest) ProtoMessage() {}
+
+func (x *CreateVLANRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateVLANRequest.ProtoReflect.Descriptor instead.
+func (*CreateVLANRequest) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateVLANRequest) GetVlan() *VLAN {
+	if x != nil {
+		return x.Vlan
+	}
+	return nil
+}
+
+type UpdateVLANRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The VLAN to replace, identified by its id.
+	Vlan          *VLAN `protobuf:"bytes,1,opt,name=vlan,proto3" json:"vlan,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateVLANRequest) Reset() {
+	*x = UpdateVLANRequest{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateVLANRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateVLANRequest) ProtoMessage() {}
+
+func (x *UpdateVLANRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateVLANRequest.ProtoReflect.Descriptor instead.
+func (*UpdateVLANRequest) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateVLANRequest) GetVlan() *VLAN {
+	if x != nil {
+		return x.Vlan
+	}
+	return nil
+}
+
+type DeleteVLANRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteVLANRequest) Reset() {
+	*x = DeleteVLANRequest{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteVLANRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteVLANRequest) ProtoMessage() {}
+
+func (x *DeleteVLANRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteVLANRequest.ProtoReflect.Descriptor instead.
+func (*DeleteVLANRequest) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteVLANRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Resume after this event, 0 to receive only new events.
+	LastEventId uint64 `protobuf:"varint,1,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	// Epoch of the event to resume after.
+	Epoch         string `protobuf:"bytes,2,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchRequest) GetLastEventId() uint64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetEpoch() string {
+	if x != nil {
+		return x.Epoch
+	}
+	return ""
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Sequence number of the event, which restarts at 1 with a new epoch when the server restarts.
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          EventType              `protobuf:"varint,2,opt,name=type,proto3,enum=netadmin.vlan.v1.EventType" json:"type,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	Vlan          *VLAN                  `protobuf:"bytes,4,opt,name=vlan,proto3" json:"vlan,omitempty"`
+	Epoch         string                 `protobuf:"bytes,5,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_api_vlan_v1_vlan_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_api_vlan_v1_vlan_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Event) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Event) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetVlan() *VLAN {
+	if x != nil {
+		return x.Vlan
+	}
+	return nil
+}
+
+func (x *Event) GetEpoch() string {
+	if x != nil {
+		return x.Epoch
+	}
+	return ""
+}
+
+var File_api_vlan_v1_vlan_proto protoreflect.FileDescriptor
+
+const file_api_vlan_v1_vlan_proto_rawDesc = "" +
+	"\n" +
+	"\x16api/vlan/v1/vlan.proto\x12\x10netadmin.vlan.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfc\x01\n" +
+	"\x04VLAN\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03vid\x18\x02 \x01(\rR\x03vid\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x16\n" +
+	"\x06subnet\x18\x04 \x01(\tR\x06subnet\x12\x18\n" +
+	"\agateway\x18\x05 \x01(\tR\agateway\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"managed_by\x18\a \x01(\tR\tmanagedBy\x12\x10\n" +
+	"\x03vrf\x18\b \x01(\tR\x03vrf\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x12/\n" +
+	"\x06fields\x18\n" +
+	" \x01(\v2\x17.google.protobuf.StructR\x06fields\"\x12\n" +
+	"\x10ListVLANsRequest\"A\n" +
+	"\x11ListVLANsResponse\x12,\n" +
+	"\x05vlans\x18\x01 \x03(\v2\x16.netadmin.vlan.v1.VLANR\x05vlans\" \n" +
+	"\x0eGetVLANRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"?\n" +
+	"\x11CreateVLANRequest\x12*\n" +
+	"\x04vlan\x18\x01 \x01(\v2\x16.netadmin.vlan.v1.VLANR\x04vlan\"?\n" +
+	"\x11UpdateVLANRequest\x12*\n" +
+	"\x04vlan\x18\x01 \x01(\v2\x16.netadmin.vlan.v1.VLANR\x04vlan\"#\n" +
+	"\x11DeleteVLANRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"H\n" +
+	"\fWatchRequest\x12\"\n" +
+	"\rlast_event_id\x18\x01 \x01(\x04R\vlastEventId\x12\x14\n" +
+	"\x05epoch\x18\x02 \x01(\tR\x05epoch\"\xba\x01\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12/\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x1b.netadmin.vlan.v1.EventTypeR\x04type\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12*\n" +
+	"\x04vlan\x18\x04 \x01(\v2\x16.netadmin.vlan.v1.VLANR\x04vlan\x12\x14\n" +
+	"\x05epoch\x18\x05 \x01(\tR\x05epoch*o\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12EVENT_TYPE_CREATED\x10\x01\x12\x16\n" +
+	"\x12EVENT_TYPE_UPDATED\x10\x02\x12\x16\n" +
+	"\x12EVENT_TYPE_DELETED\x10\x032\xcd\x03\n" +
+	"\vVLANService\x12T\n" +
+	"\tListVLANs\x12\".netadmin.vlan.v1.ListVLANsRequest\x1a#.netadmin.vlan.v1.ListVLANsResponse\x12C\n" +
+	"\aGetVLAN\x12 .netadmin.vlan.v1.GetVLANRequest\x1a\x16.netadmin.vlan.v1.VLAN\x12I\n" +
+	"\n" +
+	"CreateVLAN\x12#.netadmin.vlan.v1.CreateVLANRequest\x1a\x16.netadmin.vlan.v1.VLAN\x12I\n" +
+	"\n" +
+	"UpdateVLAN\x12#.netadmin.vlan.v1.UpdateVLANRequest\x1a\x16.netadmin.vlan.v1.VLAN\x12I\n" +
+	"\n" +
+	"DeleteVLAN\x12#.netadmin.vlan.v1.DeleteVLANRequest\x1a\x16.google.protobuf.Empty\x12B\n" +
+	"\x05Watch\x12\x1e.netadmin.vlan.v1.WatchRequest\x1a\x17.netadmin.vlan.v1.Event0\x01B\"Z net-admin-api/api/vlan/v1;vlanv1b\x06proto3"
+
+var (
+	file_api_vlan_v1_vlan_proto_rawDescOnce sync.Once
+	file_api_vlan_v1_vlan_proto_rawDescData []byte
+)
+
+func file_api_vlan_v1_vlan_proto_rawDescGZIP() []byte {
+	file_api_vlan_v1_vlan_proto_rawDescOnce.Do(func() {
+		file_api_vlan_v1_vlan_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_vlan_v1_vlan_proto_rawDesc), len(file_api_vlan_v1_vlan_proto_rawDesc)))
+	})
+	return file_api_vlan_v1_vlan_proto_rawDescData
+}
+
+var file_api_vlan_v1_vlan_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_vlan_v1_vlan_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_api_vlan_v1_vlan_proto_goTypes = []any{
+	(EventType)(0),                // 0: netadmin.vlan.v1.EventType
+	(*VLAN)(nil),                  // 1: netadmin.vlan.v1.VLAN
+	(*ListVLANsRequest)(nil),      // 2: netadmin.vlan.v1.ListVLANsRequest
+	(*ListVLANsResponse)(nil),     // 3: netadmin.vlan.v1.ListVLANsResponse
+	(*GetVLANRequest)(nil),        // 4: netadmin.vlan.v1.GetVLANRequest
+	(*CreateVLANRequest)(nil),     // 5: netadmin.vlan.v1.CreateVLANRequest
+	(*UpdateVLANRequest)(nil),     // 6: netadmin.vlan.v1.UpdateVLANRequest
+	(*DeleteVLANRequest)(nil),     // 7: netadmin.vlan.v1.DeleteVLANRequest
+	(*WatchRequest)(nil),          // 8: netadmin.vlan.v1.WatchRequest
+	(*Event)(nil),                 // 9: netadmin.vlan.v1.Event
+	(*structpb.Struct)(nil),       // 10: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),         // 12: google.protobuf.Empty
+}
+var file_api_vlan_v1_vlan_proto_depIdxs = []int32{
+	10, // 0: netadmin.vlan.v1.VLAN.fields:type_name -> google.protobuf.Struct
+	1,  // 1: netadmin.vlan.v1.ListVLANsResponse.vlans:type_name -> netadmin.vlan.v1.VLAN
+	1,  // 2: netadmin.vlan.v1.CreateVLANRequest.vlan:type_name -> netadmin.vlan.v1.VLAN
+	1,  // 3: netadmin.vlan.v1.UpdateVLANRequest.vlan:type_name -> netadmin.vlan.v1.VLAN
+	0,  // 4: netadmin.vlan.v1.Event.type:type_name -> netadmin.vlan.v1.EventType
+	11, // 5: netadmin.vlan.v1.Event.time:type_name -> google.protobuf.Timestamp
+	1,  // 6: netadmin.vlan.v1.Event.vlan:type_name -> netadmin.vlan.v1.VLAN
+	2,  // 7: netadmin.vlan.v1.VLANService.ListVLANs:input_type -> netadmin.vlan.v1.ListVLANsRequest
+	4,  // 8: netadmin.vlan.v1.VLANService.GetVLAN:input_type -> netadmin.vlan.v1.GetVLANRequest
+	5,  // 9: netadmin.vlan.v1.VLANService.CreateVLAN:input_type -> netadmin.vlan.v1.CreateVLANRequest
+	6,  // 10: netadmin.vlan.v1.VLANService.UpdateVLAN:input_type -> netadmin.vlan.v1.UpdateVLANRequest
+	7,  // 11: netadmin.vlan.v1.VLANService.DeleteVLAN:input_type -> netadmin.vlan.v1.DeleteVLANRequest
+	8,  // 12: netadmin.vlan.v1.VLANService.Watch:input_type -> netadmin.vlan.v1.WatchRequest
+	3,  // 13: netadmin.vlan.v1.VLANService.ListVLANs:output_type -> netadmin.vlan.v1.ListVLANsResponse
+	1,  // 14: netadmin.vlan.v1.VLANService.GetVLAN:output_type -> netadmin.vlan.v1.VLAN
+	1,  // 15: netadmin.vlan.v1.VLANService.CreateVLAN:output_type -> netadmin.vlan.v1.VLAN
+	1,  // 16: netadmin.vlan.v1.VLANService.UpdateVLAN:output_type -> netadmin.vlan.v1.VLAN
+	12, // 17: netadmin.vlan.v1.VLANService.DeleteVLAN:output_type -> google.protobuf.Empty
+	9,  // 18: netadmin.vlan.v1.VLANService.Watch:output_type -> netadmin.vlan.v1.Event
+	13, // [13:19] is the sub-list for method output_type
+	7,  // [7:13] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_api_vlan_v1_vlan_proto_init() }
+func file_api_vlan_v1_vlan_proto_init() {
+	if File_api_vlan_v1_vlan_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_vlan_v1_vlan_proto_rawDesc), len(file_api_vlan_v1_vlan_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_vlan_v1_vlan_proto_goTypes,
+		DependencyIndexes: file_api_vlan_v1_vlan_proto_depIdxs,
+		EnumInfos:         file_api_vlan_v1_vlan_proto_enumTypes,
+		MessageInfos:      file_api_vlan_v1_vlan_proto_msgTypes,
+	}.Build()
+	File_api_vlan_v1_vlan_proto = out.File
+	file_api_vlan_v1_vlan_proto_goTypes = nil
+	file_api_vlan_v1_vlan_proto_depIdxs = nil
+}
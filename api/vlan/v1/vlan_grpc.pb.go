@@ -0,0 +1,336 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/vlan/v1/vlan.proto
+
+// The VLAN service mirrors the /api/v1/vlans REST endpoints.
+
+package vlanv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VLANService_ListVLANs_FullMethodName  = "/netadmin.vlan.v1.VLANService/ListVLANs"
+	VLANService_GetVLAN_FullMethodName    = "/netadmin.vlan.v1.VLANService/GetVLAN"
+	VLANService_CreateVLAN_FullMethodName = "/netadmin.vlan.v1.VLANService/CreateVLAN"
+	VLANService_UpdateVLAN_FullMethodName = "/netadmin.vlan.v1.VLANService/UpdateVLAN"
+	VLANService_DeleteVLAN_FullMethodName = "/netadmin.vlan.v1.VLANService/DeleteVLAN"
+	VLANService_Watch_FullMethodName      = "/netadmin.vlan.v1.VLANService/Watch"
+)
+
+// VLANServiceClient is the client API for VLANService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VLANServiceClient interface {
+	ListVLANs(ctx context.Context, in *ListVLANsRequest, opts ...grpc.CallOption) (*ListVLANsResponse, error)
+	// Fails with NOT_FOUND if the VLAN does not exist.
+	GetVLAN(ctx context.Context, in *GetVLANRequest, opts ...grpc.CallOption) (*VLAN, error)
+	// Fails with INVALID_ARGUMENT if the VLAN is not valid or its VRF does not exist, and with
+	// FAILED_PRECONDITION if its subnet overlaps another VLAN in the same VRF. The ID is assigned by the server.
+	CreateVLAN(ctx context.Context, in *CreateVLANRequest, opts ...grpc.CallOption) (*VLAN, error)
+	// Fails with FAILED_PRECONDITION if the VLAN is managed, e.g. by the reconciler, and otherwise like
+	// CreateVLAN.
+	UpdateVLAN(ctx context.Context, in *UpdateVLANRequest, opts ...grpc.CallOption) (*VLAN, error)
+	DeleteVLAN(ctx context.Context, in *DeleteVLANRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Streams VLAN changes until the client cancels, response headers are sent once the stream is
+	// subscribed. Fails with OUT_OF_RANGE if the changes after last_event_id are no longer available, or if
+	// epoch is not the current one because the server restarted, after which clients should list the VLANs
+	// again.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type vLANServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVLANServiceClient(cc grpc.ClientConnInterface) VLANServiceClient {
+	return &vLANServiceClient{cc}
+}
+
+func (c *vLANServiceClient) ListVLANs(ctx context.Context, in *ListVLANsRequest, opts ...grpc.CallOption) (*ListVLANsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListVLANsResponse)
+	err := c.cc.Invoke(ctx, VLANService_ListVLANs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vLANServiceClient) GetVLAN(ctx context.Context, in *GetVLANRequest, opts ...grpc.CallOption) (*VLAN, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VLAN)
+	err := c.cc.Invoke(ctx, VLANService_GetVLAN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vLANServiceClient) CreateVLAN(ctx context.Context, in *CreateVLANRequest, opts ...grpc.CallOption) (*VLAN, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VLAN)
+	err := c.cc.Invoke(ctx, VLANService_CreateVLAN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vLANServiceClient) UpdateVLAN(ctx context.Context, in *UpdateVLANRequest, opts ...grpc.CallOption) (*VLAN, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VLAN)
+	err := c.cc.Invoke(ctx, VLANService_UpdateVLAN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vLANServiceClient) DeleteVLAN(ctx context.Context, in *DeleteVLANRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, VLANService_DeleteVLAN_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vLANServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VLANService_ServiceDesc.Streams[0], VLANService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VLANService_WatchClient = grpc.ServerStreamingClient[Event]
+
+// VLANServiceServer is the server API for VLANService service.
+// All implementations must embed UnimplementedVLANServiceServer
+// for forward compatibility.
+type VLANServiceServer interface {
+	ListVLANs(context.Context, *ListVLANsRequest) (*ListVLANsResponse, error)
+	// Fails with NOT_FOUND if the VLAN does not exist.
+	GetVLAN(context.Context, *GetVLANRequest) (*VLAN, error)
+	// Fails with INVALID_ARGUMENT if the VLAN is not valid or its VRF does not exist, and with
+	// FAILED_PRECONDITION if its subnet overlaps another VLAN in the same VRF. The ID is assigned by the server.
+	CreateVLAN(context.Context, *CreateVLANRequest) (*VLAN, error)
+	// Fails with FAILED_PRECONDITION if the VLAN is managed, e.g. by the reconciler, and otherwise like
+	// CreateVLAN.
+	UpdateVLAN(context.Context, *UpdateVLANRequest) (*VLAN, error)
+	DeleteVLAN(context.Context, *DeleteVLANRequest) (*emptypb.Empty, error)
+	// Streams VLAN changes until the client cancels, response headers are sent once the stream is
+	// subscribed. Fails with OUT_OF_RANGE if the changes after last_event_id are no longer available, or if
+	// epoch is not the current one because the server restarted, after which clients should list the VLANs
+	// again.
+	Watch(*WatchRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedVLANServiceServer()
+}
+
+// UnimplementedVLANServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVLANServiceServer struct{}
+
+func (UnimplementedVLANServiceServer) ListVLANs(context.Context, *ListVLANsRequest) (*ListVLANsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVLANs not implemented")
+}
+func (UnimplementedVLANServiceServer) GetVLAN(context.Context, *GetVLANRequest) (*VLAN, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVLAN not implemented")
+}
+func (UnimplementedVLANServiceServer) CreateVLAN(context.Context, *CreateVLANRequest) (*VLAN, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateVLAN not implemented")
+}
+func (UnimplementedVLANServiceServer) UpdateVLAN(context.Context, *UpdateVLANRequest) (*VLAN, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateVLAN not implemented")
+}
+func (UnimplementedVLANServiceServer) DeleteVLAN(context.Context, *DeleteVLANRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVLAN not implemented")
+}
+func (UnimplementedVLANServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedVLANServiceServer) mustEmbedUnimplementedVLANServiceServer() {}
+func (UnimplementedVLANServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeVLANServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VLANServiceServer will
+// result in compilation errors.
+type UnsafeVLANServiceServer interface {
+	mustEmbedUnimplementedVLANServiceServer()
+}
+
+func RegisterVLANServiceServer(s grpc.ServiceRegistrar, srv VLANServiceServer) {
+	// If the following call pancis, it indicates UnimplementedVLANServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VLANService_ServiceDesc, srv)
+}
+
+func _VLANService_ListVLANs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVLANsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VLANServiceServer).ListVLANs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VLANService_ListVLANs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VLANServiceServer).ListVLANs(ctx, req.(*ListVLANsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VLANService_GetVLAN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVLANRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VLANServiceServer).GetVLAN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VLANService_GetVLAN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VLANServiceServer).GetVLAN(ctx, req.(*GetVLANRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VLANService_CreateVLAN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateVLANRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VLANServiceServer).CreateVLAN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VLANService_CreateVLAN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VLANServiceServer).CreateVLAN(ctx, req.(*CreateVLANRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VLANService_UpdateVLAN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateVLANRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VLANServiceServer).UpdateVLAN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VLANService_UpdateVLAN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VLANServiceServer).UpdateVLAN(ctx, req.(*UpdateVLANRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VLANService_DeleteVLAN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteVLANRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VLANServiceServer).DeleteVLAN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VLANService_DeleteVLAN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VLANServiceServer).DeleteVLAN(ctx, req.(*DeleteVLANRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VLANService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VLANServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VLANService_WatchServer = grpc.ServerStreamingServer[Event]
+
+// VLANService_ServiceDesc is the grpc.ServiceDesc for VLANService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VLANService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "netadmin.vlan.v1.VLANService",
+	HandlerType: (*VLANServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListVLANs",
+			Handler:    _VLANService_ListVLANs_Handler,
+		},
+		{
+			MethodName: "GetVLAN",
+			Handler:    _VLANService_GetVLAN_Handler,
+		},
+		{
+			MethodName: "CreateVLAN",
+			Handler:    _VLANService_CreateVLAN_Handler,
+		},
+		{
+			MethodName: "UpdateVLAN",
+			Handler:    _VLANService_UpdateVLAN_Handler,
+		},
+		{
+			MethodName: "DeleteVLAN",
+			Handler:    _VLANService_DeleteVLAN_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _VLANService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/vlan/v1/vlan.proto",
+}
@@ -0,0 +1,314 @@
+// Package client is a Go client for the VLAN endpoints of the Network Administration API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VLAN is the VLAN resource of the API.
+type VLAN struct {
+	ID      uuid.UUID    `json:"id"`
+	VID     uint16       `json:"vid"`
+	Name    string       `json:"name"`
+	Subnet  netip.Prefix `json:"subnet"`
+	Gateway netip.Addr   `json:"gateway"`
+	Status  string       `json:"status"`
+	// VRF is the name of the VRF the VLAN is bound to, the global routing table if empty.
+	VRF  string   `json:"vrf,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// Fields are the values of the custom fields defined by the API.
+	Fields map[string]any `json:"fields,omitempty"`
+
+	// ManagedBy names the component that owns the VLAN, e.g. the reconciler. Managed VLANs are read-only.
+	ManagedBy string `json:"managedBy,omitempty"`
+	// Deleted is set on VLANs in the trash.
+	Deleted *Deletion `json:"deleted,omitempty"`
+}
+
+// Deletion records when and by whom a VLAN in the trash was deleted.
+type Deletion struct {
+	At time.Time `json:"at"`
+	By string    `json:"by"`
+}
+
+// Errors matched by errors.Is on errors returned for API error responses.
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+)
+
+// APIError is an error response of the API.
+type APIError struct {
+	StatusCode int
+	// Machine-readable error code, empty if the response was not an ErrorResponse.
+	Code    string
+	Message string
+
+	// Whether the request was sent more than once.
+	retried bool
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("api error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("api error: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInvalidInput:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// Client calls the API. Idempotent requests are retried on network errors and 5xx or 429 responses.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	retries    int
+	backoff    time.Duration
+}
+
+type Option func(*Client)
+
+// WithToken authenticates requests with a bearer token.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithTimeout sets the timeout of each request attempt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithRetries sets how many times idempotent requests are retried, and the backoff before the first
+// retry. The backoff doubles for every following retry.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a client for the API at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base url %q: expected http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retries:    3,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func (c *Client) List(ctx context.Context) ([]VLAN, error) {
+	vlans := []VLAN{}
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/vlans", nil, &vlans); err != nil {
+		return nil, err
+	}
+	return vlans, nil
+}
+
+func (c *Client) Get(ctx context.Context, id uuid.UUID) (*VLAN, error) {
+	v := &VLAN{}
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/vlans/"+id.String(), nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Create creates a VLAN and returns it with the ID assigned by the API.
+func (c *Client) Create(ctx context.Context, v VLAN) (*VLAN, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/vlans", v, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(path.Base(resp.Header.Get("Location")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid location of created vlan: %w", err)
+	}
+	v.ID = id
+	return &v, nil
+}
+
+func (c *Client) Update(ctx context.Context, v VLAN) error {
+	_, err := c.do(ctx, http.MethodPut, "/api/v1/vlans/"+v.ID.String(), v, nil)
+	return err
+}
+
+// Delete deletes a VLAN. A VLAN that is not found when the request is retried counts as deleted, since an
+// earlier attempt may have deleted it without its response reaching the client.
+func (c *Client) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/v1/vlans/"+id.String(), nil, nil)
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && apiErr.retried {
+		return nil
+	}
+	return err
+}
+
+// do sends a request with an optional JSON body, and decodes the JSON response into out if it is not nil.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) (*http.Response, error) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+	if method != http.MethodPost {
+		attempts += c.retries
+	}
+
+	var resp *http.Response
+	var err error
+	attempt := 0
+	for ; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.retryDelay(attempt, resp)); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = c.send(ctx, method, path, reqBody)
+		if err == nil && (!retryable(resp.StatusCode) || attempt == attempts-1) {
+			break
+		}
+		if err == nil {
+			// Only the status and headers of retried responses are used, the last response is decoded
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		apiErr := decodeError(resp)
+		apiErr.retried = attempt > 0
+		return resp, apiErr
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.JoinPath(path).String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// retryDelay honours Retry-After of the previous response, or backs off exponentially.
+func (c *Client) retryDelay(attempt int, prev *http.Response) time.Duration {
+	if prev != nil {
+		if seconds, err := strconv.Atoi(prev.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return c.backoff << (attempt - 1)
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func decodeError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	errResp := struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") &&
+		json.NewDecoder(resp.Body).Decode(&errResp) == nil {
+		apiErr.Code = errResp.Code
+		apiErr.Message = errResp.Message
+	}
+	return apiErr
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,247 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/server"
+	"net-admin-api/internal/vlan"
+)
+
+func TestClient_OK(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newTestClient(t, newAPIServer(t).Handler)
+
+	vlans, err := c.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, vlans)
+
+	created, err := c.Create(ctx, newVLAN(1, "test1", "192.168.0.0/24", "192.168.0.1"))
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, created.ID)
+
+	fetched, err := c.Get(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, *created, *fetched)
+
+	created.Name = "a better name"
+	require.NoError(t, c.Update(ctx, *created))
+	vlans, err = c.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []VLAN{*created}, vlans)
+
+	require.NoError(t, c.Delete(ctx, created.ID))
+	vlans, err = c.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, vlans)
+}
+
+func TestClient_Errors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := newTestClient(t, newAPIServer(t).Handler)
+
+	_, err := c.Get(ctx, uuid.New())
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, c.Delete(ctx, uuid.New()), ErrNotFound)
+
+	// Gateway outside of the subnet
+	_, err = c.Create(ctx, newVLAN(1, "test1", "192.168.0.0/24", "10.0.0.1"))
+	require.ErrorIs(t, err, ErrInvalidInput)
+	apiErr := &APIError{}
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, server.ErrCodeInvalidInput, apiErr.Code)
+	require.NotEmpty(t, apiErr.Message)
+	require.NotErrorIs(t, err, ErrNotFound)
+}
+
+func TestClient_Conflict(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	managed := vlan.VLAN{
+		ID:        uuid.New(),
+		VID:       1,
+		Name:      "managed",
+		Subnet:    netip.MustParsePrefix("192.168.0.0/24"),
+		Gateway:   netip.MustParseAddr("192.168.0.1"),
+		Status:    "active",
+		ManagedBy: "reconciler",
+	}
+	require.NoError(t, vlanStore.Save(managed))
+
+	apiServer, err := server.NewServer(0, vlanStore)
+	require.NoError(t, err)
+	c := newTestClient(t, apiServer.Handler)
+
+	require.ErrorIs(t, c.Delete(ctx, managed.ID), ErrConflict)
+}
+
+func TestClient_Token(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: "alice-token"}})
+	require.NoError(t, err)
+	handler := newAPIServer(t, server.WithUsers(users)).Handler
+
+	_, err = newTestClient(t, handler).List(ctx)
+	require.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = newTestClient(t, handler, WithToken("alice-token")).List(ctx)
+	require.NoError(t, err)
+}
+
+func TestClient_Retries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	apiHandler := newAPIServer(t).Handler
+	requests := atomic.Int32{}
+	failures := atomic.Int32{}
+	failures.Store(2)
+	handler := http.HandlerFunc(func(respWriter http.ResponseWriter, req *http.Request) {
+		if requests.Add(1) <= failures.Load() {
+			respWriter.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		apiHandler.ServeHTTP(respWriter, req)
+	})
+
+	// Idempotent requests are retried
+	c := newTestClient(t, handler, WithRetries(2, time.Millisecond))
+	_, err := c.List(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, requests.Load())
+
+	// Creates are not
+	requests.Store(0)
+	_, err = c.Create(ctx, newVLAN(1, "test1", "192.168.0.0/24", "192.168.0.1"))
+	require.Error(t, err)
+	require.EqualValues(t, 1, requests.Load())
+
+	// Retries are exhausted
+	requests.Store(0)
+	failures.Store(10)
+	_, err = c.List(ctx)
+	apiErr := &APIError{}
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	require.EqualValues(t, 3, requests.Load())
+}
+
+func TestClient_RetryResponses(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	apiHandler := newAPIServer(t).Handler
+	requests := atomic.Int32{}
+	unavailable := atomic.Bool{}
+	handler := http.HandlerFunc(func(respWriter http.ResponseWriter, req *http.Request) {
+		// The first delete is applied, but its response is lost
+		if req.Method == http.MethodDelete && requests.Add(1) == 1 {
+			apiHandler.ServeHTTP(httptest.NewRecorder(), req)
+			respWriter.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if unavailable.Load() {
+			respWriter.Header().Set("Content-Type", "application/json")
+			respWriter.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = respWriter.Write([]byte(`{"code":"UNAVAILABLE","message":"down for maintenance"}`))
+			return
+		}
+		apiHandler.ServeHTTP(respWriter, req)
+	})
+	c := newTestClient(t, handler, WithRetries(2, time.Millisecond))
+
+	// A retried delete that finds the VLAN deleted succeeds
+	created, err := c.Create(ctx, newVLAN(1, "test1", "192.168.0.0/24", "192.168.0.1"))
+	require.NoError(t, err)
+	require.NoError(t, c.Delete(ctx, created.ID))
+	require.EqualValues(t, 2, requests.Load())
+	_, err = c.Get(ctx, created.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	// A delete that is not retried does not
+	require.ErrorIs(t, c.Delete(ctx, created.ID), ErrNotFound)
+
+	// The body of the last retried response is decoded
+	unavailable.Store(true)
+	_, err = c.List(ctx)
+	apiErr := &APIError{}
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, &APIError{StatusCode: http.StatusServiceUnavailable, Code: "UNAVAILABLE", Message: "down for maintenance",
+		retried: true}, apiErr)
+}
+
+// The VLAN of the client encodes like the VLAN of the API.
+func TestVLAN_JSON(t *testing.T) {
+	t.Parallel()
+	apiVLAN := vlan.VLAN{
+		ID:        uuid.New(),
+		VID:       10,
+		Name:      "users",
+		Subnet:    netip.MustParsePrefix("192.168.10.0/24"),
+		Gateway:   netip.MustParseAddr("192.168.10.1"),
+		Status:    "active",
+		VRF:       "red",
+		Tags:      []string{"office"},
+		Fields:    map[string]any{"site": "ber1"},
+		ManagedBy: "reconciler",
+		Deleted:   &vlan.Deletion{At: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), By: "alice"},
+	}
+	data, err := json.Marshal(apiVLAN)
+	require.NoError(t, err)
+
+	clientVLAN := VLAN{}
+	require.NoError(t, json.Unmarshal(data, &clientVLAN))
+	clientData, err := json.Marshal(clientVLAN)
+	require.NoError(t, err)
+	require.JSONEq(t, string(data), string(clientData))
+}
+
+func TestNew_NOK(t *testing.T) {
+	t.Parallel()
+	_, err := New("localhost:8080")
+	require.Error(t, err)
+	_, err = New("://")
+	require.Error(t, err)
+}
+
+func newAPIServer(t *testing.T, opts ...server.Option) *http.Server {
+	t.Helper()
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	apiServer, err := server.NewServer(0, vlanStore, opts...)
+	require.NoError(t, err)
+	return apiServer
+}
+
+func newTestClient(t *testing.T, handler http.Handler, opts ...Option) *Client {
+	t.Helper()
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+	c, err := New(httpServer.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func newVLAN(vid uint16, name, subnet, gateway string) VLAN {
+	return VLAN{
+		VID:     vid,
+		Name:    name,
+		Subnet:  netip.MustParsePrefix(subnet),
+		Gateway: netip.MustParseAddr(gateway),
+		Status:  "active",
+	}
+}
@@ -2,55 +2,256 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/change"
+	"net-admin-api/internal/config"
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/dhcp"
+	"net-admin-api/internal/dns"
+	"net-admin-api/internal/encryption"
+	"net-admin-api/internal/grpcapi"
+	"net-admin-api/internal/idempotency"
+	"net-admin-api/internal/ratelimit"
+	"net-admin-api/internal/reconcile"
 	"net-admin-api/internal/server"
+	"net-admin-api/internal/tlsconfig"
+	"net-admin-api/internal/vlan"
+	"net-admin-api/internal/vrf"
+	"net-admin-api/internal/webhook"
 )
 
-const DEFAULT_PORT            = 8080
-const DEFAULT_VLAN_STORE_PATH = "vlans.json"
-
 func main() {
-	port := DEFAULT_PORT
-	if portStr := os.Getenv("PORT"); portStr != "" {
-		var err error
-		port, err = strconv.Atoi(portStr)
+	cfg, opts, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opts.PrintConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			log.Fatalf("failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	// VLANs are checked against their VRFs and the custom field schema, so these are opened first
+	vrfs, err := vrf.NewStore(cfg.Stores.VRFs)
+	if err != nil {
+		log.Fatalf("failed to open VRF store: %v", err)
+	}
+	customFields, err := customfield.NewStore(cfg.Stores.CustomFields)
+	if err != nil {
+		log.Fatalf("failed to open custom field store: %v", err)
+	}
+
+	vlanStoreOpts := []vlan.StoreOption{
+		vlan.WithCompactAfter(cfg.Stores.VLANCompactAfter),
+		vlan.WithVRFs(vrfs.Exists),
+		vlan.WithFieldSchema(customFields.Get),
+	}
+	keyring, err := loadKeyring(cfg.Stores)
+	if err != nil {
+		log.Fatalf("failed to load VLAN store encryption keys: %v", err)
+	}
+	if keyring != nil {
+		vlanStoreOpts = append(vlanStoreOpts, vlan.WithEncryption(keyring))
+		log.Printf("Encrypting VLAN store with key %s", keyring.CurrentKeyID())
+		if cfg.Stores.VLANEncryptPlaintext {
+			vlanStoreOpts = append(vlanStoreOpts, vlan.WithPlaintextMigration())
+		}
+	}
+	vlanStore, err := vlan.NewStore(cfg.Stores.VLANs, vlanStoreOpts...)
+	if errors.Is(err, vlan.ErrNotEncrypted) {
+		log.Fatalf("failed to open VLAN store: %v; set NETADMIN_VLAN_ENCRYPT_PLAINTEXT=true once to encrypt an existing plaintext store", err)
+	} else if err != nil {
+		log.Fatalf("failed to open VLAN store: %v", err)
+	}
+	defer vlanStore.Close()
+
+	// Change requests, recorded responses and DHCP scopes describe VLANs too, so they are encrypted along with the
+	// VLAN store
+	changeRequestOpts := []change.StoreOption{}
+	idempotencyOpts := []idempotency.StoreOption{}
+	dhcpOpts := []dhcp.StoreOption{}
+	if keyring != nil {
+		changeRequestOpts = append(changeRequestOpts, change.WithEncryption(keyring))
+		idempotencyOpts = append(idempotencyOpts, idempotency.WithEncryption(keyring))
+		dhcpOpts = append(dhcpOpts, dhcp.WithEncryption(keyring))
+	}
+	changeRequests, err := change.NewStore(cfg.Stores.ChangeRequests, changeRequestOpts...)
+	if err != nil {
+		log.Fatalf("failed to open change request store: %v", err)
+	}
+
+	idempotencyKeys, err := idempotency.NewStore(cfg.Stores.IdempotencyKeys, cfg.Stores.IdempotencyKeyTTL, idempotencyOpts...)
+	if err != nil {
+		log.Fatalf("failed to open idempotency key store: %v", err)
+	}
+
+	serverOpts := []server.Option{
+		server.WithChangeRequests(changeRequests),
+		server.WithIdempotencyKeys(idempotencyKeys),
+		server.WithVRFs(vrfs),
+		server.WithCustomFields(customFields),
+		server.WithTimeouts(cfg.Server.ReadTimeout, cfg.Server.WriteTimeout, cfg.Server.IdleTimeout),
+		server.WithMaxBodySize(cfg.Server.MaxBodySize),
+		server.WithEventStream(cfg.Server.EventLogSize, cfg.Server.EventHeartbeat),
+	}
+	grpcOpts := []grpcapi.Option{grpcapi.WithEventLogSize(cfg.Server.EventLogSize)}
+	if !cfg.RateLimits.Disabled {
+		// Both APIs take from the same buckets, so that clients cannot double their budget
+		limiters := ratelimit.NewLimiters(
+			ratelimit.Limit{Rate: cfg.RateLimits.ReadRate, Burst: cfg.RateLimits.ReadBurst},
+			ratelimit.Limit{Rate: cfg.RateLimits.WriteRate, Burst: cfg.RateLimits.WriteBurst},
+		)
+		serverOpts = append(serverOpts, server.WithRateLimits(limiters))
+		grpcOpts = append(grpcOpts, grpcapi.WithRateLimits(limiters))
+	}
+	if cfg.Server.MetricsPerVLAN {
+		serverOpts = append(serverOpts, server.WithPerVLANMetrics())
+	}
+	if cfg.Auth.UsersPath != "" {
+		users, err := auth.LoadUsers(cfg.Auth.UsersPath)
+		if err != nil {
+			log.Fatalf("failed to load users: %v", err)
+		}
+		serverOpts = append(serverOpts, server.WithUsers(users))
+		grpcOpts = append(grpcOpts, grpcapi.WithUsers(users))
+	}
+
+	// Background workers stop when the API server shuts down
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	if cfg.TLS.CertPath != "" {
+		reloader, err := tlsconfig.New(tlsconfig.Options{
+			CertFile:       cfg.TLS.CertPath,
+			KeyFile:        cfg.TLS.KeyPath,
+			ClientCAFile:   cfg.TLS.ClientCAPath,
+			ReloadInterval: cfg.TLS.ReloadInterval,
+		})
 		if err != nil {
-			log.Fatalf("invalid API server port: %v", err)
+			log.Fatalf("failed to load TLS certificate: %v", err)
 		}
+		go reloader.Run(backgroundCtx)
+		tlsConfig := reloader.Config()
+		serverOpts = append(serverOpts, server.WithTLS(tlsConfig))
+		grpcOpts = append(grpcOpts, grpcapi.WithTLS(tlsConfig))
+	}
+
+	if cfg.Stores.VLANTrashRetention > 0 {
+		go vlanStore.RunPurge(backgroundCtx, cfg.Stores.VLANTrashRetention)
+	}
+
+	webhooks, err := webhook.NewStore(cfg.Stores.Webhooks)
+	if err != nil {
+		log.Fatalf("failed to open webhook store: %v", err)
+	}
+	webhookDispatcher := webhook.NewDispatcher(webhooks, webhook.Options(cfg.Webhooks))
+	vlanStore.Subscribe(webhookDispatcher.Notify)
+	webhooksDone := make(chan struct{})
+	go func() {
+		webhookDispatcher.Run(backgroundCtx)
+		close(webhooksDone)
+	}()
+	serverOpts = append(serverOpts, server.WithWebhooks(webhooks, webhookDispatcher))
+
+	dhcpScopes, err := dhcp.NewStore(cfg.Stores.DHCPScopes, dhcpOpts...)
+	if err != nil {
+		log.Fatalf("failed to open DHCP scope store: %v", err)
+	}
+	serverOpts = append(serverOpts, server.WithDHCP(dhcpScopes))
+
+	if cfg.DNS.Domain != "" {
+		serverOpts = append(serverOpts, server.WithDNS(dns.Options{
+			Domain:      cfg.DNS.Domain,
+			Nameservers: cfg.DNS.Nameservers,
+			Hostmaster:  cfg.DNS.Hostmaster,
+			TTL:         uint32(cfg.DNS.TTL.Seconds()),
+		}))
 	}
 
-	vlanStorePath := os.Getenv("VLAN_STORE_PATH")
-	if vlanStorePath == "" {
-		vlanStorePath = DEFAULT_VLAN_STORE_PATH
+	if cfg.Reconcile.Dir != "" {
+		reconciler := reconcile.New(vlanStore, reconcile.Options{
+			Dir:      cfg.Reconcile.Dir,
+			Interval: cfg.Reconcile.Interval,
+			Prune:    cfg.Reconcile.Prune,
+			DryRun:   cfg.Reconcile.DryRun,
+		})
+		go reconciler.Run(backgroundCtx)
+		serverOpts = append(serverOpts, server.WithReconciler(reconciler))
+		log.Printf("Reconciling VLANs from %v", cfg.Reconcile.Dir)
 	}
 
-	server, err := server.NewServer(port, vlanStorePath)
+	server, err := server.NewServer(cfg.Server.Port, vlanStore, serverOpts...)
 	if err != nil {
 		log.Fatalf("failed to create API server: %v", err)
 	}
+	server.RegisterOnShutdown(stopBackground)
+
+	// The gRPC server shares the VLAN store, and stops along with the API server
+	grpcServer := grpcapi.NewServer(vlanStore, grpcOpts...)
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+	if err != nil {
+		log.Fatalf("failed to listen for gRPC: %v", err)
+	}
+	server.RegisterOnShutdown(grpcServer.GracefulStop)
+	go func() {
+		log.Printf("Starting gRPC server at %v", grpcListener.Addr())
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("gRPC server error: %s", err)
+		}
+	}()
 
 	shutdownDone := make(chan bool, 1)
-	go gracefulShutdown(server, shutdownDone)
+	go gracefulShutdown(server, cfg.Shutdown.DrainTimeout, shutdownDone)
 
 	log.Printf("Starting API server at %v", server.Addr)
 
-	err = server.ListenAndServe()
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
 	if err != nil && err != http.ErrServerClosed {
 		log.Fatalf("API server error: %s", err)
 	}
 
 	<-shutdownDone
+	// Queued webhook deliveries are attempted before exiting
+	select {
+	case <-webhooksDone:
+	case <-time.After(cfg.Shutdown.DrainTimeout):
+		log.Println("Webhook deliveries not drained in time")
+	}
 	log.Println("API server shutdown complete")
 }
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+// loadKeyring returns the keys that encrypt the VLAN store, or nil if it is not encrypted.
+func loadKeyring(stores config.Stores) (*encryption.Keyring, error) {
+	switch {
+	case stores.VLANEncryptionKeyPath != "":
+		return encryption.LoadKeyring(stores.VLANEncryptionKeyPath)
+	case stores.VLANEncryptionKey != "":
+		return encryption.ParseKeyring(stores.VLANEncryptionKey)
+	default:
+		return nil, nil
+	}
+}
+
+func gracefulShutdown(apiServer *http.Server, drainTimeout time.Duration, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -61,8 +262,8 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 	log.Println("Shutting down gracefully, press Ctrl+C again to force")
 	stop() // Allow Ctrl+C to force shutdown by not diverting the signals any more
 
-	// Allow 5 seconds to finish ongoing requests
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Allow the drain timeout to finish ongoing requests
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
 	defer cancel()
 	if err := apiServer.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown with error: %v", err)
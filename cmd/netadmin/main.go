@@ -0,0 +1,171 @@
+// Command netadmin is a command-line tool for the Network Administration API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"net-admin-api/client"
+)
+
+const DEFAULT_URL = "http://localhost:8080"
+const DEFAULT_TIMEOUT = 30 * time.Second
+
+// Exit codes, one per API error code.
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitInvalidInput
+	exitUnauthorized
+	exitForbidden
+	exitNotFound
+	exitConflict
+)
+
+const usage = `Usage: netadmin <resource> <command> [flags]
+
+Commands:
+  vlan list                  List VLANs
+  vlan get <id>              Show a VLAN
+  vlan create [flags]        Create a VLAN from a file or flags
+  vlan update <id> [flags]   Update a VLAN from a file or flags
+  vlan delete <id>           Delete a VLAN
+
+Run 'netadmin vlan <command> -h' for the flags of a command.
+`
+
+var errUsage = errors.New("usage error")
+
+// cli holds the input, output and flags shared by all commands.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	url     string
+	token   string
+	output  string
+	timeout time.Duration
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) < 2 || args[0] != "vlan" {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	var err error
+	switch args[1] {
+	case "list":
+		err = c.vlanList(args[2:])
+	case "get":
+		err = c.vlanGet(args[2:])
+	case "create":
+		err = c.vlanCreate(args[2:])
+	case "update":
+		err = c.vlanUpdate(args[2:])
+	case "delete":
+		err = c.vlanDelete(args[2:])
+	default:
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	// A bare errUsage has already been reported along with the usage
+	if err != errUsage {
+		fmt.Fprintf(stderr, "netadmin: %v\n", err)
+	}
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, client.ErrInvalidInput):
+		return exitInvalidInput
+	case errors.Is(err, client.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, client.ErrForbidden):
+		return exitForbidden
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrConflict):
+		return exitConflict
+	}
+	return exitError
+}
+
+// flagSet returns a flag set of a command with the flags shared by all commands.
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("netadmin vlan "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	url := os.Getenv("NETADMIN_URL")
+	if url == "" {
+		url = DEFAULT_URL
+	}
+	fs.StringVar(&c.url, "url", url, "API base URL (env NETADMIN_URL)")
+	fs.StringVar(&c.token, "token", os.Getenv("NETADMIN_TOKEN"), "API bearer token (env NETADMIN_TOKEN)")
+	fs.StringVar(&c.output, "o", "table", "output format: table, json or yaml")
+	fs.DurationVar(&c.timeout, "timeout", DEFAULT_TIMEOUT, "timeout of the command")
+	return fs
+}
+
+// parse parses flags and returns the positional arguments, which may be mixed with the flags.
+func (c *cli) parse(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	values := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		values = append(values, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(values) != positional {
+		fmt.Fprintf(c.stderr, "%s: expected %d argument(s), got %d\n", fs.Name(), positional, len(values))
+		fs.Usage()
+		return nil, errUsage
+	}
+	switch c.output {
+	case "table", "json", "yaml":
+	default:
+		fmt.Fprintf(c.stderr, "%s: unknown output format %q\n", fs.Name(), c.output)
+		return nil, errUsage
+	}
+	return values, nil
+}
+
+func (c *cli) client() (*client.Client, error) {
+	opts := []client.Option{client.WithTimeout(c.timeout)}
+	if c.token != "" {
+		opts = append(opts, client.WithToken(c.token))
+	}
+	return client.New(c.url, opts...)
+}
+
+// context bounds a command, including retries, by the timeout flag.
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"net-admin-api/client"
+	"net-admin-api/internal/server"
+	"net-admin-api/internal/vlan"
+)
+
+func TestVLANCommands_OK(t *testing.T) {
+	t.Parallel()
+	url := newAPIServer(t)
+
+	// Create from flags
+	stdout := runOK(t, url, "", "vlan", "create", "-o", "json",
+		"--vid", "10", "--name", "users", "--subnet", "10.0.10.0/24", "--gateway", "10.0.10.1", "--status", "active")
+	created := client.VLAN{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &created))
+	require.NotEqual(t, uuid.Nil, created.ID)
+	require.Equal(t, "users", created.Name)
+
+	// Create from a YAML file, with a flag taking precedence
+	file := filepath.Join(t.TempDir(), "vlan.yml")
+	require.NoError(t, os.WriteFile(file, []byte("vid: 20\nname: servers\nsubnet: 10.0.20.0/24\ngateway: 10.0.20.1\n"), 0o644))
+	runOK(t, url, "", "vlan", "create", "-f", file, "--status", "planned")
+
+	// Create from JSON on stdin
+	runOK(t, url, `{"vid": 30, "name": "lab", "subnet": "10.0.30.0/24", "gateway": "10.0.30.1"}`,
+		"vlan", "create", "-f", "-")
+
+	// List as YAML, ordered by VID
+	stdout = runOK(t, url, "", "vlan", "list", "-o", "yaml")
+	listed := []client.VLAN{}
+	require.NoError(t, yaml.Unmarshal([]byte(stdout), &listed))
+	require.Len(t, listed, 3)
+	require.Equal(t, []uint16{10, 20, 30}, []uint16{listed[0].VID, listed[1].VID, listed[2].VID})
+	require.Equal(t, "planned", listed[1].Status)
+
+	// List as a table
+	stdout = runOK(t, url, "", "vlan", "list")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, []string{"ID", "VID", "NAME", "SUBNET", "GATEWAY", "STATUS", "MANAGED", "BY"}, strings.Fields(lines[0]))
+	require.Equal(t, []string{created.ID.String(), "10", "users", "10.0.10.0/24", "10.0.10.1", "active"}, strings.Fields(lines[1]))
+
+	// Update a single field, the ID may come before the flags
+	runOK(t, url, "", "vlan", "update", created.ID.String(), "--name", "staff")
+	stdout = runOK(t, url, "", "vlan", "get", "-o", "json", created.ID.String())
+	updated := client.VLAN{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &updated))
+	created.Name = "staff"
+	require.Equal(t, created, updated)
+
+	// Delete
+	runOK(t, url, "", "vlan", "delete", created.ID.String())
+	code, _, _ := runCLI(t, url, "", "vlan", "get", created.ID.String())
+	require.Equal(t, exitNotFound, code)
+}
+
+func TestVLANCommands_NOK(t *testing.T) {
+	t.Parallel()
+	url := newAPIServer(t)
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no command", args: []string{"vlan"}, code: exitUsage},
+		{name: "unknown command", args: []string{"vlan", "move"}, code: exitUsage},
+		{name: "unknown flag", args: []string{"vlan", "list", "--color"}, code: exitUsage},
+		{name: "unknown output", args: []string{"vlan", "list", "-o", "xml"}, code: exitUsage},
+		{name: "missing id", args: []string{"vlan", "get"}, code: exitUsage},
+		{name: "invalid id", args: []string{"vlan", "delete", "42"}, code: exitUsage},
+		{name: "missing flags", args: []string{"vlan", "create", "--vid", "10"}, code: exitUsage},
+		{name: "not found", args: []string{"vlan", "get", uuid.NewString()}, code: exitNotFound},
+		{name: "invalid input", code: exitInvalidInput, args: []string{"vlan", "create",
+			"--vid", "10", "--name", "users", "--subnet", "10.0.10.0/24", "--gateway", "10.0.99.1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, _, stderr := runCLI(t, url, "", test.args...)
+			require.Equal(t, test.code, code, stderr)
+			require.NotEmpty(t, stderr)
+		})
+	}
+}
+
+func newAPIServer(t *testing.T) string {
+	t.Helper()
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	apiServer, err := server.NewServer(0, vlanStore)
+	require.NoError(t, err)
+	httpServer := httptest.NewServer(apiServer.Handler)
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL
+}
+
+func runCLI(t *testing.T, url, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	if len(args) >= 2 {
+		args = append(args[:2:2], append([]string{"--url", url}, args[2:]...)...)
+	}
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func runOK(t *testing.T, url, stdin string, args ...string) string {
+	t.Helper()
+	code, stdout, stderr := runCLI(t, url, stdin, args...)
+	require.Equal(t, exitOK, code, stderr)
+	return stdout
+}
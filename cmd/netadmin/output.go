@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"net-admin-api/client"
+)
+
+func (c *cli) printVLAN(v client.VLAN) error {
+	return c.print(v, []client.VLAN{v})
+}
+
+func (c *cli) printVLANs(vlans []client.VLAN) error {
+	return c.print(vlans, vlans)
+}
+
+// print writes doc in the JSON or YAML output format, or the VLANs as a table.
+func (c *cli) print(doc any, vlans []client.VLAN) error {
+	switch c.output {
+	case "json":
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	case "yaml":
+		return c.writeYAML(doc)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tVID\tNAME\tSUBNET\tGATEWAY\tSTATUS\tMANAGED BY")
+	for _, v := range vlans {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", v.ID, v.VID, v.Name, v.Subnet, v.Gateway, v.Status, v.ManagedBy)
+	}
+	return w.Flush()
+}
+
+// writeYAML writes doc as YAML with the field names and order of its JSON encoding.
+func (c *cli) writeYAML(doc any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	node := yaml.Node{}
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	resetStyle(&node)
+
+	buf := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(buf.Bytes())
+	return err
+}
+
+// resetStyle drops the flow style and quoting that nodes decoded from JSON have.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
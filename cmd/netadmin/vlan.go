@@ -0,0 +1,261 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"net-admin-api/client"
+)
+
+// vlanFlags are the flags of commands that take a VLAN definition.
+type vlanFlags struct {
+	file    string
+	vid     uint
+	name    string
+	subnet  string
+	gateway string
+	status  string
+}
+
+func (c *cli) vlanList(args []string) error {
+	fs := c.flagSet("list")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	apiClient, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	vlans, err := apiClient.List(ctx)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(vlans, func(a, b client.VLAN) int { return cmp.Compare(a.VID, b.VID) })
+	return c.printVLANs(vlans)
+}
+
+func (c *cli) vlanGet(args []string) error {
+	fs := c.flagSet("get")
+	values, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(values[0])
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	v, err := apiClient.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return c.printVLAN(*v)
+}
+
+func (c *cli) vlanCreate(args []string) error {
+	fs := c.flagSet("create")
+	vf := addVLANFlags(fs)
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	v := client.VLAN{}
+	if vf.file != "" {
+		if err := c.readVLAN(vf.file, &v); err != nil {
+			return err
+		}
+	} else {
+		for _, required := range []string{"vid", "name", "subnet", "gateway"} {
+			if !isSet(fs, required) {
+				fmt.Fprintf(c.stderr, "%s: -f or -%s is required\n", fs.Name(), required)
+				return errUsage
+			}
+		}
+	}
+	if err := vf.apply(fs, &v); err != nil {
+		return err
+	}
+
+	apiClient, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	created, err := apiClient.Create(ctx, v)
+	if err != nil {
+		return err
+	}
+	return c.printVLAN(*created)
+}
+
+// vlanUpdate replaces a VLAN with the one read from a file, or changes the fields given as flags.
+func (c *cli) vlanUpdate(args []string) error {
+	fs := c.flagSet("update")
+	vf := addVLANFlags(fs)
+	values, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(values[0])
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	v := client.VLAN{}
+	if vf.file != "" {
+		if err := c.readVLAN(vf.file, &v); err != nil {
+			return err
+		}
+	} else {
+		existing, err := apiClient.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		v = *existing
+	}
+	if err := vf.apply(fs, &v); err != nil {
+		return err
+	}
+	v.ID = id
+
+	if err := apiClient.Update(ctx, v); err != nil {
+		return err
+	}
+	return c.printVLAN(v)
+}
+
+func (c *cli) vlanDelete(args []string) error {
+	fs := c.flagSet("delete")
+	values, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(values[0])
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+
+	return apiClient.Delete(ctx, id)
+}
+
+func addVLANFlags(fs *flag.FlagSet) *vlanFlags {
+	vf := &vlanFlags{}
+	fs.StringVar(&vf.file, "f", "", "read the VLAN from a JSON or YAML file, - for stdin")
+	fs.UintVar(&vf.vid, "vid", 0, "VLAN ID (1..4094)")
+	fs.StringVar(&vf.name, "name", "", "VLAN name")
+	fs.StringVar(&vf.subnet, "subnet", "", "subnet, e.g. 192.168.0.0/24")
+	fs.StringVar(&vf.gateway, "gateway", "", "gateway address within the subnet")
+	fs.StringVar(&vf.status, "status", "", "VLAN status")
+	return vf
+}
+
+// apply sets the fields of v that were given as flags, which take precedence over the file.
+func (vf *vlanFlags) apply(fs *flag.FlagSet, v *client.VLAN) error {
+	if isSet(fs, "vid") {
+		if vf.vid > 4094 {
+			return fmt.Errorf("%s: invalid VLAN ID %d (expected range 1..4094): %w", fs.Name(), vf.vid, errUsage)
+		}
+		v.VID = uint16(vf.vid)
+	}
+	if isSet(fs, "name") {
+		v.Name = vf.name
+	}
+	if isSet(fs, "subnet") {
+		subnet, err := netip.ParsePrefix(vf.subnet)
+		if err != nil {
+			return fmt.Errorf("%s: invalid subnet: %w", fs.Name(), err)
+		}
+		v.Subnet = subnet
+	}
+	if isSet(fs, "gateway") {
+		gateway, err := netip.ParseAddr(vf.gateway)
+		if err != nil {
+			return fmt.Errorf("%s: invalid gateway: %w", fs.Name(), err)
+		}
+		v.Gateway = gateway
+	}
+	if isSet(fs, "status") {
+		v.Status = vf.status
+	}
+	return nil
+}
+
+// readVLAN decodes a VLAN from a JSON or YAML file. YAML uses the same field names as the JSON API.
+func (c *cli) readVLAN(path string, v *client.VLAN) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %w", path, err)
+	}
+
+	if ext := filepath.Ext(path); ext != ".json" {
+		// JSON is also valid YAML, so anything but a .json file is decoded as YAML
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to decode %v: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("failed to decode %v: %w", path, err)
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %v: %w", path, err)
+	}
+	return nil
+}
+
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func parseID(value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid VLAN id %q: %w", value, errUsage)
+	}
+	return id, nil
+}
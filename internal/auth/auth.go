@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+const RoleAdmin = "admin"
+
+// Anonymous is the actor of requests when authentication is not configured.
+var Anonymous = Actor{Name: "anonymous"}
+
+// Actor is the authenticated user making a request.
+type Actor struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+func (a Actor) HasRole(role string) bool {
+	return slices.Contains(a.Roles, role)
+}
+
+// User is an entry in the users file. Users without a token can only authenticate with a client
+// certificate whose common name is the user name.
+type User struct {
+	Name  string   `json:"name"`
+	Token string   `json:"token,omitempty"`
+	Roles []string `json:"roles"`
+}
+
+// Users authenticates API tokens. Tokens are looked up by their SHA-256 hash, so that the time a lookup takes
+// reveals nothing about how much of a guessed token is right.
+type Users struct {
+	byToken map[[sha256.Size]byte]Actor
+	byName  map[string]Actor
+}
+
+func NewUsers(users []User) (*Users, error) {
+	byToken := make(map[[sha256.Size]byte]Actor, len(users))
+	byName := make(map[string]Actor, len(users))
+	for _, user := range users {
+		if user.Name == "" {
+			return nil, fmt.Errorf("user name must not be empty")
+		}
+		if _, ok := byName[user.Name]; ok {
+			return nil, fmt.Errorf("duplicate user %q", user.Name)
+		}
+		actor := Actor{Name: user.Name, Roles: user.Roles}
+		byName[user.Name] = actor
+		if user.Token == "" {
+			continue
+		}
+		hash := sha256.Sum256([]byte(user.Token))
+		if _, ok := byToken[hash]; ok {
+			return nil, fmt.Errorf("duplicate token for user %q", user.Name)
+		}
+		byToken[hash] = actor
+	}
+	return &Users{byToken: byToken, byName: byName}, nil
+}
+
+// LoadUsers reads users from a JSON file containing an array of User.
+func LoadUsers(path string) (*Users, error) {
+	usersFile, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %v: %w", path, err)
+	}
+	defer usersFile.Close()
+
+	users := []User{}
+	if err := json.NewDecoder(usersFile).Decode(&users); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %w", path, err)
+	}
+	return NewUsers(users)
+}
+
+func (u *Users) Authenticate(token string) (Actor, bool) {
+	actor, ok := u.byToken[sha256.Sum256([]byte(token))]
+	return actor, ok
+}
+
+// CertificateActor returns the actor of a connection with a verified client certificate. The actor is
+// named by the common name of the certificate subject, and has the roles of the user with that name.
+func CertificateActor(state *tls.ConnectionState, users *Users) (Actor, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Actor{}, false
+	}
+	name := state.VerifiedChains[0][0].Subject.CommonName
+	if name == "" {
+		return Actor{}, false
+	}
+	if users != nil {
+		if actor, ok := users.byName[name]; ok {
+			return actor, true
+		}
+	}
+	return Actor{Name: name}, true
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor of a request, or Anonymous if there is none.
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Anonymous
+}
@@ -0,0 +1,49 @@
+package change
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/vlan"
+)
+
+type Status string
+
+const (
+	StatusPending     Status = "pending"
+	StatusApproved    Status = "approved"
+	StatusRejected    Status = "rejected"
+	StatusInvalidated Status = "invalidated"
+)
+
+// Request is a proposed VLAN change that is applied only after it has been approved.
+type Request struct {
+	ID   uuid.UUID          `json:"id"`
+	Op   vlan.OperationType `json:"op"`
+	VLAN vlan.VLAN          `json:"vlan"`
+	// Target VLAN as it was when the request was submitted, nil for creates.
+	Base *vlan.VLAN `json:"base,omitempty"`
+
+	Status      Status     `json:"status"`
+	Reason      string     `json:"reason,omitempty"`
+	SubmittedBy string     `json:"submittedBy"`
+	SubmittedAt time.Time  `json:"submittedAt"`
+	ReviewedBy  string     `json:"reviewedBy,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewedAt,omitempty"`
+	Comment     string     `json:"comment,omitempty"`
+}
+
+// Operation returns the store operation that applies the request. Deletes are recorded as made by the submitter.
+func (r *Request) Operation() vlan.Operation {
+	return vlan.Operation{Type: r.Op, VLAN: r.VLAN, By: r.SubmittedBy}
+}
+
+// IsStale reports whether the target VLAN has changed since the request was submitted.
+func (r *Request) IsStale(current *vlan.VLAN) bool {
+	if r.Base == nil {
+		return false
+	}
+	return current == nil || !reflect.DeepEqual(*r.Base, *current)
+}
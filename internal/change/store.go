@@ -0,0 +1,198 @@
+package change
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/encryption"
+	"net-admin-api/internal/jsonfile"
+	"net-admin-api/internal/vlan"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrNotPending = errors.New("change request is not pending")
+	ErrSelfReview = errors.New("change request cannot be reviewed by its submitter")
+	// ErrStale is returned by the apply function of Store.Review when the target VLAN has changed.
+	ErrStale = errors.New("target vlan changed since the change request was submitted")
+)
+
+// Review is the decision of a reviewer on a pending Request.
+type Review struct {
+	Reviewer string
+	Approve  bool
+	Comment  string
+	// AllowSelfReview lets submitters review their own requests, for when requests are not authenticated and
+	// everyone is the same anonymous actor.
+	AllowSelfReview bool
+}
+
+// Store manages a JSON file to persist change requests.
+type Store struct {
+	path         string
+	requestsByID map[uuid.UUID]Request
+	mu           sync.RWMutex
+	keyring      *encryption.Keyring
+}
+
+// Additional data of the sealed store file, so that it cannot be passed off as the file of another store.
+var fileAAD = []byte("net-admin-api change requests")
+
+type StoreOption func(*Store)
+
+// WithEncryption encrypts the store file with the current key of the keyring, since change requests contain the
+// VLANs they change. Plaintext files, and files encrypted with a previous key, are encrypted with the current key on
+// startup.
+func WithEncryption(keyring *encryption.Keyring) StoreOption {
+	return func(s *Store) {
+		s.keyring = keyring
+	}
+}
+
+func NewStore(path string, opts ...StoreOption) (*Store, error) {
+	store := &Store{
+		path: path,
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	// store file does not exist
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		store.requestsByID = make(map[uuid.UUID]Request, 0)
+		if err := store.writeRequests(); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	// store file exists
+	stale, err := store.readRequests()
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		if err := store.writeRequests(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// List returns change requests ordered by submission time.
+func (s *Store) List() []Request {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.SortedFunc(maps.Values(s.requestsByID), func(a, b Request) int {
+		return a.SubmittedAt.Compare(b.SubmittedAt)
+	})
+}
+
+func (s *Store) Get(id uuid.UUID) *Request {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if req, ok := s.requestsByID[id]; ok {
+		return &req
+	}
+	return nil
+}
+
+func (s *Store) Save(req Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requestsByID[req.ID] = req
+	return s.writeRequests()
+}
+
+// Invalidate marks all pending requests for which isStale returns true as invalidated.
+func (s *Store) Invalidate(isStale func(Request) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for id, req := range s.requestsByID {
+		if req.Status == StatusPending && isStale(req) {
+			req.Status = StatusInvalidated
+			req.Reason = ErrStale.Error()
+			s.requestsByID[id] = req
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.writeRequests()
+}
+
+// Review approves or rejects a pending request. Approved requests are passed to apply before they
+// are saved, if apply returns ErrStale the request is saved as invalidated instead. apply is called with the
+// store locked, so that a request is applied at most once, and must not call back into the store.
+func (s *Store) Review(id uuid.UUID, review Review, apply func(Request) error) (Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, ok := s.requestsByID[id]
+	if !ok {
+		return Request{}, ErrNotFound
+	}
+	if req.Status != StatusPending {
+		return req, ErrNotPending
+	}
+	if req.SubmittedBy == review.Reviewer && !review.AllowSelfReview {
+		return req, ErrSelfReview
+	}
+
+	var applyErr error
+	req.Status = StatusRejected
+	if review.Approve {
+		req.Status = StatusApproved
+		if applyErr = apply(req); errors.Is(applyErr, ErrStale) {
+			req.Status = StatusInvalidated
+			req.Reason = applyErr.Error()
+		} else if applyErr != nil {
+			return s.requestsByID[id], applyErr
+		}
+	}
+
+	reviewedAt := time.Now().UTC()
+	req.ReviewedBy = review.Reviewer
+	req.ReviewedAt = &reviewedAt
+	req.Comment = review.Comment
+	s.requestsByID[id] = req
+	if err := s.writeRequests(); err != nil {
+		return req, err
+	}
+	return req, applyErr
+}
+
+func (s *Store) readRequests() (bool, error) {
+	requests := []Request{}
+	stale, err := jsonfile.ReadSealed(s.path, s.keyring, fileAAD, &requests)
+	if err != nil {
+		return false, err
+	}
+
+	requestsByID := make(map[uuid.UUID]Request, len(requests))
+	for _, req := range requests {
+		if errors := req.VLAN.Validate(); req.Op != vlan.OperationDelete && len(errors) > 0 {
+			return false, fmt.Errorf("invalid change request in %s: %s", s.path, strings.Join(errors, ", "))
+		}
+		requestsByID[req.ID] = req
+	}
+	s.requestsByID = requestsByID
+	return stale, nil
+}
+
+func (s *Store) writeRequests() error {
+	return jsonfile.WriteSealed(s.path, s.keyring, fileAAD, slices.Collect(maps.Values(s.requestsByID)))
+}
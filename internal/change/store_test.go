@@ -0,0 +1,266 @@
+package change
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/encryption"
+	"net-admin-api/internal/vlan"
+)
+
+func TestStore_Review(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "change-requests.json")
+	store := openStore(t, path)
+	applied := []Request{}
+	apply := func(req Request) error {
+		applied = append(applied, req)
+		return nil
+	}
+
+	approved, rejected := newRequest("alice", vlan.OperationCreate, nil), newRequest("alice", vlan.OperationCreate, nil)
+	require.NoError(t, store.Save(approved))
+	require.NoError(t, store.Save(rejected))
+
+	_, err := store.Review(uuid.New(), Review{Reviewer: "bob", Approve: true}, apply)
+	require.ErrorIs(t, err, ErrNotFound)
+	// Submitters cannot review their own requests
+	_, err = store.Review(approved.ID, Review{Reviewer: "alice", Approve: true}, apply)
+	require.ErrorIs(t, err, ErrSelfReview)
+	require.Equal(t, StatusPending, store.Get(approved.ID).Status)
+
+	reviewed, err := store.Review(approved.ID, Review{Reviewer: "bob", Approve: true, Comment: "lgtm"}, apply)
+	require.NoError(t, err)
+	require.Equal(t, StatusApproved, reviewed.Status)
+	require.Equal(t, "bob", reviewed.ReviewedBy)
+	require.NotNil(t, reviewed.ReviewedAt)
+	require.Equal(t, "lgtm", reviewed.Comment)
+	// Unless self reviews are allowed, for requests that are not authenticated
+	reviewed, err = store.Review(rejected.ID, Review{Reviewer: "alice", AllowSelfReview: true}, apply)
+	require.NoError(t, err)
+	require.Equal(t, StatusRejected, reviewed.Status)
+	require.Equal(t, "alice", reviewed.ReviewedBy)
+	// Only approved requests are applied, once
+	require.Len(t, applied, 1)
+	require.Equal(t, approved.ID, applied[0].ID)
+	_, err = store.Review(approved.ID, Review{Reviewer: "carol", Approve: true}, apply)
+	require.ErrorIs(t, err, ErrNotPending)
+	require.Len(t, applied, 1)
+
+	// Reviews survive restarts
+	reopened := openStore(t, path)
+	require.Equal(t, store.List(), reopened.List())
+	require.Equal(t, StatusApproved, reopened.Get(approved.ID).Status)
+}
+
+func TestStore_ReviewApplyErrors(t *testing.T) {
+	t.Parallel()
+	store := openStore(t, filepath.Join(t.TempDir(), "change-requests.json"))
+	req := newRequest("alice", vlan.OperationCreate, nil)
+	require.NoError(t, store.Save(req))
+
+	// Requests that fail to apply stay pending
+	_, err := store.Review(req.ID, Review{Reviewer: "bob", Approve: true}, func(Request) error {
+		return vlan.ErrOverlap
+	})
+	require.ErrorIs(t, err, vlan.ErrOverlap)
+	require.Equal(t, StatusPending, store.Get(req.ID).Status)
+	require.Empty(t, store.Get(req.ID).ReviewedBy)
+
+	// Stale requests are invalidated
+	reviewed, err := store.Review(req.ID, Review{Reviewer: "bob", Approve: true}, func(Request) error {
+		return ErrStale
+	})
+	require.ErrorIs(t, err, ErrStale)
+	require.Equal(t, StatusInvalidated, reviewed.Status)
+	require.Equal(t, ErrStale.Error(), reviewed.Reason)
+	require.Equal(t, reviewed, *store.Get(req.ID))
+}
+
+func TestStore_ConcurrentReviews(t *testing.T) {
+	t.Parallel()
+	store := openStore(t, filepath.Join(t.TempDir(), "change-requests.json"))
+	vlans, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	t.Cleanup(func() { vlans.Close() })
+	req := newRequest("alice", vlan.OperationCreate, nil)
+	require.NoError(t, store.Save(req))
+
+	// apply runs with the store locked, so a request approved concurrently is applied once. It locks the VLAN
+	// store in turn, while other goroutines read both stores.
+	var applied atomic.Int32
+	apply := func(req Request) error {
+		applied.Add(1)
+		return vlans.Apply([]vlan.Operation{req.Operation()})
+	}
+	var wg sync.WaitGroup
+	var approved atomic.Int32
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := store.Review(req.ID, Review{Reviewer: "bob", Approve: true}, apply)
+			if err == nil {
+				approved.Add(1)
+			} else if !errors.Is(err, ErrNotPending) {
+				t.Errorf("review %d: %v", i, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			store.List()
+			vlans.Get(req.VLAN.ID)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), approved.Load())
+	require.Equal(t, int32(1), applied.Load())
+	require.NotNil(t, vlans.Get(req.VLAN.ID))
+}
+
+func TestStore_Invalidate(t *testing.T) {
+	t.Parallel()
+	store := openStore(t, filepath.Join(t.TempDir(), "change-requests.json"))
+	base := newVLAN(10)
+	stale, current := newRequest("alice", vlan.OperationUpdate, &base), newRequest("alice", vlan.OperationUpdate, &base)
+	reviewed := newRequest("alice", vlan.OperationUpdate, &base)
+	reviewed.Status = StatusRejected
+	for _, req := range []Request{stale, current, reviewed} {
+		require.NoError(t, store.Save(req))
+	}
+
+	require.NoError(t, store.Invalidate(func(req Request) bool {
+		return req.ID != current.ID
+	}))
+	require.Equal(t, StatusInvalidated, store.Get(stale.ID).Status)
+	require.Equal(t, ErrStale.Error(), store.Get(stale.ID).Reason)
+	require.Equal(t, StatusPending, store.Get(current.ID).Status)
+	// Only pending requests are invalidated
+	require.Equal(t, StatusRejected, store.Get(reviewed.ID).Status)
+}
+
+func TestStore_Encryption(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "change-requests.json")
+	req := newRequest("alice", vlan.OperationCreate, nil)
+	require.NoError(t, openStore(t, path).Save(req))
+	subnet := req.VLAN.Subnet.String()
+	require.Contains(t, readFile(t, path), subnet)
+
+	// Plaintext files are encrypted on startup
+	keyring := newKeyring(t)
+	store, err := NewStore(path, WithEncryption(keyring))
+	require.NoError(t, err)
+	require.NotContains(t, readFile(t, path), subnet)
+	require.Equal(t, []Request{req}, store.List())
+	reopened, err := NewStore(path, WithEncryption(keyring))
+	require.NoError(t, err)
+	require.Equal(t, store.List(), reopened.List())
+
+	// Encrypted files cannot be read without the key
+	_, err = NewStore(path)
+	require.ErrorContains(t, err, "no encryption key is configured")
+	_, err = NewStore(path, WithEncryption(newKeyring(t)))
+	require.ErrorIs(t, err, encryption.ErrUnknownKey)
+}
+
+func TestRequest_IsStale(t *testing.T) {
+	t.Parallel()
+	base := newVLAN(10)
+	base.Tags = []string{"users"}
+	base.Fields = map[string]any{"floor": float64(3)}
+	req := newRequest("alice", vlan.OperationUpdate, &base)
+
+	current := base
+	require.False(t, req.IsStale(&current))
+	require.True(t, req.IsStale(nil))
+	current.Tags = []string{"users", "staff"}
+	require.True(t, req.IsStale(&current))
+	current = base
+	current.Fields = map[string]any{"floor": float64(4)}
+	require.True(t, req.IsStale(&current))
+	current = base
+	current.Status = "disabled"
+	require.True(t, req.IsStale(&current))
+
+	// Creates have no target VLAN
+	create := newRequest("alice", vlan.OperationCreate, nil)
+	require.False(t, create.IsStale(nil))
+
+	// The target VLAN is compared as it was read back from the store file
+	path := filepath.Join(t.TempDir(), "change-requests.json")
+	store := openStore(t, path)
+	require.NoError(t, store.Save(req))
+	require.False(t, openStore(t, path).Get(req.ID).IsStale(&base))
+}
+
+func TestRequest_Operation(t *testing.T) {
+	t.Parallel()
+	base := newVLAN(10)
+	req := newRequest("alice", vlan.OperationDelete, &base)
+	require.Equal(t, vlan.Operation{Type: vlan.OperationDelete, VLAN: req.VLAN, By: "alice"}, req.Operation())
+}
+
+func openStore(t *testing.T, path string) *Store {
+	t.Helper()
+	store, err := NewStore(path)
+	require.NoError(t, err)
+	return store
+}
+
+func newRequest(submittedBy string, op vlan.OperationType, base *vlan.VLAN) Request {
+	target := newVLAN(20)
+	if base != nil {
+		target = *base
+		target.Name = "renamed"
+	}
+	return Request{
+		ID:          uuid.New(),
+		Op:          op,
+		VLAN:        target,
+		Base:        base,
+		Status:      StatusPending,
+		SubmittedBy: submittedBy,
+		SubmittedAt: time.Now().UTC(),
+	}
+}
+
+func newVLAN(vid uint16) vlan.VLAN {
+	addr := netip.AddrFrom4([4]byte{10, 0, byte(vid), 0})
+	return vlan.VLAN{
+		ID:      uuid.New(),
+		VID:     vid,
+		Name:    "vlan",
+		Subnet:  netip.PrefixFrom(addr, 24),
+		Gateway: addr.Next(),
+		Status:  "enabled",
+	}
+}
+
+func newKeyring(t *testing.T) *encryption.Keyring {
+	t.Helper()
+	key := make([]byte, encryption.KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	keyring, err := encryption.ParseKeyring(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	return keyring
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
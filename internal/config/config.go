@@ -0,0 +1,409 @@
+// Package config loads the configuration of the API server from a file, environment variables and flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"net-admin-api/internal/idempotency"
+	"net-admin-api/internal/vlan"
+	"net-admin-api/internal/webhook"
+)
+
+// Config holds all settings of the API server. Durations are written as strings, e.g. "30s".
+type Config struct {
+	Server     Server     `yaml:"server"`
+	GRPC       GRPC       `yaml:"grpc"`
+	Stores     Stores     `yaml:"stores"`
+	Auth       Auth       `yaml:"auth"`
+	TLS        TLS        `yaml:"tls"`
+	RateLimits RateLimits `yaml:"rateLimits"`
+	Webhooks   Webhooks   `yaml:"webhooks"`
+	Reconcile  Reconcile  `yaml:"reconcile"`
+	DNS        DNS        `yaml:"dns"`
+	Shutdown   Shutdown   `yaml:"shutdown"`
+}
+
+type Server struct {
+	Port           int           `yaml:"port"`
+	ReadTimeout    time.Duration `yaml:"readTimeout"`
+	WriteTimeout   time.Duration `yaml:"writeTimeout"`
+	IdleTimeout    time.Duration `yaml:"idleTimeout"`
+	MaxBodySize    int64         `yaml:"maxBodySize"`
+	EventLogSize   int           `yaml:"eventLogSize"`
+	EventHeartbeat time.Duration `yaml:"eventHeartbeat"`
+	// Whether the metrics include the series of every single VLAN, as many as there are VLANs.
+	MetricsPerVLAN bool `yaml:"metricsPerVLAN"`
+}
+
+type GRPC struct {
+	Port int `yaml:"port"`
+}
+
+// Stores are the paths of the JSON files where resources are stored.
+type Stores struct {
+	VLANs          string `yaml:"vlans"`
+	ChangeRequests string `yaml:"changeRequests"`
+	Webhooks       string `yaml:"webhooks"`
+	DHCPScopes     string `yaml:"dhcpScopes"`
+	VRFs           string `yaml:"vrfs"`
+	CustomFields   string `yaml:"customFields"`
+	// Responses of requests with an Idempotency-Key header, kept until the key expires.
+	IdempotencyKeys   string        `yaml:"idempotencyKeys"`
+	IdempotencyKeyTTL time.Duration `yaml:"idempotencyKeyTTL"`
+	// Log records after which the VLAN store is compacted into a snapshot.
+	VLANCompactAfter int `yaml:"vlanCompactAfter"`
+	// How long deleted VLANs are kept in the trash before they are purged, forever if 0.
+	VLANTrashRetention time.Duration `yaml:"vlanTrashRetention"`
+	// File of base64 encoded keys that encrypt the VLAN store, the current key first.
+	VLANEncryptionKeyPath string `yaml:"vlanEncryptionKeyPath"`
+	// The same keys separated by commas, only read from the NETADMIN_VLAN_ENCRYPTION_KEY environment variable
+	// so that they are neither in the configuration file nor printed with it.
+	VLANEncryptionKey string `yaml:"-"`
+	// Whether a plaintext VLAN store is encrypted on startup, instead of being rejected. Only meant to be set
+	// once, when encryption is enabled for an existing store.
+	VLANEncryptPlaintext bool `yaml:"vlanEncryptPlaintext"`
+}
+
+type Auth struct {
+	// JSON file of API users, authentication is disabled when empty.
+	UsersPath string `yaml:"usersPath"`
+}
+
+// TLS is disabled when CertPath is empty.
+type TLS struct {
+	CertPath       string        `yaml:"certPath"`
+	KeyPath        string        `yaml:"keyPath"`
+	ClientCAPath   string        `yaml:"clientCAPath"`
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+}
+
+type RateLimits struct {
+	Disabled   bool    `yaml:"disabled"`
+	ReadRate   float64 `yaml:"readRate"`
+	ReadBurst  int     `yaml:"readBurst"`
+	WriteRate  float64 `yaml:"writeRate"`
+	WriteBurst int     `yaml:"writeBurst"`
+}
+
+// Webhooks are the options of the webhook dispatcher, convertible to webhook.Options.
+type Webhooks struct {
+	Workers        int           `yaml:"workers"`
+	QueueSize      int           `yaml:"queueSize"`
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	Timeout        time.Duration `yaml:"timeout"`
+	HistorySize    int           `yaml:"historySize"`
+	DeadLetterSize int           `yaml:"deadLetterSize"`
+}
+
+// Reconcile is disabled when Dir is empty.
+type Reconcile struct {
+	Dir      string        `yaml:"dir"`
+	Interval time.Duration `yaml:"interval"`
+	Prune    bool          `yaml:"prune"`
+	DryRun   bool          `yaml:"dryRun"`
+}
+
+// DNS zone generation is disabled when Domain is empty.
+type DNS struct {
+	Domain      string        `yaml:"domain"`
+	Nameservers []string      `yaml:"nameservers"`
+	Hostmaster  string        `yaml:"hostmaster"`
+	TTL         time.Duration `yaml:"ttl"`
+}
+
+type Shutdown struct {
+	// How long ongoing requests may take to finish on shutdown.
+	DrainTimeout time.Duration `yaml:"drainTimeout"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:           8080,
+			ReadTimeout:    10 * time.Second,
+			WriteTimeout:   30 * time.Second,
+			IdleTimeout:    time.Minute,
+			MaxBodySize:    1 << 20,
+			EventLogSize:   1000,
+			EventHeartbeat: 15 * time.Second,
+		},
+		GRPC: GRPC{Port: 9090},
+		Stores: Stores{
+			VLANs:              "vlans.json",
+			ChangeRequests:     "change-requests.json",
+			Webhooks:           "webhooks.json",
+			DHCPScopes:         "dhcp-scopes.json",
+			VRFs:               "vrfs.json",
+			CustomFields:       "custom-fields.json",
+			IdempotencyKeys:    "idempotency-keys.json",
+			IdempotencyKeyTTL:  idempotency.DefaultTTL,
+			VLANCompactAfter:   vlan.DefaultCompactAfter,
+			VLANTrashRetention: vlan.DefaultTrashRetention,
+		},
+		TLS: TLS{ReloadInterval: time.Minute},
+		RateLimits: RateLimits{
+			ReadRate:   20,
+			ReadBurst:  40,
+			WriteRate:  2,
+			WriteBurst: 10,
+		},
+		Webhooks:  Webhooks(webhook.DefaultOptions()),
+		Reconcile: Reconcile{Interval: 30 * time.Second},
+		DNS:       DNS{Nameservers: []string{}, TTL: time.Hour},
+		Shutdown:  Shutdown{DrainTimeout: 5 * time.Second},
+	}
+}
+
+// bind defines a flag for every setting of cfg. The environment variable of a setting is named after
+// its flag, e.g. NETADMIN_VLAN_STORE_PATH for -vlan-store-path.
+func bind(fs *flag.FlagSet, cfg *Config) {
+	fs.IntVar(&cfg.Server.Port, "port", cfg.Server.Port, "port of the API server")
+	fs.DurationVar(&cfg.Server.ReadTimeout, "read-timeout", cfg.Server.ReadTimeout, "maximum duration for reading requests")
+	fs.DurationVar(&cfg.Server.WriteTimeout, "write-timeout", cfg.Server.WriteTimeout, "maximum duration for writing responses")
+	fs.DurationVar(&cfg.Server.IdleTimeout, "idle-timeout", cfg.Server.IdleTimeout, "how long idle keep-alive connections are kept open")
+	fs.Int64Var(&cfg.Server.MaxBodySize, "max-body-size", cfg.Server.MaxBodySize, "maximum size of request bodies in bytes")
+	fs.IntVar(&cfg.Server.EventLogSize, "event-log-size", cfg.Server.EventLogSize, "events kept for resuming event streams")
+	fs.DurationVar(&cfg.Server.EventHeartbeat, "event-heartbeat", cfg.Server.EventHeartbeat, "how often idle event streams send heartbeats")
+	fs.BoolVar(&cfg.Server.MetricsPerVLAN, "metrics-per-vlan", cfg.Server.MetricsPerVLAN, "include the series of every single VLAN in the metrics")
+	fs.IntVar(&cfg.GRPC.Port, "grpc-port", cfg.GRPC.Port, "port of the gRPC server")
+
+	fs.StringVar(&cfg.Stores.VLANs, "vlan-store-path", cfg.Stores.VLANs, "JSON file where VLANs are stored")
+	fs.IntVar(&cfg.Stores.VLANCompactAfter, "vlan-compact-after", cfg.Stores.VLANCompactAfter, "log records after which the VLAN store is compacted")
+	fs.DurationVar(&cfg.Stores.VLANTrashRetention, "vlan-trash-retention", cfg.Stores.VLANTrashRetention, "how long deleted VLANs are kept in the trash, forever if 0")
+	fs.StringVar(&cfg.Stores.VLANEncryptionKeyPath, "vlan-encryption-key-path", cfg.Stores.VLANEncryptionKeyPath, "file of base64 keys that encrypt the VLAN store, the current key first")
+	fs.BoolVar(&cfg.Stores.VLANEncryptPlaintext, "vlan-encrypt-plaintext", cfg.Stores.VLANEncryptPlaintext, "encrypt a plaintext VLAN store on startup instead of rejecting it")
+	fs.StringVar(&cfg.Stores.ChangeRequests, "change-request-store-path", cfg.Stores.ChangeRequests, "JSON file where change requests are stored")
+	fs.StringVar(&cfg.Stores.Webhooks, "webhook-store-path", cfg.Stores.Webhooks, "JSON file where webhook subscriptions are stored")
+	fs.StringVar(&cfg.Stores.DHCPScopes, "dhcp-store-path", cfg.Stores.DHCPScopes, "JSON file where DHCP scopes are stored")
+	fs.StringVar(&cfg.Stores.VRFs, "vrf-store-path", cfg.Stores.VRFs, "JSON file where VRFs are stored")
+	fs.StringVar(&cfg.Stores.CustomFields, "custom-field-store-path", cfg.Stores.CustomFields, "JSON file where the custom field schema is stored")
+	fs.StringVar(&cfg.Stores.IdempotencyKeys, "idempotency-key-store-path", cfg.Stores.IdempotencyKeys, "JSON file where the responses of requests with idempotency keys are stored")
+	fs.DurationVar(&cfg.Stores.IdempotencyKeyTTL, "idempotency-key-ttl", cfg.Stores.IdempotencyKeyTTL, "how long idempotency keys are kept")
+	fs.StringVar(&cfg.Auth.UsersPath, "auth-users-path", cfg.Auth.UsersPath, "JSON file of API users, enables authentication")
+
+	fs.StringVar(&cfg.TLS.CertPath, "tls-cert-path", cfg.TLS.CertPath, "PEM certificate, enables TLS")
+	fs.StringVar(&cfg.TLS.KeyPath, "tls-key-path", cfg.TLS.KeyPath, "PEM key of the certificate")
+	fs.StringVar(&cfg.TLS.ClientCAPath, "tls-client-ca-path", cfg.TLS.ClientCAPath, "PEM CA bundle that client certificates are verified against")
+	fs.DurationVar(&cfg.TLS.ReloadInterval, "tls-reload-interval", cfg.TLS.ReloadInterval, "how often certificate files are checked for rotation")
+
+	fs.BoolVar(&cfg.RateLimits.Disabled, "rate-limit-disabled", cfg.RateLimits.Disabled, "disable rate limits")
+	fs.Float64Var(&cfg.RateLimits.ReadRate, "rate-limit-read-rate", cfg.RateLimits.ReadRate, "GET requests per second per client")
+	fs.IntVar(&cfg.RateLimits.ReadBurst, "rate-limit-read-burst", cfg.RateLimits.ReadBurst, "GET request burst per client")
+	fs.Float64Var(&cfg.RateLimits.WriteRate, "rate-limit-write-rate", cfg.RateLimits.WriteRate, "other requests per second per client")
+	fs.IntVar(&cfg.RateLimits.WriteBurst, "rate-limit-write-burst", cfg.RateLimits.WriteBurst, "other request burst per client")
+
+	fs.IntVar(&cfg.Webhooks.Workers, "webhook-workers", cfg.Webhooks.Workers, "concurrent webhook deliveries")
+	fs.IntVar(&cfg.Webhooks.QueueSize, "webhook-queue-size", cfg.Webhooks.QueueSize, "queued webhook deliveries")
+	fs.IntVar(&cfg.Webhooks.MaxAttempts, "webhook-max-attempts", cfg.Webhooks.MaxAttempts, "attempts per webhook delivery")
+	fs.DurationVar(&cfg.Webhooks.InitialBackoff, "webhook-initial-backoff", cfg.Webhooks.InitialBackoff, "delay before the first webhook retry")
+	fs.DurationVar(&cfg.Webhooks.MaxBackoff, "webhook-max-backoff", cfg.Webhooks.MaxBackoff, "maximum delay between webhook retries")
+	fs.DurationVar(&cfg.Webhooks.Timeout, "webhook-timeout", cfg.Webhooks.Timeout, "timeout of a webhook delivery attempt")
+	fs.IntVar(&cfg.Webhooks.HistorySize, "webhook-history-size", cfg.Webhooks.HistorySize, "deliveries kept per webhook subscription")
+	fs.IntVar(&cfg.Webhooks.DeadLetterSize, "webhook-dead-letter-size", cfg.Webhooks.DeadLetterSize, "deliveries kept in the dead-letter list")
+
+	fs.StringVar(&cfg.Reconcile.Dir, "reconcile-dir", cfg.Reconcile.Dir, "directory of YAML VLAN definitions, enables reconciliation")
+	fs.DurationVar(&cfg.Reconcile.Interval, "reconcile-interval", cfg.Reconcile.Interval, "how often the directory is reconciled")
+	fs.BoolVar(&cfg.Reconcile.Prune, "reconcile-prune", cfg.Reconcile.Prune, "delete undeclared and adopt unmanaged VLANs")
+	fs.BoolVar(&cfg.Reconcile.DryRun, "reconcile-dry-run", cfg.Reconcile.DryRun, "only report drift")
+
+	fs.StringVar(&cfg.DNS.Domain, "dns-domain", cfg.DNS.Domain, "domain of the forward DNS zone, enables DNS zone generation")
+	fs.Var((*stringList)(&cfg.DNS.Nameservers), "dns-nameservers", "comma-separated name servers of the DNS zones, the primary first")
+	fs.StringVar(&cfg.DNS.Hostmaster, "dns-hostmaster", cfg.DNS.Hostmaster, "email address of the DNS zone administrator (default hostmaster@<domain>)")
+	fs.DurationVar(&cfg.DNS.TTL, "dns-ttl", cfg.DNS.TTL, "TTL of DNS records")
+
+	fs.DurationVar(&cfg.Shutdown.DrainTimeout, "shutdown-drain-timeout", cfg.Shutdown.DrainTimeout, "how long ongoing requests may take to finish on shutdown")
+}
+
+// stringList is a flag of comma-separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// EnvPrefix is the prefix of all environment variables of the API server, so that they do not collide with
+// variables of other programs, e.g. PORT.
+const EnvPrefix = "NETADMIN_"
+
+// EnvName returns the environment variable of the setting with the given flag name.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Options are the command-line options that are not settings.
+type Options struct {
+	// Configuration file, also set by the NETADMIN_CONFIG_PATH environment variable.
+	ConfigPath  string
+	PrintConfig bool
+}
+
+// Load returns the default configuration, overridden by the configuration file, environment variables
+// and flags, in that order. It fails if any of them is invalid, or if the resulting configuration is.
+func Load(args []string, getenv func(string) string) (*Config, Options, error) {
+	opts := Options{}
+	flags := flag.NewFlagSet("api", flag.ContinueOnError)
+	flags.StringVar(&opts.ConfigPath, "config", getenv(EnvName("config-path")), fmt.Sprintf("YAML or JSON configuration file (env %s)", EnvName("config-path")))
+	flags.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	bind(flags, Default())
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" && f.Name != "print-config" {
+			f.Usage = fmt.Sprintf("%s (env %s)", f.Usage, EnvName(f.Name))
+		}
+	})
+	if err := flags.Parse(args); err != nil {
+		return nil, opts, err
+	}
+	if flags.NArg() > 0 {
+		return nil, opts, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	cfg := Default()
+	if opts.ConfigPath != "" {
+		if err := cfg.loadFile(opts.ConfigPath); err != nil {
+			return nil, opts, err
+		}
+	}
+
+	// Settings are bound again to the loaded configuration, so that only set variables and flags override it
+	settings := flag.NewFlagSet("api", flag.ContinueOnError)
+	bind(settings, cfg)
+	errs := []error{}
+	settings.VisitAll(func(f *flag.Flag) {
+		if value := getenv(EnvName(f.Name)); value != "" {
+			if err := settings.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", EnvName(f.Name), err))
+			}
+		}
+	})
+	flags.Visit(func(f *flag.Flag) {
+		if settings.Lookup(f.Name) != nil {
+			errs = append(errs, settings.Set(f.Name, f.Value.String()))
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, opts, err
+	}
+	cfg.Stores.VLANEncryptionKey = getenv(EnvName("vlan-encryption-key"))
+
+	if err := cfg.Validate(); err != nil {
+		return nil, opts, err
+	}
+	return cfg, opts, nil
+}
+
+// loadFile overrides the configuration with the settings in a YAML or JSON file, unknown settings are errors.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %w", path, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to decode %v: %w", path, err)
+	}
+	return nil
+}
+
+// Write writes the configuration as YAML.
+func (c *Config) Write(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Validate returns all invalid settings as a single error.
+func (c *Config) Validate() error {
+	errs := []error{}
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port %d must be in range 1..65535", c.Server.Port)
+	check(validPort(c.GRPC.Port), "grpc.port %d must be in range 1..65535", c.GRPC.Port)
+	check(c.Server.Port != c.GRPC.Port, "server.port and grpc.port must differ")
+	check(c.Server.ReadTimeout > 0, "server.readTimeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.writeTimeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idleTimeout must be positive")
+	check(c.Server.MaxBodySize > 0, "server.maxBodySize must be positive")
+	check(c.Server.EventLogSize > 0, "server.eventLogSize must be positive")
+	check(c.Server.EventHeartbeat > 0, "server.eventHeartbeat must be positive")
+
+	check(c.Stores.VLANs != "", "stores.vlans must not be empty")
+	check(c.Stores.VLANCompactAfter > 0, "stores.vlanCompactAfter must be positive")
+	check(c.Stores.VLANTrashRetention >= 0, "stores.vlanTrashRetention must not be negative")
+	check(c.Stores.VLANEncryptionKeyPath == "" || c.Stores.VLANEncryptionKey == "", "stores.vlanEncryptionKeyPath and NETADMIN_VLAN_ENCRYPTION_KEY must not be set together")
+	check(!c.Stores.VLANEncryptPlaintext || c.Stores.VLANEncryptionKeyPath != "" || c.Stores.VLANEncryptionKey != "", "stores.vlanEncryptPlaintext requires stores.vlanEncryptionKeyPath or NETADMIN_VLAN_ENCRYPTION_KEY")
+	check(c.Stores.ChangeRequests != "", "stores.changeRequests must not be empty")
+	check(c.Stores.Webhooks != "", "stores.webhooks must not be empty")
+	check(c.Stores.DHCPScopes != "", "stores.dhcpScopes must not be empty")
+	check(c.Stores.VRFs != "", "stores.vrfs must not be empty")
+	check(c.Stores.CustomFields != "", "stores.customFields must not be empty")
+	check(c.Stores.IdempotencyKeys != "", "stores.idempotencyKeys must not be empty")
+	check(c.Stores.IdempotencyKeyTTL > 0, "stores.idempotencyKeyTTL must be positive")
+
+	check((c.TLS.CertPath == "") == (c.TLS.KeyPath == ""), "tls.certPath and tls.keyPath must be set together")
+	check(c.TLS.ClientCAPath == "" || c.TLS.CertPath != "", "tls.clientCAPath requires tls.certPath")
+	check(c.TLS.ReloadInterval > 0, "tls.reloadInterval must be positive")
+
+	if !c.RateLimits.Disabled {
+		check(c.RateLimits.ReadRate > 0, "rateLimits.readRate must be positive")
+		check(c.RateLimits.ReadBurst > 0, "rateLimits.readBurst must be positive")
+		check(c.RateLimits.WriteRate > 0, "rateLimits.writeRate must be positive")
+		check(c.RateLimits.WriteBurst > 0, "rateLimits.writeBurst must be positive")
+	}
+
+	check(c.Webhooks.Workers > 0, "webhooks.workers must be positive")
+	check(c.Webhooks.QueueSize > 0, "webhooks.queueSize must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.maxAttempts must be positive")
+	check(c.Webhooks.InitialBackoff > 0, "webhooks.initialBackoff must be positive")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff, "webhooks.maxBackoff must not be less than webhooks.initialBackoff")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.HistorySize > 0, "webhooks.historySize must be positive")
+	check(c.Webhooks.DeadLetterSize > 0, "webhooks.deadLetterSize must be positive")
+
+	check(c.Reconcile.Interval > 0, "reconcile.interval must be positive")
+	if c.DNS.Domain != "" {
+		check(len(c.DNS.Nameservers) > 0, "dns.nameservers must not be empty")
+		check(c.DNS.TTL >= time.Second && c.DNS.TTL%time.Second == 0, "dns.ttl must be a positive number of seconds")
+	}
+	check(c.Shutdown.DrainTimeout > 0, "shutdown.drainTimeout must be positive")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func validPort(port int) bool {
+	return port >= 1 && port <= 65535
+}
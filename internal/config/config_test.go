@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/server"
+)
+
+func TestLoad_Layers(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "config.yml", `
+server:
+  port: 8000
+  writeTimeout: 1m
+stores:
+  vlans: /data/vlans.json
+rateLimits:
+  readRate: 5
+shutdown:
+  drainTimeout: 20s
+`)
+	env := map[string]string{
+		"NETADMIN_CONFIG_PATH":          path,
+		"NETADMIN_PORT":                 "8001",
+		"NETADMIN_RATE_LIMIT_READ_RATE": "7.5",
+		"NETADMIN_RECONCILE_PRUNE":      "true",
+	}
+	cfg, opts, err := Load([]string{"-port", "8002", "-shutdown-drain-timeout", "30s"}, getenv(env))
+	require.NoError(t, err)
+	require.Equal(t, path, opts.ConfigPath)
+	require.False(t, opts.PrintConfig)
+
+	// Flags override variables, which override the file, which overrides the defaults
+	require.Equal(t, 8002, cfg.Server.Port)
+	require.Equal(t, 7.5, cfg.RateLimits.ReadRate)
+	require.True(t, cfg.Reconcile.Prune)
+	require.Equal(t, 30*time.Second, cfg.Shutdown.DrainTimeout)
+	require.Equal(t, time.Minute, cfg.Server.WriteTimeout)
+	require.Equal(t, "/data/vlans.json", cfg.Stores.VLANs)
+	require.Equal(t, Default().Server.ReadTimeout, cfg.Server.ReadTimeout)
+	require.Equal(t, Default().Webhooks, cfg.Webhooks)
+}
+
+func TestLoad_EnvPrefix(t *testing.T) {
+	t.Parallel()
+	// Variables of other programs are ignored
+	cfg, _, err := Load(nil, getenv(map[string]string{"PORT": "8001", "GRPC_PORT": "9001"}))
+	require.NoError(t, err)
+	require.Equal(t, Default(), cfg)
+	require.Equal(t, "NETADMIN_VLAN_STORE_PATH", EnvName("vlan-store-path"))
+}
+
+// The defaults of the configuration are those of the server without options.
+func TestDefault_Server(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, Server{
+		Port:           8080,
+		ReadTimeout:    server.DefaultReadTimeout,
+		WriteTimeout:   server.DefaultWriteTimeout,
+		IdleTimeout:    server.DefaultIdleTimeout,
+		MaxBodySize:    server.DefaultMaxBodySize,
+		EventLogSize:   server.DefaultEventLogSize,
+		EventHeartbeat: server.DefaultEventHeartbeat,
+	}, Default().Server)
+}
+
+func TestLoad_JSON(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "config.json", `{"grpc": {"port": 9443}, "webhooks": {"maxBackoff": "10m"}}`)
+	cfg, _, err := Load([]string{"-config", path}, getenv(nil))
+	require.NoError(t, err)
+	require.Equal(t, 9443, cfg.GRPC.Port)
+	require.Equal(t, 10*time.Minute, cfg.Webhooks.MaxBackoff)
+}
+
+func TestLoad_PrintConfig(t *testing.T) {
+	t.Parallel()
+	cfg, opts, err := Load([]string{"--print-config", "-reconcile-dir", "/etc/vlans"}, getenv(nil))
+	require.NoError(t, err)
+	require.True(t, opts.PrintConfig)
+
+	// The printed configuration can be loaded again
+	out := &bytes.Buffer{}
+	require.NoError(t, cfg.Write(out))
+	path := writeFile(t, "printed.yml", out.String())
+	reloaded, _, err := Load([]string{"-config", path}, getenv(nil))
+	require.NoError(t, err)
+	require.Equal(t, cfg, reloaded)
+}
+
+func TestLoad_DNS(t *testing.T) {
+	t.Parallel()
+	path := writeFile(t, "config.yml", "dns:\n  domain: site.example\n  nameservers: [ns1.site.example.]\n")
+	env := map[string]string{"NETADMIN_DNS_NAMESERVERS": "ns1.site.example., ns2.site.example."}
+	cfg, _, err := Load([]string{"-config", path, "-dns-ttl", "5m"}, getenv(env))
+	require.NoError(t, err)
+	require.Equal(t, DNS{
+		Domain:      "site.example",
+		Nameservers: []string{"ns1.site.example.", "ns2.site.example."},
+		TTL:         5 * time.Minute,
+	}, cfg.DNS)
+}
+
+func TestLoad_EncryptionKey(t *testing.T) {
+	t.Parallel()
+	cfg, _, err := Load(nil, getenv(map[string]string{"NETADMIN_VLAN_ENCRYPTION_KEY": "c2VjcmV0"}))
+	require.NoError(t, err)
+	require.Equal(t, "c2VjcmV0", cfg.Stores.VLANEncryptionKey)
+
+	// The key is never printed
+	out := &bytes.Buffer{}
+	require.NoError(t, cfg.Write(out))
+	require.NotContains(t, out.String(), "c2VjcmV0")
+}
+
+func TestLoad_NOK(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		file  string
+		args  []string
+		env   map[string]string
+		error string
+	}{
+		{name: "unknown flag", args: []string{"-colour"}, error: "flag provided but not defined"},
+		{name: "argument", args: []string{"serve"}, error: `unexpected argument "serve"`},
+		{name: "invalid variable", env: map[string]string{"NETADMIN_PORT": "http"}, error: "invalid NETADMIN_PORT"},
+		{name: "invalid flag", args: []string{"-reconcile-interval", "often"}, error: "invalid value"},
+		{name: "missing file", args: []string{"-config", "/nonexistent.yml"}, error: "failed to read"},
+		{name: "unknown setting", file: "server:\n  prot: 8000\n", error: "field prot not found"},
+		{name: "invalid duration", file: "shutdown:\n  drainTimeout: soon\n", error: "failed to decode"},
+		{name: "invalid port", args: []string{"-port", "70000"}, error: "server.port 70000 must be in range 1..65535"},
+		{name: "same ports", args: []string{"-grpc-port", "8080"}, error: "server.port and grpc.port must differ"},
+		{name: "key without cert", args: []string{"-tls-key-path", "tls.key"}, error: "tls.certPath and tls.keyPath must be set together"},
+		{name: "negative timeout", env: map[string]string{"NETADMIN_SHUTDOWN_DRAIN_TIMEOUT": "-1s"}, error: "shutdown.drainTimeout must be positive"},
+		{name: "negative retention", args: []string{"-vlan-trash-retention", "-1h"}, error: "stores.vlanTrashRetention must not be negative"},
+		{
+			name:  "two encryption keys",
+			args:  []string{"-vlan-encryption-key-path", "keys"},
+			env:   map[string]string{"NETADMIN_VLAN_ENCRYPTION_KEY": "c2VjcmV0"},
+			error: "stores.vlanEncryptionKeyPath and NETADMIN_VLAN_ENCRYPTION_KEY must not be set together",
+		},
+		{
+			name:  "plaintext migration without key",
+			env:   map[string]string{"NETADMIN_VLAN_ENCRYPT_PLAINTEXT": "true"},
+			error: "stores.vlanEncryptPlaintext requires stores.vlanEncryptionKeyPath or NETADMIN_VLAN_ENCRYPTION_KEY",
+		},
+		{name: "dns without nameservers", args: []string{"-dns-domain", "site.example"}, error: "dns.nameservers must not be empty"},
+		{name: "dns ttl", args: []string{"-dns-domain", "site.example", "-dns-nameservers", "ns1.", "-dns-ttl", "1500ms"}, error: "dns.ttl must be a positive number of seconds"},
+		{name: "backoff", file: "webhooks:\n  initialBackoff: 1m\n  maxBackoff: 1s\n", error: "webhooks.maxBackoff"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := test.args
+			if test.file != "" {
+				args = append(args, "-config", writeFile(t, "config.yml", test.file))
+			}
+			_, _, err := Load(args, getenv(test.env))
+			require.ErrorContains(t, err, test.error)
+		})
+	}
+}
+
+func TestValidate_AllErrors(t *testing.T) {
+	t.Parallel()
+	cfg := Default()
+	cfg.Server.ReadTimeout = 0
+	cfg.Stores.VLANs = ""
+	cfg.RateLimits.WriteBurst = 0
+	err := cfg.Validate()
+	require.ErrorContains(t, err, "server.readTimeout")
+	require.ErrorContains(t, err, "stores.vlans")
+	require.ErrorContains(t, err, "rateLimits.writeBurst")
+
+	// Rate limits are not validated when disabled
+	cfg = Default()
+	cfg.RateLimits = RateLimits{Disabled: true}
+	require.NoError(t, cfg.Validate())
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func getenv(env map[string]string) func(string) string {
+	return func(name string) string {
+		return env[name]
+	}
+}
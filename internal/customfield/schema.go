@@ -0,0 +1,225 @@
+// Package customfield defines the custom fields that admins add to VLANs, and checks the values of VLANs against
+// them.
+package customfield
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+type Type string
+
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeEnum   Type = "enum"
+	TypeIP     Type = "ip"
+	TypePrefix Type = "prefix"
+	TypeBool   Type = "bool"
+)
+
+var Types = []Type{TypeString, TypeInt, TypeEnum, TypeIP, TypePrefix, TypeBool}
+
+// Field is the definition of a custom field. Values of int fields are JSON numbers, values of bool fields JSON
+// booleans, and all other values strings.
+type Field struct {
+	Name        string `json:"name"`
+	Type        Type   `json:"type"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Regular expression that the whole value of a string field must match.
+	Pattern string `json:"pattern,omitempty"`
+	// Allowed values of an enum field.
+	Values []string `json:"values,omitempty"`
+
+	// The compiled Pattern, set by Validate.
+	pattern *regexp.Regexp
+}
+
+// Schema is the set of custom fields that VLANs may have.
+type Schema struct {
+	Fields []Field `json:"fields"`
+}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,63}$`)
+
+// ValidName reports whether a custom field name is valid: up to 64 letters, digits or '_', starting with a letter.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Validate returns the errors of the field definition. It compiles the pattern of the field once, so that checking
+// values does not compile it again.
+func (f *Field) Validate() []string {
+	errors := make([]string, 0)
+	if !ValidName(f.Name) {
+		errors = append(errors, fmt.Sprintf("invalid field name %q (expected up to 64 letters, digits or '_', starting with a letter)", f.Name))
+	}
+	if !slices.Contains(Types, f.Type) {
+		errors = append(errors, fmt.Sprintf("unknown type %q of field %s (expected one of %v)", f.Type, f.Name, Types))
+	}
+	if f.Pattern != "" {
+		if f.Type != TypeString {
+			errors = append(errors, fmt.Sprintf("pattern of field %s requires type %s", f.Name, TypeString))
+		} else if pattern, err := compile(f.Pattern); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid pattern of field %s: %v", f.Name, err))
+		} else {
+			f.pattern = pattern
+		}
+	}
+	switch {
+	case f.Type == TypeEnum && len(f.Values) == 0:
+		errors = append(errors, fmt.Sprintf("enum field %s must have values", f.Name))
+	case f.Type != TypeEnum && len(f.Values) > 0:
+		errors = append(errors, fmt.Sprintf("values of field %s require type %s", f.Name, TypeEnum))
+	}
+	for i, value := range f.Values {
+		if slices.Contains(f.Values[:i], value) {
+			errors = append(errors, fmt.Sprintf("duplicate value %q of field %s", value, f.Name))
+		}
+	}
+	return errors
+}
+
+func (s *Schema) Validate() []string {
+	errors := make([]string, 0)
+	for i := range s.Fields {
+		field := &s.Fields[i]
+		errors = append(errors, field.Validate()...)
+		if slices.ContainsFunc(s.Fields[:i], func(other Field) bool { return other.Name == field.Name }) {
+			errors = append(errors, fmt.Sprintf("duplicate field %s", field.Name))
+		}
+	}
+	return errors
+}
+
+// Check checks custom field values against the schema: required fields must be set, every value must be of the
+// type of its field, and there must be no values of undefined fields.
+func (s *Schema) Check(values map[string]any) []string {
+	errors := make([]string, 0)
+	for _, field := range s.Fields {
+		value, ok := values[field.Name]
+		if !ok {
+			if field.Required {
+				errors = append(errors, fmt.Sprintf("custom field %s is required", field.Name))
+			}
+			continue
+		}
+		if err := field.check(value); err != "" {
+			errors = append(errors, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if !slices.ContainsFunc(s.Fields, func(field Field) bool { return field.Name == name }) {
+			errors = append(errors, fmt.Sprintf("unknown custom field %s", name))
+		}
+	}
+	return errors
+}
+
+// check returns why a value is not valid for the field, or an empty string.
+func (f *Field) check(value any) string {
+	invalid := fmt.Sprintf("invalid value %s of custom field %s (expected %s)", Format(value), f.Name, f.Type)
+	if f.Type == TypeInt {
+		if _, ok := asInt(value); !ok {
+			return invalid
+		}
+		return ""
+	}
+	if f.Type == TypeBool {
+		if _, ok := value.(bool); !ok {
+			return invalid
+		}
+		return ""
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return invalid
+	}
+	switch f.Type {
+	case TypeString:
+		if f.Pattern != "" {
+			pattern := f.pattern
+			if pattern == nil {
+				// Fields that were not validated compile their pattern on every check
+				pattern, _ = compile(f.Pattern)
+			}
+			if pattern == nil || !pattern.MatchString(str) {
+				return fmt.Sprintf("value %q of custom field %s must match %s", str, f.Name, f.Pattern)
+			}
+		}
+	case TypeEnum:
+		if !slices.Contains(f.Values, str) {
+			return fmt.Sprintf("invalid value %q of custom field %s (expected one of %v)", str, f.Name, f.Values)
+		}
+	case TypeIP:
+		if _, err := netip.ParseAddr(str); err != nil {
+			return invalid
+		}
+	case TypePrefix:
+		if _, err := netip.ParsePrefix(str); err != nil {
+			return invalid
+		}
+	}
+	return ""
+}
+
+// compile compiles a pattern that must match the whole value.
+func compile(pattern string) (*regexp.Regexp, error) {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+// asInt returns the value of an integer, which is a float64 if it was decoded from JSON.
+func asInt(value any) (int64, bool) {
+	switch value := value.(type) {
+	case int:
+		return int64(value), true
+	case int64:
+		return value, true
+	case float64:
+		if value != math.Trunc(value) || math.Abs(value) > 1<<53 {
+			return 0, false
+		}
+		return int64(value), true
+	}
+	return 0, false
+}
+
+// Format returns a value the way it is given in queries, e.g. 9000 or true.
+func Format(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// Normalize returns values the way they are decoded from JSON, with numbers as float64, so that VLANs compare
+// equal before and after they are stored. It returns nil if there are no values.
+func Normalize(values map[string]any) map[string]any {
+	if len(values) == 0 {
+		return nil
+	}
+	normalized := make(map[string]any, len(values))
+	for name, value := range values {
+		switch number := value.(type) {
+		case int:
+			value = float64(number)
+		case int64:
+			value = float64(number)
+		}
+		normalized[name] = value
+	}
+	return normalized
+}
@@ -0,0 +1,154 @@
+package customfield
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		schema Schema
+		errors []string
+	}{
+		{
+			name: "valid",
+			schema: Schema{Fields: []Field{
+				{Name: "site", Type: TypeEnum, Values: []string{"ber1", "fra1"}, Required: true},
+				{Name: "owner", Type: TypeString, Pattern: `[a-z]+@example\.com`},
+				{Name: "rack", Type: TypeInt},
+				{Name: "router", Type: TypeIP},
+				{Name: "aggregate", Type: TypePrefix},
+				{Name: "monitored", Type: TypeBool},
+			}},
+		},
+		{
+			name: "invalid",
+			schema: Schema{Fields: []Field{
+				{Name: "1st", Type: TypeString},
+				{Name: "size", Type: "float"},
+				{Name: "rack", Type: TypeInt, Pattern: "[0-9]+"},
+				{Name: "owner", Type: TypeString, Pattern: "("},
+				{Name: "site", Type: TypeEnum},
+				{Name: "zone", Type: TypeString, Values: []string{"a"}},
+				{Name: "env", Type: TypeEnum, Values: []string{"prod", "prod"}},
+				{Name: "rack", Type: TypeInt},
+			}},
+			errors: []string{
+				`invalid field name "1st" (expected up to 64 letters, digits or '_', starting with a letter)`,
+				`unknown type "float" of field size (expected one of [string int enum ip prefix bool])`,
+				"pattern of field rack requires type string",
+				"invalid pattern of field owner: error parsing regexp: missing closing ): `(`",
+				"enum field site must have values",
+				"values of field zone require type enum",
+				`duplicate value "prod" of field env`,
+				"duplicate field rack",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			errors := test.schema.Validate()
+			if test.errors == nil {
+				require.Empty(t, errors)
+			} else {
+				require.Equal(t, test.errors, errors)
+			}
+		})
+	}
+}
+
+func TestSchema_Check(t *testing.T) {
+	t.Parallel()
+	schema := Schema{Fields: []Field{
+		{Name: "site", Type: TypeEnum, Values: []string{"ber1", "fra1"}, Required: true},
+		{Name: "owner", Type: TypeString, Pattern: `[a-z]+@example\.com`},
+		{Name: "rack", Type: TypeInt},
+		{Name: "router", Type: TypeIP},
+		{Name: "aggregate", Type: TypePrefix},
+		{Name: "monitored", Type: TypeBool},
+	}}
+
+	tests := []struct {
+		name   string
+		values map[string]any
+		errors []string
+	}{
+		{
+			name: "valid",
+			values: map[string]any{
+				"site":      "ber1",
+				"owner":     "noc@example.com",
+				"rack":      float64(12),
+				"router":    "2001:db8::1",
+				"aggregate": "10.0.0.0/8",
+				"monitored": true,
+			},
+		},
+		{
+			name:   "only required fields",
+			values: map[string]any{"site": "fra1"},
+		},
+		{
+			name: "invalid values",
+			values: map[string]any{
+				"owner":     "noc@example.com.evil",
+				"rack":      12.5,
+				"router":    "10.0.0.256",
+				"aggregate": 8,
+				"monitored": "yes",
+				"color":     "red",
+			},
+			errors: []string{
+				"custom field site is required",
+				`value "noc@example.com.evil" of custom field owner must match [a-z]+@example\.com`,
+				"invalid value 12.5 of custom field rack (expected int)",
+				"invalid value 10.0.0.256 of custom field router (expected ip)",
+				"invalid value 8 of custom field aggregate (expected prefix)",
+				"invalid value yes of custom field monitored (expected bool)",
+				"unknown custom field color",
+			},
+		},
+		{
+			name:   "invalid enum value",
+			values: map[string]any{"site": "muc1"},
+			errors: []string{`invalid value "muc1" of custom field site (expected one of [ber1 fra1])`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			errors := schema.Check(test.values)
+			if test.errors == nil {
+				require.Empty(t, errors)
+			} else {
+				require.Equal(t, test.errors, errors)
+			}
+		})
+	}
+}
+
+func TestField_Pattern(t *testing.T) {
+	t.Parallel()
+	unvalidated := Field{Name: "owner", Type: TypeString, Pattern: `[a-z]+@example\.com`}
+	validated := unvalidated
+	require.Empty(t, validated.Validate())
+	require.NotNil(t, validated.pattern)
+
+	// Validated fields check values with the compiled pattern, others compile it for every check
+	for _, field := range []Field{validated, unvalidated} {
+		require.Empty(t, field.check("noc@example.com"))
+		require.Equal(t, `value "noc@example.com.evil" of custom field owner must match [a-z]+@example\.com`,
+			field.check("noc@example.com.evil"))
+	}
+
+	// Schemas compile the patterns of their fields
+	schema := Schema{Fields: []Field{unvalidated}}
+	require.Empty(t, schema.Validate())
+	require.NotNil(t, schema.Fields[0].pattern)
+}
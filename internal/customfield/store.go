@@ -0,0 +1,61 @@
+package customfield
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"net-admin-api/internal/jsonfile"
+)
+
+// Store manages a JSON file to persist the custom field schema.
+type Store struct {
+	path   string
+	schema Schema
+	mu     sync.RWMutex
+}
+
+func NewStore(path string) (*Store, error) {
+	store := &Store{
+		path:   path,
+		schema: Schema{Fields: []Field{}},
+	}
+
+	// store file does not exist
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := jsonfile.Write(path, store.schema); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	// store file exists
+	if err := jsonfile.Read(path, &store.schema); err != nil {
+		return nil, err
+	}
+	if errors := store.schema.Validate(); len(errors) > 0 {
+		return nil, fmt.Errorf("invalid custom field schema in %s: %s", path, strings.Join(errors, ", "))
+	}
+	return store, nil
+}
+
+// Get returns the current schema.
+func (s *Store) Get() Schema {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Schema{Fields: slices.Clone(s.schema.Fields)}
+}
+
+// Save replaces the schema, which must be valid.
+func (s *Store) Save(schema Schema) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := jsonfile.Write(s.path, schema); err != nil {
+		return err
+	}
+	s.schema = schema
+	return nil
+}
@@ -0,0 +1,191 @@
+package dhcp
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"net-admin-api/internal/vlan"
+)
+
+// Family selects the Kea server, DHCPv4 or DHCPv6, and the VLANs of the matching address family.
+type Family string
+
+const (
+	FamilyDHCP4 Family = "dhcp4"
+	FamilyDHCP6 Family = "dhcp6"
+)
+
+var Families = []Family{FamilyDHCP4, FamilyDHCP6}
+
+// Subnet is a scope together with the VLAN it belongs to.
+type Subnet struct {
+	VLAN  vlan.VLAN
+	Scope Scope
+}
+
+type keaServer struct {
+	InterfacesConfig keaInterfaces    `json:"interfaces-config"`
+	LeaseDatabase    keaLeaseDatabase `json:"lease-database"`
+	ValidLifetime    int              `json:"valid-lifetime"`
+	Subnet4          *[]keaSubnet     `json:"subnet4,omitempty"`
+	Subnet6          *[]keaSubnet     `json:"subnet6,omitempty"`
+}
+
+type keaInterfaces struct {
+	Interfaces []string `json:"interfaces"`
+}
+
+type keaLeaseDatabase struct {
+	Type    string `json:"type"`
+	Persist bool   `json:"persist"`
+	Name    string `json:"name"`
+}
+
+type keaSubnet struct {
+	ID            uint16           `json:"id"`
+	Subnet        string           `json:"subnet"`
+	ValidLifetime int              `json:"valid-lifetime"`
+	Pools         []keaPool        `json:"pools"`
+	OptionData    []keaOption      `json:"option-data,omitempty"`
+	Reservations  []keaReservation `json:"reservations,omitempty"`
+	UserContext   keaUserContext   `json:"user-context"`
+}
+
+type keaPool struct {
+	Pool string `json:"pool"`
+}
+
+type keaOption struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+type keaReservation struct {
+	HWAddress   string   `json:"hw-address"`
+	IPAddress   string   `json:"ip-address,omitempty"`
+	IPAddresses []string `json:"ip-addresses,omitempty"`
+	Hostname    string   `json:"hostname,omitempty"`
+}
+
+// keaUserContext links Kea subnets back to their VLANs.
+type keaUserContext struct {
+	VLANID string `json:"vlan-id"`
+	VLAN   string `json:"vlan"`
+}
+
+// RenderKea renders a complete Kea configuration of the subnets of the family, ordered by VID. A Kea server serves
+// one routing domain, so the subnets must be of VLANs in the same VRF. The VID is the Kea subnet ID, which keeps
+// leases attached to their subnet across changes, so it must be unique, and the subnets must not overlap, see
+// vlan.CheckConflicts.
+func RenderKea(family Family, subnets []Subnet) ([]byte, error) {
+	subnets = slices.DeleteFunc(slices.Clone(subnets), func(subnet Subnet) bool {
+		return !family.Matches(subnet.VLAN.Subnet)
+	})
+	slices.SortFunc(subnets, func(a, b Subnet) int {
+		return cmp.Compare(a.VLAN.VID, b.VLAN.VID)
+	})
+
+	vlans := make([]vlan.VLAN, len(subnets))
+	for i, subnet := range subnets {
+		if subnet.VLAN.VRF != subnets[0].VLAN.VRF {
+			return nil, fmt.Errorf("vlans %s and %s are in different vrfs", subnets[0].VLAN.Name, subnet.VLAN.Name)
+		}
+		vlans[i] = subnet.VLAN
+	}
+	if err := vlan.CheckConflicts(vlans); err != nil {
+		return nil, err
+	}
+	keaSubnets := make([]keaSubnet, 0, len(subnets))
+	for _, subnet := range subnets {
+		keaSubnets = append(keaSubnets, renderSubnet(family, subnet))
+	}
+
+	server := keaServer{
+		InterfacesConfig: keaInterfaces{Interfaces: []string{"*"}},
+		LeaseDatabase: keaLeaseDatabase{
+			Type:    "memfile",
+			Persist: true,
+			Name:    fmt.Sprintf("/var/lib/kea/kea-leases%s.csv", strings.TrimPrefix(string(family), "dhcp")),
+		},
+		ValidLifetime: DefaultLeaseTime,
+	}
+	root := "Dhcp4"
+	if family == FamilyDHCP4 {
+		server.Subnet4 = &keaSubnets
+	} else {
+		root = "Dhcp6"
+		server.Subnet6 = &keaSubnets
+	}
+	return json.MarshalIndent(map[string]keaServer{root: server}, "", "  ")
+}
+
+func renderSubnet(family Family, subnet Subnet) keaSubnet {
+	scope := subnet.Scope
+	rendered := keaSubnet{
+		ID:            subnet.VLAN.VID,
+		Subnet:        subnet.VLAN.Subnet.Masked().String(),
+		ValidLifetime: scope.LeaseTime(),
+		Pools:         make([]keaPool, len(scope.Pools)),
+		UserContext:   keaUserContext{VLANID: subnet.VLAN.ID.String(), VLAN: subnet.VLAN.Name},
+	}
+	for i, pool := range scope.Pools {
+		rendered.Pools[i] = keaPool{Pool: fmt.Sprintf("%s - %s", pool.Start, pool.End)}
+	}
+
+	dnsServers := make([]string, len(scope.Options.DNSServers))
+	for i, server := range scope.Options.DNSServers {
+		dnsServers[i] = server.String()
+	}
+	if family == FamilyDHCP4 {
+		rendered.OptionData = append(rendered.OptionData, keaOption{Name: "routers", Data: subnet.VLAN.Gateway.String()})
+		if len(dnsServers) > 0 {
+			rendered.OptionData = append(rendered.OptionData, keaOption{Name: "domain-name-servers", Data: strings.Join(dnsServers, ", ")})
+		}
+		if scope.Options.Domain != "" {
+			rendered.OptionData = append(rendered.OptionData, keaOption{Name: "domain-name", Data: scope.Options.Domain})
+		}
+	} else {
+		// IPv6 hosts learn their router from router advertisements, not from DHCPv6
+		if len(dnsServers) > 0 {
+			rendered.OptionData = append(rendered.OptionData, keaOption{Name: "dns-servers", Data: strings.Join(dnsServers, ", ")})
+		}
+		if scope.Options.Domain != "" {
+			rendered.OptionData = append(rendered.OptionData, keaOption{Name: "domain-search", Data: scope.Options.Domain})
+		}
+	}
+
+	reservations := slices.SortedFunc(slices.Values(scope.Reservations), func(a, b Reservation) int {
+		return a.IP.Compare(b.IP)
+	})
+	for _, reservation := range reservations {
+		rendered.Reservations = append(rendered.Reservations, renderReservation(family, reservation))
+	}
+	return rendered
+}
+
+func renderReservation(family Family, reservation Reservation) keaReservation {
+	rendered := keaReservation{HWAddress: normalizeMAC(reservation.MAC), Hostname: reservation.Hostname}
+	if family == FamilyDHCP4 {
+		rendered.IPAddress = reservation.IP.String()
+	} else {
+		rendered.IPAddresses = []string{reservation.IP.String()}
+	}
+	return rendered
+}
+
+func normalizeMAC(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return mac
+}
+
+// Matches reports whether the family serves the addresses of a prefix.
+func (f Family) Matches(prefix netip.Prefix) bool {
+	return prefix.Addr().Is4() == (f == FamilyDHCP4)
+}
@@ -0,0 +1,90 @@
+package dhcp
+
+import (
+	"flag"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRenderKea(t *testing.T) {
+	t.Parallel()
+	subnets := []Subnet{
+		{
+			VLAN: newVLAN(20, "servers", "192.168.20.0/24", "192.168.20.1"),
+			Scope: Scope{
+				Pools: []Pool{newPool("192.168.20.100", "192.168.20.199")},
+				Reservations: []Reservation{
+					{MAC: "00-11-22-33-44-56", IP: addr("192.168.20.11"), Hostname: "db"},
+					{MAC: "00:11:22:33:44:55", IP: addr("192.168.20.10"), Hostname: "web"},
+				},
+				Options: Options{
+					DNSServers: []netip.Addr{addr("192.168.20.2"), addr("192.168.20.3")},
+					Domain:     "servers.example.com",
+					LeaseTime:  86400,
+				},
+			},
+		},
+		{
+			VLAN:  newVLAN(10, "users", "192.168.10.0/24", "192.168.10.1"),
+			Scope: Scope{Pools: []Pool{newPool("192.168.10.100", "192.168.10.149"), newPool("192.168.10.150", "192.168.10.254")}},
+		},
+		{
+			VLAN: newVLAN(30, "lab", "2001:db8:30::/64", "2001:db8:30::1"),
+			Scope: Scope{
+				Pools:        []Pool{newPool("2001:db8:30::1000", "2001:db8:30::1fff")},
+				Reservations: []Reservation{{MAC: "00:11:22:33:44:57", IP: addr("2001:db8:30::10"), Hostname: "scope"}},
+				Options:      Options{DNSServers: []netip.Addr{addr("2001:db8:30::53")}, Domain: "lab.example.com"},
+			},
+		},
+	}
+
+	for _, family := range Families {
+		t.Run(string(family), func(t *testing.T) {
+			config, err := RenderKea(family, subnets)
+			require.NoError(t, err)
+			requireGolden(t, filepath.Join("testdata", "kea-"+string(family)+".json"), config)
+		})
+	}
+}
+
+func TestRenderKea_Conflicts(t *testing.T) {
+	t.Parallel()
+	subnets := []Subnet{
+		{VLAN: newVLAN(10, "users", "192.168.10.0/24", "192.168.10.1")},
+		{VLAN: newVLAN(10, "guests", "192.168.11.0/24", "192.168.11.1")},
+	}
+	_, err := RenderKea(FamilyDHCP4, subnets)
+	require.ErrorIs(t, err, vlan.ErrDuplicateVID)
+
+	// VLANs of the other family are not rendered
+	_, err = RenderKea(FamilyDHCP6, subnets)
+	require.NoError(t, err)
+
+	subnets[1].VLAN = newVLAN(11, "cameras", "192.168.10.64/26", "192.168.10.65")
+	_, err = RenderKea(FamilyDHCP4, subnets)
+	require.ErrorIs(t, err, vlan.ErrOverlap)
+
+	// One configuration only serves one VRF
+	subnets[1].VLAN.VRF = "red"
+	_, err = RenderKea(FamilyDHCP4, subnets)
+	require.ErrorContains(t, err, "vlans users and cameras are in different vrfs")
+}
+
+// requireGolden compares data to a golden file, which is rewritten instead when running with -update.
+func requireGolden(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if *update {
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	golden, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(data))
+}
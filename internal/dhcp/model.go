@@ -0,0 +1,164 @@
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/vlan"
+)
+
+// DefaultLeaseTime is the lease time in seconds of scopes that do not set one.
+const DefaultLeaseTime = 3600
+
+// Scope is the DHCP configuration of a VLAN, served on the VLAN subnet with its gateway as the router.
+type Scope struct {
+	VLANID       uuid.UUID     `json:"vlanId"`
+	Pools        []Pool        `json:"pools"`
+	Reservations []Reservation `json:"reservations,omitempty"`
+	Options      Options       `json:"options"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+}
+
+// Pool is an inclusive range of dynamically leased addresses.
+type Pool struct {
+	Start netip.Addr `json:"start"`
+	End   netip.Addr `json:"end"`
+}
+
+// Reservation always leases the same address to a MAC address.
+type Reservation struct {
+	MAC      string     `json:"mac"`
+	IP       netip.Addr `json:"ip"`
+	Hostname string     `json:"hostname,omitempty"`
+}
+
+type Options struct {
+	DNSServers []netip.Addr `json:"dnsServers,omitempty"`
+	Domain     string       `json:"domain,omitempty"`
+	// Lease time in seconds, DefaultLeaseTime if zero.
+	LeaseTime int `json:"leaseTime,omitempty"`
+}
+
+var domainPattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// Validate checks the scope against the VLAN it belongs to. Pools and reservations must be in the VLAN
+// subnet, and must not overlap each other or the addresses reserved in it: the gateway, and for IPv4 the
+// network and broadcast addresses.
+func (s *Scope) Validate(v vlan.VLAN) []string {
+	errors := make([]string, 0)
+	subnet := v.Subnet.Masked()
+	reserved := reservedAddrs(v)
+
+	for i, pool := range s.Pools {
+		switch {
+		case !subnet.Contains(pool.Start) || !subnet.Contains(pool.End):
+			errors = append(errors, fmt.Sprintf("pool %s must belong to subnet %s", pool, subnet))
+		case pool.End.Less(pool.Start):
+			errors = append(errors, fmt.Sprintf("pool %s must not end before it starts", pool))
+		default:
+			for _, addr := range reserved {
+				if pool.Contains(addr) {
+					errors = append(errors, fmt.Sprintf("pool %s must not contain reserved address %s", pool, addr))
+				}
+			}
+			for _, other := range s.Pools[:i] {
+				if pool.Overlaps(other) {
+					errors = append(errors, fmt.Sprintf("pool %s overlaps pool %s", pool, other))
+				}
+			}
+		}
+	}
+
+	macs := map[string]bool{}
+	ips := map[netip.Addr]bool{}
+	for _, reservation := range s.Reservations {
+		mac, err := net.ParseMAC(reservation.MAC)
+		if err != nil || len(mac) != 6 {
+			errors = append(errors, fmt.Sprintf("invalid MAC address %q", reservation.MAC))
+		} else if macs[mac.String()] {
+			errors = append(errors, fmt.Sprintf("duplicate reservation for MAC address %s", mac))
+		} else {
+			macs[mac.String()] = true
+		}
+
+		switch {
+		case !subnet.Contains(reservation.IP):
+			errors = append(errors, fmt.Sprintf("reservation %s must belong to subnet %s", reservation.IP, subnet))
+		case slices.Contains(reserved, reservation.IP):
+			errors = append(errors, fmt.Sprintf("reservation %s must not be a reserved address", reservation.IP))
+		case ips[reservation.IP]:
+			errors = append(errors, fmt.Sprintf("duplicate reservation for %s", reservation.IP))
+		}
+		ips[reservation.IP] = true
+
+		if reservation.Hostname != "" && !domainPattern.MatchString(reservation.Hostname) {
+			errors = append(errors, fmt.Sprintf("invalid hostname %q", reservation.Hostname))
+		}
+	}
+
+	for _, server := range s.Options.DNSServers {
+		if !server.IsValid() || server.Is4() != subnet.Addr().Is4() {
+			errors = append(errors, fmt.Sprintf("DNS server %s must be an %s address", server, family(subnet)))
+		}
+	}
+	if s.Options.Domain != "" && !domainPattern.MatchString(s.Options.Domain) {
+		errors = append(errors, fmt.Sprintf("invalid domain %q", s.Options.Domain))
+	}
+	if s.Options.LeaseTime < 0 {
+		errors = append(errors, "lease time must not be negative")
+	}
+	return errors
+}
+
+// LeaseTime returns the lease time of the scope in seconds.
+func (s *Scope) LeaseTime() int {
+	if s.Options.LeaseTime == 0 {
+		return DefaultLeaseTime
+	}
+	return s.Options.LeaseTime
+}
+
+func (p Pool) String() string {
+	return fmt.Sprintf("%s-%s", p.Start, p.End)
+}
+
+func (p Pool) Contains(addr netip.Addr) bool {
+	return addr.BitLen() == p.Start.BitLen() && p.Start.Compare(addr) <= 0 && addr.Compare(p.End) <= 0
+}
+
+func (p Pool) Overlaps(other Pool) bool {
+	return p.Start.Compare(other.End) <= 0 && other.Start.Compare(p.End) <= 0
+}
+
+// reservedAddrs returns the addresses of a VLAN subnet that must not be leased.
+func reservedAddrs(v vlan.VLAN) []netip.Addr {
+	reserved := []netip.Addr{v.Gateway}
+	subnet := v.Subnet.Masked()
+	if subnet.Addr().Is4() && subnet.Bits() < 31 {
+		reserved = append(reserved, subnet.Addr(), lastAddr(subnet))
+	}
+	return reserved
+}
+
+// lastAddr returns the last address of a prefix, the broadcast address of IPv4 subnets.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(addr)*8; i++ {
+		addr[i/8] |= 1 << (7 - i%8)
+	}
+	last, _ := netip.AddrFromSlice(addr)
+	return last
+}
+
+func family(prefix netip.Prefix) string {
+	if prefix.Addr().Is4() {
+		return "IPv4"
+	}
+	return "IPv6"
+}
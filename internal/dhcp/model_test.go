@@ -0,0 +1,135 @@
+package dhcp
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+)
+
+func TestScope_Validate(t *testing.T) {
+	t.Parallel()
+	vlan4 := newVLAN(10, "users", "192.168.10.0/24", "192.168.10.1")
+	vlan6 := newVLAN(20, "servers", "2001:db8:20::/64", "2001:db8:20::1")
+
+	tests := []struct {
+		name   string
+		vlan   vlan.VLAN
+		scope  Scope
+		errors []string
+	}{
+		{
+			name: "valid IPv4",
+			vlan: vlan4,
+			scope: Scope{
+				Pools: []Pool{newPool("192.168.10.100", "192.168.10.149"), newPool("192.168.10.150", "192.168.10.254")},
+				Reservations: []Reservation{
+					{MAC: "00:11:22:33:44:55", IP: addr("192.168.10.10"), Hostname: "printer"},
+					{MAC: "00-11-22-33-44-56", IP: addr("192.168.10.11")},
+				},
+				Options: Options{DNSServers: []netip.Addr{addr("192.168.10.2")}, Domain: "users.example.com", LeaseTime: 600},
+			},
+		},
+		{
+			name:  "valid IPv6",
+			vlan:  vlan6,
+			scope: Scope{Pools: []Pool{newPool("2001:db8:20::1000", "2001:db8:20::1fff")}},
+		},
+		{
+			name:  "IPv4 /31 has no network and broadcast addresses",
+			vlan:  newVLAN(30, "p2p", "10.0.0.0/31", "10.0.0.0"),
+			scope: Scope{Pools: []Pool{newPool("10.0.0.1", "10.0.0.1")}},
+		},
+		{
+			name: "invalid pools",
+			vlan: vlan4,
+			scope: Scope{Pools: []Pool{
+				newPool("192.168.11.1", "192.168.11.10"),
+				newPool("192.168.10.20", "192.168.10.10"),
+				newPool("192.168.10.0", "192.168.10.5"),
+				newPool("192.168.10.200", "192.168.10.255"),
+				newPool("192.168.10.100", "192.168.10.150"),
+				newPool("192.168.10.150", "192.168.10.160"),
+			}},
+			errors: []string{
+				"pool 192.168.11.1-192.168.11.10 must belong to subnet 192.168.10.0/24",
+				"pool 192.168.10.20-192.168.10.10 must not end before it starts",
+				"pool 192.168.10.0-192.168.10.5 must not contain reserved address 192.168.10.1",
+				"pool 192.168.10.0-192.168.10.5 must not contain reserved address 192.168.10.0",
+				"pool 192.168.10.200-192.168.10.255 must not contain reserved address 192.168.10.255",
+				"pool 192.168.10.150-192.168.10.160 overlaps pool 192.168.10.100-192.168.10.150",
+			},
+		},
+		{
+			name: "invalid reservations",
+			vlan: vlan4,
+			scope: Scope{Reservations: []Reservation{
+				{MAC: "00:11:22:33:44:55", IP: addr("192.168.10.10")},
+				{MAC: "00:11:22:33:44:55", IP: addr("192.168.10.11")},
+				{MAC: "invalid", IP: addr("192.168.10.10")},
+				{MAC: "00:11:22:33:44:57", IP: addr("192.168.10.1")},
+				{MAC: "00:11:22:33:44:58", IP: addr("10.0.0.1"), Hostname: "-printer"},
+			}},
+			errors: []string{
+				"duplicate reservation for MAC address 00:11:22:33:44:55",
+				`invalid MAC address "invalid"`,
+				"duplicate reservation for 192.168.10.10",
+				"reservation 192.168.10.1 must not be a reserved address",
+				"reservation 10.0.0.1 must belong to subnet 192.168.10.0/24",
+				`invalid hostname "-printer"`,
+			},
+		},
+		{
+			name: "invalid options",
+			vlan: vlan6,
+			scope: Scope{Options: Options{
+				DNSServers: []netip.Addr{addr("192.168.10.2")},
+				Domain:     "example..com",
+				LeaseTime:  -1,
+			}},
+			errors: []string{
+				"DNS server 192.168.10.2 must be an IPv6 address",
+				`invalid domain "example..com"`,
+				"lease time must not be negative",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := tt.scope.Validate(tt.vlan)
+			if tt.errors == nil {
+				require.Empty(t, errors)
+			} else {
+				require.Equal(t, tt.errors, errors)
+			}
+		})
+	}
+}
+
+func TestScope_LeaseTime(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, DefaultLeaseTime, (&Scope{}).LeaseTime())
+	require.Equal(t, 600, (&Scope{Options: Options{LeaseTime: 600}}).LeaseTime())
+}
+
+func newVLAN(vid uint16, name, subnet, gateway string) vlan.VLAN {
+	return vlan.VLAN{
+		ID:      uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)),
+		VID:     vid,
+		Name:    name,
+		Subnet:  netip.MustParsePrefix(subnet),
+		Gateway: netip.MustParseAddr(gateway),
+		Status:  "active",
+	}
+}
+
+func newPool(start, end string) Pool {
+	return Pool{Start: addr(start), End: addr(end)}
+}
+
+func addr(s string) netip.Addr {
+	return netip.MustParseAddr(s)
+}
@@ -0,0 +1,131 @@
+package dhcp
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/encryption"
+	"net-admin-api/internal/jsonfile"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Store manages a JSON file to persist DHCP scopes, one per VLAN.
+type Store struct {
+	path           string
+	scopesByVLANID map[uuid.UUID]Scope
+	mu             sync.RWMutex
+	keyring        *encryption.Keyring
+}
+
+// Additional data of the sealed store file, so that it cannot be passed off as the file of another store.
+var fileAAD = []byte("net-admin-api dhcp scopes")
+
+type StoreOption func(*Store)
+
+// WithEncryption encrypts the store file with the current key of the keyring, since pools and reservations reveal
+// the subnets of their VLANs. Plaintext files, and files encrypted with a previous key, are encrypted with the
+// current key on startup.
+func WithEncryption(keyring *encryption.Keyring) StoreOption {
+	return func(s *Store) {
+		s.keyring = keyring
+	}
+}
+
+func NewStore(path string, opts ...StoreOption) (*Store, error) {
+	store := &Store{
+		path: path,
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	// store file does not exist
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		store.scopesByVLANID = make(map[uuid.UUID]Scope, 0)
+		if err := store.writeScopes(); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	// store file exists
+	stale, err := store.readScopes()
+	if err != nil {
+		return nil, err
+	}
+	if stale {
+		if err := store.writeScopes(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// List returns the scopes ordered by VLAN ID.
+func (s *Store) List() []Scope {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.SortedFunc(maps.Values(s.scopesByVLANID), func(a, b Scope) int {
+		return slices.Compare(a.VLANID[:], b.VLANID[:])
+	})
+}
+
+func (s *Store) Get(vlanID uuid.UUID) *Scope {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if scope, ok := s.scopesByVLANID[vlanID]; ok {
+		return &scope
+	}
+	return nil
+}
+
+// Save creates or replaces the scope of a VLAN.
+func (s *Store) Save(scope Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scopesByVLANID[scope.VLANID] = scope
+	return s.writeScopes()
+}
+
+func (s *Store) Delete(vlanID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.scopesByVLANID[vlanID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.scopesByVLANID, vlanID)
+	return s.writeScopes()
+}
+
+func (s *Store) readScopes() (bool, error) {
+	scopes := []Scope{}
+	stale, err := jsonfile.ReadSealed(s.path, s.keyring, fileAAD, &scopes)
+	if err != nil {
+		return false, err
+	}
+
+	scopesByVLANID := make(map[uuid.UUID]Scope, len(scopes))
+	for _, scope := range scopes {
+		if scope.VLANID == uuid.Nil {
+			return false, fmt.Errorf("invalid DHCP scope in %s: missing VLAN ID", s.path)
+		}
+		scopesByVLANID[scope.VLANID] = scope
+	}
+	s.scopesByVLANID = scopesByVLANID
+	return stale, nil
+}
+
+func (s *Store) writeScopes() error {
+	return jsonfile.WriteSealed(s.path, s.keyring, fileAAD, slices.Collect(maps.Values(s.scopesByVLANID)))
+}
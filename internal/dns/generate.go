@@ -0,0 +1,249 @@
+package dns
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"net-admin-api/internal/vlan"
+)
+
+// SOA timers in seconds.
+const (
+	Refresh     = 3600
+	Retry       = 600
+	Expire      = 604800
+	NegativeTTL = 300
+)
+
+// Options configure the generated zones.
+type Options struct {
+	// Domain of the forward zone, VLAN hosts are named <host>.vlan<VID>.<domain>.
+	Domain string
+	// Authoritative name servers of all zones, the first one is the primary.
+	Nameservers []string
+	// Email address of the zone administrator, hostmaster@<domain> if empty.
+	Hostmaster string
+	TTL        uint32
+	Serial     uint32
+}
+
+// Network is a VLAN and the hosts named in it, in addition to its gateway.
+type Network struct {
+	VLAN  vlan.VLAN
+	Hosts []Host
+}
+
+// Host is a name of an address. The name is relative to the VLAN, and may have several labels.
+type Host struct {
+	Name string
+	Addr netip.Addr
+}
+
+// GatewayHost is the name of the gateway of every VLAN.
+const GatewayHost = "gw"
+
+// Generate returns the forward zone of the domain, followed by the reverse zones of the VLAN subnets ordered by
+// name. The zones serve one routing domain, so the VLANs must be in the same VRF, and must neither share VIDs nor
+// overlap, see vlan.CheckConflicts, since their records would conflict.
+//
+// IPv4 subnets are covered by /8, /16 or /24 reverse zones, and IPv6 subnets by nibble-aligned reverse zones.
+// Longer IPv4 prefixes get a zone named <network>-<bits> in their /24 zone, which is delegated to it with NS
+// records and CNAME records for the named addresses, as described in RFC 2317.
+func Generate(opts Options, networks []Network) ([]Zone, error) {
+	vlans := make([]vlan.VLAN, len(networks))
+	for i, network := range networks {
+		if network.VLAN.VRF != networks[0].VLAN.VRF {
+			return nil, fmt.Errorf("vlans %s and %s are in different vrfs", networks[0].VLAN.Name, network.VLAN.Name)
+		}
+		vlans[i] = network.VLAN
+	}
+	if err := vlan.CheckConflicts(vlans); err != nil {
+		return nil, err
+	}
+
+	generator := &generator{opts: opts, zones: map[string]*Zone{}}
+	forward := generator.zone(fqdn(opts.Domain))
+
+	networks = slices.SortedFunc(slices.Values(networks), func(a, b Network) int {
+		return cmp.Or(cmp.Compare(a.VLAN.VID, b.VLAN.VID), a.VLAN.Subnet.Addr().Compare(b.VLAN.Subnet.Addr()))
+	})
+	for _, network := range networks {
+		subnet := network.VLAN.Subnet.Masked()
+		for _, zone := range reverseZones(subnet) {
+			generator.zone(zone)
+		}
+		if classless(subnet) {
+			generator.delegate(subnet)
+		}
+
+		hosts := append([]Host{{Name: GatewayHost, Addr: network.VLAN.Gateway}}, network.Hosts...)
+		for _, host := range hosts {
+			if !subnet.Contains(host.Addr) {
+				continue
+			}
+			name := fmt.Sprintf("%s.vlan%d.%s", strings.ToLower(host.Name), network.VLAN.VID, forward.Name)
+			recordType := "A"
+			if host.Addr.Is6() {
+				recordType = "AAAA"
+			}
+			generator.add(forward, name, recordType, host.Addr.String())
+			generator.addPTR(subnet, host.Addr, name)
+		}
+	}
+
+	delete(generator.zones, forward.Name)
+	names := append([]string{forward.Name}, slices.SortedFunc(maps.Keys(generator.zones), compareNames)...)
+	generator.zones[forward.Name] = forward
+	zones := []Zone{}
+	for _, name := range names {
+		zone := generator.zones[name]
+		sortRecords(zone.Records)
+		zone.Records = slices.CompactFunc(zone.Records, func(a, b Record) bool { return a == b })
+		zones = append(zones, *zone)
+	}
+	return zones, nil
+}
+
+type generator struct {
+	opts  Options
+	zones map[string]*Zone
+}
+
+// zone returns the zone of the given name, which is created with its SOA and NS records if it is new.
+func (g *generator) zone(name string) *Zone {
+	if zone, ok := g.zones[name]; ok {
+		return zone
+	}
+	zone := &Zone{Name: name}
+	g.zones[name] = zone
+
+	primary := "."
+	if len(g.opts.Nameservers) > 0 {
+		primary = fqdn(g.opts.Nameservers[0])
+	}
+	hostmaster := g.opts.Hostmaster
+	if hostmaster == "" {
+		hostmaster = "hostmaster@" + strings.TrimSuffix(g.opts.Domain, ".")
+	}
+	soa := fmt.Sprintf("%s %s %d %d %d %d %d", primary, mailbox(hostmaster), g.opts.Serial, Refresh, Retry, Expire, NegativeTTL)
+	g.add(zone, name, "SOA", soa)
+	for _, nameserver := range g.opts.Nameservers {
+		g.add(zone, name, "NS", fqdn(nameserver))
+	}
+	return zone
+}
+
+func (g *generator) add(zone *Zone, name, recordType, data string) {
+	zone.Records = append(zone.Records, Record{Name: name, TTL: g.opts.TTL, Type: recordType, Data: data})
+}
+
+// delegate delegates the classless reverse zone of an IPv4 subnet from its /24 zone.
+func (g *generator) delegate(subnet netip.Prefix) {
+	parent := g.zone(reverseName(subnet.Addr(), 24))
+	for _, nameserver := range g.opts.Nameservers {
+		g.add(parent, classlessZone(subnet), "NS", fqdn(nameserver))
+	}
+}
+
+func (g *generator) addPTR(subnet netip.Prefix, addr netip.Addr, target string) {
+	if !classless(subnet) {
+		g.add(g.zone(reverseZoneOf(subnet, addr)), reverseName(addr, addr.BitLen()), "PTR", target)
+		return
+	}
+	last := addr.As4()[3]
+	zone := g.zone(classlessZone(subnet))
+	name := fmt.Sprintf("%d.%s", last, zone.Name)
+	g.add(zone, name, "PTR", target)
+	g.add(g.zone(reverseName(subnet.Addr(), 24)), reverseName(addr, 32), "CNAME", name)
+}
+
+// classless reports whether a subnet needs a classless reverse zone, because it is a part of a /24.
+func classless(subnet netip.Prefix) bool {
+	return subnet.Addr().Is4() && subnet.Bits() > 24
+}
+
+// classlessZone returns the name of the reverse zone of an IPv4 subnet longer than /24, e.g.
+// 64-26.10.168.192.in-addr.arpa. for 192.168.10.64/26. RFC 2317 uses a slash, which is not a valid hostname
+// character and is therefore replaced by a hyphen, a common alternative.
+func classlessZone(subnet netip.Prefix) string {
+	return fmt.Sprintf("%d-%d.%s", subnet.Addr().As4()[3], subnet.Bits(), reverseName(subnet.Addr(), 24))
+}
+
+// zoneBits returns the length of the reverse zones of a subnet, rounded up to a label boundary.
+func zoneBits(subnet netip.Prefix) int {
+	labelBits := 8
+	if subnet.Addr().Is6() {
+		labelBits = 4
+	}
+	return max(labelBits, (subnet.Bits()+labelBits-1)/labelBits*labelBits)
+}
+
+// reverseZones returns the names of the reverse zones that cover a subnet, e.g. the 16 /24 zones of a /20.
+func reverseZones(subnet netip.Prefix) []string {
+	if classless(subnet) {
+		return []string{classlessZone(subnet)}
+	}
+	bits := zoneBits(subnet)
+	zones := []string{}
+	for addr := subnet.Addr(); subnet.Contains(addr); {
+		zones = append(zones, reverseName(addr, bits))
+		next := lastAddr(netip.PrefixFrom(addr, bits)).Next()
+		if !next.IsValid() {
+			break
+		}
+		addr = next
+	}
+	return zones
+}
+
+// reverseZoneOf returns the name of the reverse zone of an address in a subnet that is not classless.
+func reverseZoneOf(subnet netip.Prefix, addr netip.Addr) string {
+	return reverseName(addr, zoneBits(subnet))
+}
+
+// reverseName returns the reverse lookup name of the first bits of an address, which must be a multiple of
+// 8 for IPv4 and of 4 for IPv6.
+func reverseName(addr netip.Addr, bits int) string {
+	labels := []string{}
+	bytes := addr.AsSlice()
+	if addr.Is4() {
+		for _, b := range bytes[:bits/8] {
+			labels = append(labels, fmt.Sprint(b))
+		}
+		slices.Reverse(labels)
+		return strings.Join(append(labels, "in-addr.arpa."), ".")
+	}
+	for i := 0; i < bits/4; i++ {
+		nibble := bytes[i/2] >> 4
+		if i%2 == 1 {
+			nibble = bytes[i/2] & 0x0f
+		}
+		labels = append(labels, fmt.Sprintf("%x", nibble))
+	}
+	slices.Reverse(labels)
+	return strings.Join(append(labels, "ip6.arpa."), ".")
+}
+
+// lastAddr returns the last address of a prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(addr)*8; i++ {
+		addr[i/8] |= 1 << (7 - i%8)
+	}
+	last, _ := netip.AddrFromSlice(addr)
+	return last
+}
+
+// mailbox returns the domain name form of an email address used in SOA records, e.g.
+// hostmaster.example.com. for hostmaster@example.com. Dots in the local part are escaped.
+func mailbox(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return fqdn(email)
+	}
+	return strings.ReplaceAll(local, ".", `\.`) + "." + fqdn(domain)
+}
@@ -0,0 +1,147 @@
+package dns
+
+import (
+	"bytes"
+	"flag"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	zones, err := Generate(testOptions(), testNetworks())
+	require.NoError(t, err)
+
+	names := []string{}
+	zoneFiles := bytes.Buffer{}
+	for _, zone := range zones {
+		names = append(names, zone.Name)
+		zoneFiles.Write(zone.ZoneFile())
+		zoneFiles.WriteByte('\n')
+	}
+	require.Equal(t, []string{
+		"site.example.",
+		"40.10.in-addr.arpa.",
+		"10.168.192.in-addr.arpa.",
+		"11.168.192.in-addr.arpa.",
+		"64-26.11.168.192.in-addr.arpa.",
+		"20.168.192.in-addr.arpa.",
+		"21.168.192.in-addr.arpa.",
+		"0.0.0.0.0.3.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		"0.0.0.0.0.4.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		"1.0.0.0.0.4.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	}, names)
+	requireGolden(t, filepath.Join("testdata", "zones.golden"), zoneFiles.Bytes())
+}
+
+func TestGenerate_Conflicts(t *testing.T) {
+	t.Parallel()
+	networks := testNetworks()
+	networks[1].VLAN.VID = 10
+	_, err := Generate(testOptions(), networks)
+	require.ErrorIs(t, err, vlan.ErrDuplicateVID)
+
+	networks[1].VLAN = newVLAN(11, "cameras", "192.168.10.64/26", "192.168.10.65")
+	_, err = Generate(testOptions(), networks)
+	require.ErrorIs(t, err, vlan.ErrOverlap)
+
+	// Zones only serve one VRF
+	networks[1].VLAN.VRF = "red"
+	_, err = Generate(testOptions(), networks)
+	require.ErrorContains(t, err, "vlans users and cameras are in different vrfs")
+}
+
+func TestReverseZones(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		subnet string
+		zones  []string
+	}{
+		{subnet: "10.0.0.0/8", zones: []string{"10.in-addr.arpa."}},
+		{subnet: "172.16.0.0/15", zones: []string{"16.172.in-addr.arpa.", "17.172.in-addr.arpa."}},
+		{subnet: "192.168.10.0/24", zones: []string{"10.168.192.in-addr.arpa."}},
+		{subnet: "192.168.10.128/25", zones: []string{"128-25.10.168.192.in-addr.arpa."}},
+		{subnet: "192.168.10.4/30", zones: []string{"4-30.10.168.192.in-addr.arpa."}},
+		{subnet: "2001:db8::/32", zones: []string{"8.b.d.0.1.0.0.2.ip6.arpa."}},
+		{subnet: "2001:db8:0:10::/63", zones: []string{"0.1.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "1.1.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subnet, func(t *testing.T) {
+			require.Equal(t, tt.zones, reverseZones(netip.MustParsePrefix(tt.subnet)))
+		})
+	}
+	require.Len(t, reverseZones(netip.MustParsePrefix("10.0.0.0/9")), 128)
+}
+
+func TestMailbox(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "hostmaster.site.example.", mailbox("hostmaster@site.example"))
+	require.Equal(t, `john\.doe.site.example.`, mailbox("john.doe@site.example"))
+	require.Equal(t, "hostmaster.site.example.", mailbox("hostmaster.site.example"))
+}
+
+func testOptions() Options {
+	return Options{
+		Domain:      "site.example",
+		Nameservers: []string{"ns1.site.example.", "ns2.site.example."},
+		Hostmaster:  "hostmaster@site.example",
+		TTL:         3600,
+		Serial:      2024010101,
+	}
+}
+
+func testNetworks() []Network {
+	return []Network{
+		{
+			VLAN: newVLAN(10, "users", "192.168.10.0/24", "192.168.10.1"),
+			Hosts: []Host{
+				{Name: "printer", Addr: netip.MustParseAddr("192.168.10.10")},
+				{Name: "NAS", Addr: netip.MustParseAddr("192.168.10.2")},
+				// Outside of the subnet, ignored
+				{Name: "other", Addr: netip.MustParseAddr("192.168.12.1")},
+			},
+		},
+		{
+			VLAN:  newVLAN(11, "cameras", "192.168.11.64/26", "192.168.11.65"),
+			Hosts: []Host{{Name: "cam1", Addr: netip.MustParseAddr("192.168.11.70")}},
+		},
+		{VLAN: newVLAN(20, "servers", "192.168.20.0/23", "192.168.20.1")},
+		{VLAN: newVLAN(30, "lab", "2001:db8:30::/64", "2001:db8:30::1")},
+		{VLAN: newVLAN(40, "transfer", "10.40.0.0/16", "10.40.0.1")},
+		{
+			VLAN:  newVLAN(50, "iot", "2001:db8:40::/63", "2001:db8:40::1"),
+			Hosts: []Host{{Name: "sensor.floor1", Addr: netip.MustParseAddr("2001:db8:40:1::10")}},
+		},
+	}
+}
+
+func newVLAN(vid uint16, name, subnet, gateway string) vlan.VLAN {
+	return vlan.VLAN{
+		ID:      uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)),
+		VID:     vid,
+		Name:    name,
+		Subnet:  netip.MustParsePrefix(subnet),
+		Gateway: netip.MustParseAddr(gateway),
+		Status:  "active",
+	}
+}
+
+// requireGolden compares data to a golden file, which is rewritten instead when running with -update.
+func requireGolden(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if *update {
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	golden, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(data))
+}
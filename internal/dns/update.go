@@ -0,0 +1,84 @@
+package dns
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChangeOp is the operation of a Change.
+type ChangeOp string
+
+const (
+	ChangeAdd    ChangeOp = "add"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// Change adds or deletes a record in the update section of a dynamic update.
+type Change struct {
+	Op     ChangeOp
+	Record Record
+}
+
+// Update is a dynamic update of a zone as described in RFC 2136.
+type Update struct {
+	Zone string
+	// SOA record that the zone must still have, so that the update is rejected if the zone changed since
+	// it was read. Nil if the current zone was read without its SOA record.
+	Prerequisite *Record
+	Changes      []Change
+}
+
+// Diff returns the update from the current records of a zone to the desired zone. SOA records are left to the
+// server, which increments the serial on every update. A record whose TTL changed is deleted and added again.
+func Diff(current []Record, desired Zone) (Update, error) {
+	update := Update{Zone: desired.Name}
+	currentTTLs := map[string]uint32{}
+	for _, record := range current {
+		if !inZone(record.Name, desired.Name) {
+			return Update{}, fmt.Errorf("record %s is not in zone %s", record.Name, desired.Name)
+		}
+		if record.Type == "SOA" {
+			if record.Name == desired.Name {
+				update.Prerequisite = &record
+			}
+			continue
+		}
+		currentTTLs[record.key()] = record.TTL
+	}
+
+	desiredTTLs := map[string]uint32{}
+	for _, record := range desired.Records {
+		if record.Type != "SOA" {
+			desiredTTLs[record.key()] = record.TTL
+		}
+	}
+
+	current = slices.Clone(current)
+	sortRecords(current)
+	for _, record := range slices.CompactFunc(current, func(a, b Record) bool { return a == b }) {
+		if ttl, ok := desiredTTLs[record.key()]; record.Type != "SOA" && (!ok || ttl != record.TTL) {
+			update.Changes = append(update.Changes, Change{Op: ChangeDelete, Record: record})
+		}
+	}
+	for _, record := range desired.Records {
+		if ttl, ok := currentTTLs[record.key()]; record.Type != "SOA" && (!ok || ttl != record.TTL) {
+			update.Changes = append(update.Changes, Change{Op: ChangeAdd, Record: record})
+		}
+	}
+	return update, nil
+}
+
+// Script returns the update as input of nsupdate, which sends it to the primary name server of the zone.
+func (u *Update) Script() []byte {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "zone %s\n", u.Zone)
+	if u.Prerequisite != nil {
+		fmt.Fprintf(&builder, "prereq yxrrset %s IN SOA %s\n", u.Prerequisite.Name, u.Prerequisite.Data)
+	}
+	for _, change := range u.Changes {
+		fmt.Fprintf(&builder, "update %s %s\n", change.Op, strings.ReplaceAll(change.Record.String(), "\t", " "))
+	}
+	builder.WriteString("send\n")
+	return []byte(builder.String())
+}
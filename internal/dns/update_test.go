@@ -0,0 +1,69 @@
+package dns
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	zones, err := Generate(testOptions(), testNetworks())
+	require.NoError(t, err)
+	zone := zones[2]
+	require.Equal(t, "10.168.192.in-addr.arpa.", zone.Name)
+
+	// Zone transfer as printed by dig, with relative names, a stale record and a changed TTL
+	current, err := ParseZone(strings.NewReader(`
+; <<>> DiG <<>> AXFR 10.168.192.in-addr.arpa.
+10.168.192.in-addr.arpa. 3600 IN SOA ns1.site.example. hostmaster.site.example. 2023120101 3600 600 604800 300
+10.168.192.in-addr.arpa. 3600 IN NS ns1.site.example.
+10.168.192.in-addr.arpa. 3600 IN NS ns2.site.example.
+$ORIGIN 10.168.192.in-addr.arpa.
+$TTL 3600
+1        IN PTR gw.vlan10.site.example.
+2  300   IN PTR NAS.vlan10.site.example.
+3           PTR old.vlan10.site.example.
+         IN TXT "still in use"
+64-26       NS  ns1.site.example.
+10.168.192.in-addr.arpa. 3600 IN SOA ns1.site.example. hostmaster.site.example. 2023120101 3600 600 604800 300
+`), zone.Name)
+	require.NoError(t, err)
+	require.Len(t, current, 9)
+
+	update, err := Diff(current, zone)
+	require.NoError(t, err)
+	requireGolden(t, filepath.Join("testdata", "update.golden"), update.Script())
+
+	// No changes once applied
+	update, err = Diff(zone.Records, zone)
+	require.NoError(t, err)
+	require.Empty(t, update.Changes)
+	require.Equal(t, zone.Records[0], *update.Prerequisite)
+}
+
+func TestDiff_NOK(t *testing.T) {
+	t.Parallel()
+	zones, err := Generate(testOptions(), testNetworks())
+	require.NoError(t, err)
+	zone := zones[0]
+
+	_, err = Diff([]Record{{Name: "gw.vlan10.other.example.", TTL: 3600, Type: "A", Data: "192.168.10.1"}}, zone)
+	require.EqualError(t, err, "record gw.vlan10.other.example. is not in zone site.example.")
+}
+
+func TestParseZone_NOK(t *testing.T) {
+	t.Parallel()
+	for zoneFile, expected := range map[string]string{
+		"  IN A 192.168.10.1":               "line 1: missing name",
+		"gw 3600 IN A":                      "line 1: missing type or data",
+		"gw 3600 IN A 2001:db8::1":          `line 1: invalid A record data "2001:db8::1"`,
+		"gw 3600 IN CNAME a. b.":            `line 1: invalid CNAME record data "a. b."`,
+		"\n$TTL one hour\ngw IN A 10.0.0.1": "line 2: invalid $TTL",
+	} {
+		_, err := ParseZone(strings.NewReader(zoneFile), "site.example")
+		require.EqualError(t, err, expected, zoneFile)
+	}
+}
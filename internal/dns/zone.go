@@ -0,0 +1,218 @@
+// Package dns generates forward and reverse DNS zones of VLANs, and the dynamic updates (RFC 2136) that bring
+// a DNS server in line with them.
+package dns
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Record is a resource record. Names are fully qualified and lower case.
+type Record struct {
+	Name string
+	TTL  uint32
+	Type string
+	Data string
+}
+
+func (r Record) String() string {
+	return fmt.Sprintf("%s\t%d\tIN\t%s\t%s", r.Name, r.TTL, r.Type, r.Data)
+}
+
+// key identifies a record regardless of its TTL.
+func (r Record) key() string {
+	return r.Name + " " + r.Type + " " + r.Data
+}
+
+// Zone is a DNS zone, its SOA record first.
+type Zone struct {
+	Name    string
+	Records []Record
+}
+
+// FindZone returns the zone of the given name, with or without trailing dot, or nil.
+func FindZone(zones []Zone, name string) *Zone {
+	name = fqdn(name)
+	for i := range zones {
+		if zones[i].Name == name {
+			return &zones[i]
+		}
+	}
+	return nil
+}
+
+// ZoneFile returns the zone in the master file format of RFC 1035, one record per line with absolute names.
+func (z *Zone) ZoneFile() []byte {
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, "$ORIGIN %s\n", z.Name)
+	for _, record := range z.Records {
+		builder.WriteString(record.String())
+		builder.WriteByte('\n')
+	}
+	return []byte(builder.String())
+}
+
+// sortRecords orders records canonically by name, the SOA and NS records of a name first, then by type and data.
+func sortRecords(records []Record) {
+	rank := func(record Record) int {
+		switch record.Type {
+		case "SOA":
+			return 0
+		case "NS":
+			return 1
+		}
+		return 2
+	}
+	slices.SortFunc(records, func(a, b Record) int {
+		return cmp.Or(
+			compareNames(a.Name, b.Name),
+			cmp.Compare(rank(a), rank(b)),
+			strings.Compare(a.Type, b.Type),
+			strings.Compare(a.Data, b.Data),
+		)
+	})
+}
+
+// compareNames compares names label by label from the root, like the canonical order of RFC 4034, except that
+// numeric labels are compared as numbers so that reverse zones are ordered by address.
+func compareNames(a, b string) int {
+	aLabels := strings.Split(strings.TrimSuffix(a, "."), ".")
+	bLabels := strings.Split(strings.TrimSuffix(b, "."), ".")
+	slices.Reverse(aLabels)
+	slices.Reverse(bLabels)
+	for i := 0; i < len(aLabels) && i < len(bLabels); i++ {
+		aNumber, aErr := strconv.Atoi(aLabels[i])
+		bNumber, bErr := strconv.Atoi(bLabels[i])
+		if aErr == nil && bErr == nil {
+			if c := cmp.Compare(aNumber, bNumber); c != 0 {
+				return c
+			}
+		} else if c := strings.Compare(aLabels[i], bLabels[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(aLabels), len(bLabels))
+}
+
+// ParseZone parses the records of a zone file, e.g. a zone transfer printed by dig. Names are relative to
+// $ORIGIN unless they end with a dot, lines that start with a blank belong to the previous name, and records
+// without a TTL have the TTL of $TTL. Records that span lines in parentheses are not supported.
+func ParseZone(reader io.Reader, origin string) ([]Record, error) {
+	origin = fqdn(origin)
+	ttl := uint32(0)
+	previous := ""
+	records := []Record{}
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), ";")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "$ORIGIN":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: invalid $ORIGIN", line)
+			}
+			origin = fqdn(fields[1])
+			continue
+		case "$TTL":
+			value, err := parseTTL(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid $TTL", line)
+			}
+			ttl = value
+			continue
+		}
+
+		record := Record{TTL: ttl}
+		if text[0] == ' ' || text[0] == '\t' {
+			if previous == "" {
+				return nil, fmt.Errorf("line %d: missing name", line)
+			}
+			record.Name = previous
+		} else {
+			record.Name = absoluteName(fields[0], origin)
+			fields = fields[1:]
+		}
+		previous = record.Name
+
+		// TTL and class may come in either order
+		for len(fields) > 0 {
+			if value, err := parseTTL(fields[:1]); err == nil {
+				record.TTL = value
+			} else if !strings.EqualFold(fields[0], "IN") {
+				break
+			}
+			fields = fields[1:]
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing type or data", line)
+		}
+		record.Type = strings.ToUpper(fields[0])
+		data, err := normalizeData(record.Type, fields[1:], origin)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		record.Data = data
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func parseTTL(fields []string) (uint32, error) {
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("invalid TTL")
+	}
+	ttl, err := strconv.ParseUint(fields[0], 10, 32)
+	return uint32(ttl), err
+}
+
+// normalizeData formats record data the way it is generated, so that records can be compared.
+func normalizeData(recordType string, fields []string, origin string) (string, error) {
+	switch recordType {
+	case "A", "AAAA":
+		addr, err := netip.ParseAddr(fields[0])
+		if err != nil || len(fields) != 1 || addr.Is4() != (recordType == "A") {
+			return "", fmt.Errorf("invalid %s record data %q", recordType, strings.Join(fields, " "))
+		}
+		return addr.String(), nil
+	case "CNAME", "NS", "PTR":
+		if len(fields) != 1 {
+			return "", fmt.Errorf("invalid %s record data %q", recordType, strings.Join(fields, " "))
+		}
+		return absoluteName(fields[0], origin), nil
+	}
+	return strings.Join(fields, " "), nil
+}
+
+func absoluteName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(name + "." + origin)
+}
+
+// fqdn returns a name in lower case with a trailing dot.
+func fqdn(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".") + ".")
+}
+
+// inZone reports whether a name is the zone name or below it.
+func inZone(name, zone string) bool {
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
@@ -0,0 +1,209 @@
+// Package encryption seals data at rest with AES-256-GCM envelope encryption. Every message is encrypted
+// with a random data key, which is itself encrypted with the current key of a Keyring.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Algorithm identifies the encryption of Sealed messages.
+const Algorithm = "AES-256-GCM"
+
+// KeySize is the size of keys in bytes.
+const KeySize = 32
+
+var (
+	// ErrUnknownKey is returned for messages sealed with a key that is not in the keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrDecrypt is returned when a message fails authentication, because it is corrupt or the key is wrong.
+	ErrDecrypt = errors.New("failed to decrypt")
+)
+
+// Key is an AES-256 key, identified by a fingerprint that does not reveal it.
+type Key struct {
+	ID  string
+	key []byte
+}
+
+// ParseKey parses a base64 encoded key.
+func ParseKey(encoded string) (Key, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return Key{}, errors.New("key must be base64 encoded")
+	}
+	if len(key) != KeySize {
+		return Key{}, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	fingerprint := sha256.Sum256(key)
+	return Key{ID: hex.EncodeToString(fingerprint[:4]), key: key}, nil
+}
+
+// Keyring seals messages with its current key, and opens messages sealed with any of its keys.
+type Keyring struct {
+	keys []Key
+}
+
+// NewKeyring returns a keyring of the current key followed by previous keys, which are kept to open
+// messages sealed before a key rotation.
+func NewKeyring(current Key, previous ...Key) *Keyring {
+	return &Keyring{keys: append([]Key{current}, previous...)}
+}
+
+// ParseKeyring parses base64 encoded keys separated by commas or newlines, the current key first.
+func ParseKeyring(encoded string) (*Keyring, error) {
+	fields := strings.FieldsFunc(encoded, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+	keys := []Key{}
+	for i, field := range fields {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		key, err := ParseKey(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d: %w", i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys")
+	}
+	return NewKeyring(keys[0], keys[1:]...), nil
+}
+
+// LoadKeyring reads a keyring from a file of keys as accepted by ParseKeyring.
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", path, err)
+	}
+	keyring, err := ParseKeyring(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keys from %v: %w", path, err)
+	}
+	return keyring, nil
+}
+
+// CurrentKeyID returns the ID of the key that seals new messages.
+func (k *Keyring) CurrentKeyID() string {
+	return k.keys[0].ID
+}
+
+// Sealed is an encrypted message. Byte fields are base64 encoded in JSON.
+type Sealed struct {
+	Algorithm string `json:"encryption"`
+	KeyID     string `json:"keyID"`
+	// Data key encrypted with the key, prefixed by its nonce.
+	WrappedKey []byte `json:"wrappedKey"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// IsSealed reports whether data is the JSON encoding of a Sealed message.
+func IsSealed(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return false
+	}
+	header := struct {
+		Algorithm *string `json:"encryption"`
+	}{}
+	return json.Unmarshal(data, &header) == nil && header.Algorithm != nil
+}
+
+// Seal encrypts plaintext with a new data key. The additional data is authenticated but not encrypted,
+// so that a message cannot be passed off as a message of another kind.
+func (k *Keyring) Seal(plaintext, additionalData []byte) (Sealed, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Sealed{}, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	current := k.keys[0]
+	wrappedKey, err := seal(current.key, dataKey, []byte(current.ID))
+	if err != nil {
+		return Sealed{}, err
+	}
+	sealedData, err := seal(dataKey, plaintext, additionalData)
+	if err != nil {
+		return Sealed{}, err
+	}
+	return Sealed{
+		Algorithm:  Algorithm,
+		KeyID:      current.ID,
+		WrappedKey: wrappedKey,
+		Nonce:      sealedData[:nonceSize],
+		Ciphertext: sealedData[nonceSize:],
+	}, nil
+}
+
+// Open decrypts a message sealed with any key of the keyring.
+func (k *Keyring) Open(sealed Sealed, additionalData []byte) ([]byte, error) {
+	if sealed.Algorithm != Algorithm {
+		return nil, fmt.Errorf("unsupported encryption %q", sealed.Algorithm)
+	}
+	i := slices.IndexFunc(k.keys, func(key Key) bool { return key.ID == sealed.KeyID })
+	if i < 0 {
+		ids := make([]string, len(k.keys))
+		for i, key := range k.keys {
+			ids[i] = key.ID
+		}
+		return nil, fmt.Errorf("%w %s, the configured keys are %s", ErrUnknownKey, sealed.KeyID, strings.Join(ids, ", "))
+	}
+	key := k.keys[i]
+
+	dataKey, err := open(key.key, sealed.WrappedKey, []byte(key.ID))
+	if err != nil {
+		return nil, fmt.Errorf("%w data key with key %s: %w", ErrDecrypt, key.ID, err)
+	}
+	plaintext, err := open(dataKey, append(slices.Clip(sealed.Nonce), sealed.Ciphertext...), additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w data with key %s: %w", ErrDecrypt, key.ID, err)
+	}
+	return plaintext, nil
+}
+
+const nonceSize = 12
+
+// seal encrypts plaintext with AES-GCM and a random nonce, and returns the nonce followed by the ciphertext.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize, nonceSize+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < nonceSize {
+		return nil, errors.New("message too short")
+	}
+	return aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
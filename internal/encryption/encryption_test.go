@@ -0,0 +1,128 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring_SealOpen(t *testing.T) {
+	t.Parallel()
+	oldKey, newKey := newKey(t), newKey(t)
+	oldKeyring := NewKeyring(oldKey)
+	sealed, err := oldKeyring.Seal([]byte("10.0.10.0/24"), []byte("snapshot"))
+	require.NoError(t, err)
+	require.Equal(t, Algorithm, sealed.Algorithm)
+	require.Equal(t, oldKey.ID, sealed.KeyID)
+	require.NotContains(t, string(sealed.Ciphertext), "10.0.10.0/24")
+
+	// Messages sealed with a previous key can be opened after a rotation
+	rotated := NewKeyring(newKey, oldKey)
+	require.Equal(t, newKey.ID, rotated.CurrentKeyID())
+	plaintext, err := rotated.Open(sealed, []byte("snapshot"))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.10.0/24", string(plaintext))
+
+	resealed, err := rotated.Seal(plaintext, []byte("snapshot"))
+	require.NoError(t, err)
+	require.Equal(t, newKey.ID, resealed.KeyID)
+
+	// Sealed messages survive a JSON round trip
+	data, err := json.Marshal(resealed)
+	require.NoError(t, err)
+	require.True(t, IsSealed(data))
+	require.False(t, IsSealed([]byte(`{"version": 2}`)))
+	require.False(t, IsSealed([]byte(`[]`)))
+	decoded := Sealed{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	plaintext, err = NewKeyring(newKey).Open(decoded, []byte("snapshot"))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.10.0/24", string(plaintext))
+}
+
+func TestKeyring_Open_NOK(t *testing.T) {
+	t.Parallel()
+	key := newKey(t)
+	keyring := NewKeyring(key)
+	sealed, err := keyring.Seal([]byte("secret"), []byte("snapshot"))
+	require.NoError(t, err)
+
+	// A key that is not in the keyring
+	other := newKey(t)
+	_, err = NewKeyring(other).Open(sealed, []byte("snapshot"))
+	require.ErrorIs(t, err, ErrUnknownKey)
+	require.ErrorContains(t, err, "unknown encryption key "+key.ID+", the configured keys are "+other.ID)
+
+	// A different key with the same ID
+	impostor := other
+	impostor.ID = key.ID
+	_, err = NewKeyring(impostor).Open(sealed, []byte("snapshot"))
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	// Other additional data
+	_, err = keyring.Open(sealed, []byte("log"))
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	// Tampered ciphertext
+	tampered := sealed
+	tampered.Ciphertext = append([]byte{}, sealed.Ciphertext...)
+	tampered.Ciphertext[0] ^= 1
+	_, err = keyring.Open(tampered, []byte("snapshot"))
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	unsupported := sealed
+	unsupported.Algorithm = "ROT13"
+	_, err = keyring.Open(unsupported, []byte("snapshot"))
+	require.ErrorContains(t, err, `unsupported encryption "ROT13"`)
+}
+
+func TestParseKeyring(t *testing.T) {
+	t.Parallel()
+	first, second := encodedKey(t), encodedKey(t)
+
+	keyring, err := ParseKeyring(first + "," + second)
+	require.NoError(t, err)
+	require.Len(t, keyring.keys, 2)
+	firstKey, err := ParseKey(first)
+	require.NoError(t, err)
+	require.Equal(t, firstKey.ID, keyring.CurrentKeyID())
+
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte(first+"\n\n"+second+"\n"), 0o600))
+	keyring, err = LoadKeyring(path)
+	require.NoError(t, err)
+	require.Len(t, keyring.keys, 2)
+	require.Equal(t, firstKey.ID, keyring.CurrentKeyID())
+
+	tests := map[string]string{
+		"":                       "no keys",
+		"not base64!":            "invalid key 1: key must be base64 encoded",
+		first + ",c2hvcnQ=":      "invalid key 2: key must be 32 bytes, got 5",
+		strings.Repeat(" \n", 3): "no keys",
+	}
+	for encoded, expected := range tests {
+		_, err := ParseKeyring(encoded)
+		require.ErrorContains(t, err, expected, encoded)
+	}
+}
+
+func newKey(t *testing.T) Key {
+	t.Helper()
+	key, err := ParseKey(encodedKey(t))
+	require.NoError(t, err)
+	return key
+}
+
+func encodedKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(key)
+}
@@ -0,0 +1,95 @@
+package eventlog
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"net-admin-api/internal/vlan"
+)
+
+// Buffered events per subscriber, subscribers that fall further behind are dropped.
+const subscriberBuffer = 64
+
+// Log keeps the most recent VLAN events in memory and fans new events out to subscribers.
+type Log struct {
+	size int
+	// Epoch of the events, see vlan.Event.
+	epoch       string
+	events      []vlan.Event
+	subscribers map[chan vlan.Event]struct{}
+	mu          sync.Mutex
+}
+
+// New returns a log of the events of the given epoch, usually vlan.Store.Epoch.
+func New(size int, epoch string) *Log {
+	return &Log{
+		size:        size,
+		epoch:       epoch,
+		events:      make([]vlan.Event, 0, size),
+		subscribers: map[chan vlan.Event]struct{}{},
+	}
+}
+
+// Append records an event and passes it to subscribers, it never blocks. It is meant to be registered
+// with vlan.Store.Subscribe, so events are appended in order of their IDs.
+func (l *Log) Append(event vlan.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.events) == l.size {
+		l.events = slices.Delete(l.events, 0, 1)
+	}
+	l.events = append(l.events, event)
+
+	for ch := range l.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is too slow, it has to resume from the log
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Since returns the retained events after the event with the given epoch and ID. It returns false if events
+// after the ID are no longer retained, or if the ID is unknown, including IDs of another epoch, e.g. from before
+// a restart.
+func (l *Log) Since(epoch string, id uint64) ([]vlan.Event, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if epoch != l.epoch {
+		return nil, false
+	}
+	if len(l.events) == 0 {
+		return nil, id == 0
+	}
+	first, last := l.events[0].ID, l.events[len(l.events)-1].ID
+	if id > last || id+1 < first {
+		return nil, false
+	}
+	i, _ := slices.BinarySearchFunc(l.events, id+1, func(event vlan.Event, id uint64) int {
+		return cmp.Compare(event.ID, id)
+	})
+	return slices.Clone(l.events[i:]), true
+}
+
+// Subscribe returns a channel receiving events appended after the call. The channel is closed when the
+// subscriber falls too far behind.
+func (l *Log) Subscribe() (<-chan vlan.Event, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan vlan.Event, subscriberBuffer)
+	l.subscribers[ch] = struct{}{}
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subscribers[ch]; ok {
+			delete(l.subscribers, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package freespace finds the VLAN IDs and address blocks that are not used by any VLAN.
+package freespace
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// VIDRange is an inclusive range of VLAN IDs.
+type VIDRange struct {
+	From uint16 `json:"from"`
+	To   uint16 `json:"to"`
+}
+
+// VIDs returns the ranges of VLAN IDs from..to that are not used, in ascending order.
+func VIDs(used []uint16, from, to uint16) []VIDRange {
+	used = slices.Clone(used)
+	slices.Sort(used)
+
+	ranges := []VIDRange{}
+	next := int(from)
+	for _, vid := range used {
+		if int(vid) < next || vid > to {
+			continue
+		}
+		if int(vid) > next {
+			ranges = append(ranges, VIDRange{From: uint16(next), To: vid - 1})
+		}
+		next = int(vid) + 1
+	}
+	if next <= int(to) {
+		ranges = append(ranges, VIDRange{From: uint16(next), To: to})
+	}
+	return ranges
+}
+
+// Prefixes returns the blocks of a supernet that no used prefix overlaps, as the minimal set of prefixes in
+// ascending order. Used prefixes of the other address family are ignored.
+func Prefixes(supernet netip.Prefix, used []netip.Prefix) []netip.Prefix {
+	overlapping := []netip.Prefix{}
+	for _, prefix := range used {
+		if prefix.Overlaps(supernet) {
+			overlapping = append(overlapping, prefix.Masked())
+		}
+	}
+	return free(supernet.Masked(), overlapping, []netip.Prefix{})
+}
+
+// free appends the free blocks of a prefix to blocks, given the used prefixes that overlap it. Blocks are split
+// in halves until they are either entirely used or entirely free, so every block is as large as possible.
+func free(prefix netip.Prefix, used []netip.Prefix, blocks []netip.Prefix) []netip.Prefix {
+	if len(used) == 0 {
+		return append(blocks, prefix)
+	}
+	for _, u := range used {
+		if u.Bits() <= prefix.Bits() {
+			// A used prefix that overlaps a longer one contains it
+			return blocks
+		}
+	}
+
+	lower, upper := halves(prefix)
+	blocks = free(lower, overlaps(used, lower), blocks)
+	return free(upper, overlaps(used, upper), blocks)
+}
+
+// Find returns the first block of the given length in the smallest free block that fits it, which leaves the
+// larger free blocks intact. Free blocks must be in ascending order, as returned by Prefixes.
+func Find(blocks []netip.Prefix, bits int) (netip.Prefix, bool) {
+	var best netip.Prefix
+	for _, block := range blocks {
+		if block.Bits() <= bits && (!best.IsValid() || block.Bits() > best.Bits()) {
+			best = block
+		}
+	}
+	if !best.IsValid() {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(best.Addr(), bits), true
+}
+
+// halves splits a prefix into the two prefixes that are one bit longer.
+func halves(prefix netip.Prefix) (netip.Prefix, netip.Prefix) {
+	bits := prefix.Bits() + 1
+	lower := netip.PrefixFrom(prefix.Addr(), bits)
+
+	// The upper half has the bit after the prefix set
+	bytes := prefix.Addr().AsSlice()
+	bytes[prefix.Bits()/8] |= 0x80 >> (prefix.Bits() % 8)
+	addr, _ := netip.AddrFromSlice(bytes)
+	return lower, netip.PrefixFrom(addr, bits)
+}
+
+func overlaps(prefixes []netip.Prefix, prefix netip.Prefix) []netip.Prefix {
+	overlapping := []netip.Prefix{}
+	for _, p := range prefixes {
+		if p.Overlaps(prefix) {
+			overlapping = append(overlapping, p)
+		}
+	}
+	return overlapping
+}
@@ -0,0 +1,98 @@
+package freespace
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVIDs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		used     []uint16
+		from, to uint16
+		free     []VIDRange
+	}{
+		{name: "nothing used", from: 1, to: 4094, free: []VIDRange{{1, 4094}}},
+		{name: "gaps", used: []uint16{20, 10, 11, 4094, 10}, from: 1, to: 4094, free: []VIDRange{{1, 9}, {12, 19}, {21, 4093}}},
+		{name: "used outside range", used: []uint16{5, 100, 150}, from: 100, to: 199, free: []VIDRange{{101, 149}, {151, 199}}},
+		{name: "all used", used: []uint16{100, 101}, from: 100, to: 101, free: []VIDRange{}},
+		{name: "single free", used: []uint16{100}, from: 100, to: 101, free: []VIDRange{{101, 101}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, test.free, VIDs(test.used, test.from, test.to))
+		})
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		supernet string
+		used     []string
+		free     []string
+	}{
+		{name: "nothing used", supernet: "10.0.0.0/16", free: []string{"10.0.0.0/16"}},
+		{
+			name:     "minimal prefixes",
+			supernet: "10.0.0.0/16",
+			used:     []string{"10.0.8.0/22", "10.0.13.0/24", "192.168.0.0/24", "2001:db8::/32"},
+			free: []string{
+				"10.0.0.0/21", "10.0.12.0/24", "10.0.14.0/23", "10.0.16.0/20", "10.0.32.0/19", "10.0.64.0/18",
+				"10.0.128.0/17",
+			},
+		},
+		{name: "used supernet", supernet: "10.0.0.0/24", used: []string{"10.0.0.0/16"}, free: []string{}},
+		{name: "unmasked prefixes", supernet: "10.0.0.1/30", used: []string{"10.0.0.1/31"}, free: []string{"10.0.0.2/31"}},
+		{
+			name:     "IPv6",
+			supernet: "2001:db8::/48",
+			used:     []string{"2001:db8:0:1::/64"},
+			free: []string{
+				"2001:db8::/64", "2001:db8:0:2::/63", "2001:db8:0:4::/62", "2001:db8:0:8::/61", "2001:db8:0:10::/60",
+				"2001:db8:0:20::/59", "2001:db8:0:40::/58", "2001:db8:0:80::/57", "2001:db8:0:100::/56",
+				"2001:db8:0:200::/55", "2001:db8:0:400::/54", "2001:db8:0:800::/53", "2001:db8:0:1000::/52",
+				"2001:db8:0:2000::/51", "2001:db8:0:4000::/50", "2001:db8:0:8000::/49",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			used := []netip.Prefix{}
+			for _, prefix := range test.used {
+				used = append(used, netip.MustParsePrefix(prefix))
+			}
+			free := []string{}
+			for _, prefix := range Prefixes(netip.MustParsePrefix(test.supernet), used) {
+				free = append(free, prefix.String())
+			}
+			require.Equal(t, test.free, free)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+	blocks := Prefixes(netip.MustParsePrefix("10.0.0.0/16"), []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.0.2.0/23"),
+	})
+
+	// The smallest free block that fits is used, the first one if there are several
+	tests := map[int]string{16: "", 24: "10.0.1.0/24", 23: "10.0.4.0/23", 22: "10.0.4.0/22", 17: "10.0.128.0/17"}
+	for bits, expected := range tests {
+		found, ok := Find(blocks, bits)
+		if expected == "" {
+			require.False(t, ok, bits)
+		} else {
+			require.True(t, ok, bits)
+			require.Equal(t, expected, found.String())
+		}
+	}
+}
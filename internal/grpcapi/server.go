@@ -0,0 +1,229 @@
+// Package grpcapi serves the VLAN service of api/vlan/v1 over gRPC.
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	vlanv1 "net-admin-api/api/vlan/v1"
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/eventlog"
+	"net-admin-api/internal/ratelimit"
+	"net-admin-api/internal/vlan"
+)
+
+// DefaultEventLogSize is how many events are kept for resuming Watch streams.
+const DefaultEventLogSize = 1000
+
+// Server implements the gRPC VLAN service on top of the same store as the REST API.
+type Server struct {
+	vlanv1.UnimplementedVLANServiceServer
+
+	vlanStore    *vlan.Store
+	users        *auth.Users
+	tlsConfig    *tls.Config
+	eventLogSize int
+	events       *eventlog.Log
+	limiters     *ratelimit.Limiters
+}
+
+// Option configures optional Server features.
+type Option func(*Server)
+
+// WithUsers requires calls to be authenticated with a bearer token of one of the users, in the
+// authorization metadata.
+func WithUsers(users *auth.Users) Option {
+	return func(s *Server) {
+		s.users = users
+	}
+}
+
+// WithTLS serves over TLS. If the configuration verifies client certificates, calls must be
+// authenticated with either a client certificate or a bearer token.
+func WithTLS(config *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = config
+	}
+}
+
+// WithRateLimits limits the calls of each client like the REST API does, identified by its actor or IP address.
+// Calls of Get, List and Watch are reads, all others are writes. Failed authentications are limited per IP address.
+func WithRateLimits(limiters *ratelimit.Limiters) Option {
+	return func(s *Server) {
+		s.limiters = limiters
+	}
+}
+
+// WithEventLogSize sets how many events are kept for resuming Watch streams.
+func WithEventLogSize(size int) Option {
+	return func(s *Server) {
+		s.eventLogSize = size
+	}
+}
+
+func NewServer(vlanStore *vlan.Store, opts ...Option) *grpc.Server {
+	server := &Server{
+		vlanStore:    vlanStore,
+		eventLogSize: DefaultEventLogSize,
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	server.events = eventlog.New(server.eventLogSize, vlanStore.Epoch())
+	vlanStore.Subscribe(server.events.Append)
+
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(server.authUnary, server.rateLimitUnary),
+		grpc.ChainStreamInterceptor(server.authStream, server.rateLimitStream),
+	}
+	if server.tlsConfig != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(server.tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
+	vlanv1.RegisterVLANServiceServer(grpcServer, server)
+	return grpcServer
+}
+
+func (s *Server) authUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate stores the actor of a call in its context, the same way as the REST API does for requests.
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	clientCerts := s.tlsConfig != nil && s.tlsConfig.ClientAuth >= tls.VerifyClientCertIfGiven
+	if s.users == nil && !clientCerts {
+		return ctx, nil
+	}
+
+	// Clients that failed to authenticate too often are rejected before their credentials are checked, so that a
+	// correct guess is not told apart from a wrong one
+	host := peerHost(ctx)
+	if s.limiters != nil {
+		if ok, retryAfter := s.limiters.FailedAuth.Check(host); !ok {
+			return nil, tooManyCalls(retryAfter, "too many failed authentications")
+		}
+	}
+	fail := func(message string) error {
+		if s.limiters != nil {
+			s.limiters.FailedAuth.Allow(host)
+		}
+		return status.Error(codes.Unauthenticated, message)
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			if actor, ok := auth.CertificateActor(&tlsInfo.State, s.users); ok {
+				return auth.WithActor(ctx, actor), nil
+			}
+		}
+	}
+	if s.users == nil {
+		return nil, fail("missing client certificate")
+	}
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return nil, fail("missing bearer token")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, fail("missing bearer token")
+	}
+	actor, ok := s.users.Authenticate(token)
+	if !ok {
+		return nil, fail("invalid bearer token")
+	}
+	return auth.WithActor(ctx, actor), nil
+}
+
+// readMethods are the methods limited as reads, like GET requests of the REST API.
+var readMethods = map[string]bool{
+	vlanv1.VLANService_ListVLANs_FullMethodName: true,
+	vlanv1.VLANService_GetVLAN_FullMethodName:   true,
+	vlanv1.VLANService_Watch_FullMethodName:     true,
+}
+
+func (s *Server) rateLimitUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.rateLimit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) rateLimitStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.rateLimit(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// rateLimit takes a token for a call from the bucket of its actor, or of its IP address for anonymous calls.
+func (s *Server) rateLimit(ctx context.Context, method string) error {
+	if s.limiters == nil {
+		return nil
+	}
+	key := "actor:" + auth.ActorFrom(ctx).Name
+	if key == "actor:"+auth.Anonymous.Name {
+		key = "ip:" + peerHost(ctx)
+	}
+	limiter := s.limiters.Writes
+	if readMethods[method] {
+		limiter = s.limiters.Reads
+	}
+	if ok, retryAfter := limiter.Allow(key); !ok {
+		return tooManyCalls(retryAfter, "rate limit exceeded")
+	}
+	return nil
+}
+
+// tooManyCalls returns the status of a rejected call, which tells when to retry in whole seconds like the
+// Retry-After header of the REST API.
+func tooManyCalls(retryAfter time.Duration, message string) error {
+	seconds := max(1, math.Ceil(retryAfter.Seconds()))
+	return status.Errorf(codes.ResourceExhausted, "%s, retry after %.0fs", message, seconds)
+}
+
+// peerHost returns the IP address of the client of a call.
+func peerHost(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
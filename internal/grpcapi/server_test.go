@@ -0,0 +1,325 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	vlanv1 "net-admin-api/api/vlan/v1"
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/ratelimit"
+	"net-admin-api/internal/tlsconfig"
+	"net-admin-api/internal/tlsconfig/tlstest"
+	"net-admin-api/internal/vlan"
+)
+
+func TestVLANService_OK(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	client, _ := newTestClient(t)
+
+	listed, err := client.ListVLANs(ctx, &vlanv1.ListVLANsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, listed.GetVlans())
+
+	created, err := client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{Vlan: newVLAN(10, "users", "10.0.10.0/24", "10.0.10.1")})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.GetId())
+	require.Equal(t, "users", created.GetName())
+	require.Empty(t, created.GetVrf())
+
+	fetched, err := client.GetVLAN(ctx, &vlanv1.GetVLANRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, created.String(), fetched.String())
+
+	created.Name = "staff"
+	created.Vrf = "red"
+	created.Tags = []string{"prod"}
+	updated, err := client.UpdateVLAN(ctx, &vlanv1.UpdateVLANRequest{Vlan: created})
+	require.NoError(t, err)
+	require.Equal(t, "staff", updated.GetName())
+	require.Equal(t, "red", updated.GetVrf())
+	require.Equal(t, []string{"prod"}, updated.GetTags())
+
+	listed, err = client.ListVLANs(ctx, &vlanv1.ListVLANsRequest{})
+	require.NoError(t, err)
+	require.Len(t, listed.GetVlans(), 1)
+	require.Equal(t, "staff", listed.GetVlans()[0].GetName())
+
+	_, err = client.DeleteVLAN(ctx, &vlanv1.DeleteVLANRequest{Id: created.GetId()})
+	require.NoError(t, err)
+	_, err = client.GetVLAN(ctx, &vlanv1.GetVLANRequest{Id: created.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestVLANService_NOK(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	client, vlanStore := newTestClient(t)
+
+	managed := vlan.VLAN{
+		ID:        uuid.New(),
+		VID:       1,
+		Name:      "managed",
+		Subnet:    netip.MustParsePrefix("192.168.0.0/24"),
+		Gateway:   netip.MustParseAddr("192.168.0.1"),
+		ManagedBy: "reconciler",
+	}
+	require.NoError(t, vlanStore.Save(managed))
+	managedPB := toProto(managed)
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{name: "get invalid id", code: codes.InvalidArgument, call: func() error {
+			_, err := client.GetVLAN(ctx, &vlanv1.GetVLANRequest{Id: "42"})
+			return err
+		}},
+		{name: "get unknown", code: codes.NotFound, call: func() error {
+			_, err := client.GetVLAN(ctx, &vlanv1.GetVLANRequest{Id: uuid.NewString()})
+			return err
+		}},
+		{name: "create without vlan", code: codes.InvalidArgument, call: func() error {
+			_, err := client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{})
+			return err
+		}},
+		{name: "create invalid vid", code: codes.InvalidArgument, call: func() error {
+			_, err := client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{Vlan: newVLAN(5000, "x", "10.0.0.0/24", "10.0.0.1")})
+			return err
+		}},
+		{name: "create gateway outside subnet", code: codes.InvalidArgument, call: func() error {
+			_, err := client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{Vlan: newVLAN(10, "x", "10.0.0.0/24", "10.1.0.1")})
+			return err
+		}},
+		{name: "create invalid subnet", code: codes.InvalidArgument, call: func() error {
+			_, err := client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{Vlan: newVLAN(10, "x", "10.0.0.0", "10.0.0.1")})
+			return err
+		}},
+		{name: "create overlapping subnet", code: codes.FailedPrecondition, call: func() error {
+			_, err := client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{Vlan: newVLAN(10, "x", "192.168.0.128/25", "192.168.0.129")})
+			return err
+		}},
+		{name: "create unknown custom field", code: codes.InvalidArgument, call: func() error {
+			pb := newVLAN(10, "x", "10.0.0.0/24", "10.0.0.1")
+			pb.Fields = &structpb.Struct{Fields: map[string]*structpb.Value{"site": structpb.NewStringValue("ber1")}}
+			_, err := client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{Vlan: pb})
+			return err
+		}},
+		{name: "update unknown", code: codes.NotFound, call: func() error {
+			pb := newVLAN(10, "x", "10.0.0.0/24", "10.0.0.1")
+			pb.Id = uuid.NewString()
+			_, err := client.UpdateVLAN(ctx, &vlanv1.UpdateVLANRequest{Vlan: pb})
+			return err
+		}},
+		{name: "update managed", code: codes.FailedPrecondition, call: func() error {
+			_, err := client.UpdateVLAN(ctx, &vlanv1.UpdateVLANRequest{Vlan: managedPB})
+			return err
+		}},
+		{name: "delete managed", code: codes.FailedPrecondition, call: func() error {
+			_, err := client.DeleteVLAN(ctx, &vlanv1.DeleteVLANRequest{Id: managed.ID.String()})
+			return err
+		}},
+		{name: "delete unknown", code: codes.NotFound, call: func() error {
+			_, err := client.DeleteVLAN(ctx, &vlanv1.DeleteVLANRequest{Id: uuid.NewString()})
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.code, status.Code(test.call()))
+		})
+	}
+}
+
+func TestVLANService_Auth(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: "alice-token"}})
+	require.NoError(t, err)
+	client, _ := newTestClient(t, WithUsers(users))
+
+	_, err = client.ListVLANs(context.Background(), &vlanv1.ListVLANsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = client.ListVLANs(ctx, &vlanv1.ListVLANsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer alice-token")
+	_, err = client.ListVLANs(ctx, &vlanv1.ListVLANsRequest{})
+	require.NoError(t, err)
+
+	// Streams are authenticated as well
+	stream, err := client.Watch(context.Background(), &vlanv1.WatchRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestVLANService_RateLimits(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: "alice-token"}, {Name: "bob", Token: "bob-token"}})
+	require.NoError(t, err)
+	// Practically no refill during the test
+	limiters := ratelimit.NewLimiters(ratelimit.Limit{Rate: 0.01, Burst: 2}, ratelimit.Limit{Rate: 0.01, Burst: 1})
+	client, _ := newTestClient(t, WithUsers(users), WithRateLimits(limiters))
+	alice := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer alice-token")
+	bob := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer bob-token")
+
+	// Reads are limited per actor, in the same buckets as REST requests, of which one took a token already
+	ok, _ := limiters.Reads.Allow("actor:alice")
+	require.True(t, ok)
+	_, err = client.ListVLANs(alice, &vlanv1.ListVLANsRequest{})
+	require.NoError(t, err)
+	_, err = client.ListVLANs(alice, &vlanv1.ListVLANsRequest{})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "retry after")
+	stream, err := client.Watch(alice, &vlanv1.WatchRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = client.ListVLANs(bob, &vlanv1.ListVLANsRequest{})
+	require.NoError(t, err)
+
+	// Writes have a separate budget
+	_, err = client.CreateVLAN(alice, &vlanv1.CreateVLANRequest{Vlan: newVLAN(10, "users", "10.0.10.0/24", "10.0.10.1")})
+	require.NoError(t, err)
+	_, err = client.CreateVLAN(alice, &vlanv1.CreateVLANRequest{Vlan: newVLAN(11, "voice", "10.0.11.0/24", "10.0.11.1")})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestVLANService_FailedAuthentication(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: "alice-token"}})
+	require.NoError(t, err)
+	limiters := ratelimit.NewLimiters(ratelimit.Limit{Rate: 0.01, Burst: 10}, ratelimit.Limit{Rate: 0.01, Burst: 2})
+	client, _ := newTestClient(t, WithUsers(users), WithRateLimits(limiters))
+
+	// Failed authentications are limited per IP address, even with a valid token afterwards
+	for range 2 {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer guess")
+		_, err = client.ListVLANs(ctx, &vlanv1.ListVLANsRequest{})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+	for _, token := range []string{"guess", "alice-token"} {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+		_, err = client.ListVLANs(ctx, &vlanv1.ListVLANsRequest{})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	}
+}
+
+func TestVLANService_Watch(t *testing.T) {
+	t.Parallel()
+	client, _ := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &vlanv1.WatchRequest{})
+	require.NoError(t, err)
+	// Headers are sent once the stream is subscribed
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	_, err = client.CreateVLAN(ctx, &vlanv1.CreateVLANRequest{Vlan: newVLAN(10, "users", "10.0.10.0/24", "10.0.10.1")})
+	require.NoError(t, err)
+	created, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, vlanv1.EventType_EVENT_TYPE_CREATED, created.GetType())
+	require.Equal(t, "users", created.GetVlan().GetName())
+	require.NotNil(t, created.GetTime())
+
+	_, err = client.DeleteVLAN(ctx, &vlanv1.DeleteVLANRequest{Id: created.GetVlan().GetId()})
+	require.NoError(t, err)
+	deleted, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, vlanv1.EventType_EVENT_TYPE_DELETED, deleted.GetType())
+	require.Equal(t, created.GetId()+1, deleted.GetId())
+
+	// Resuming replays the events after the given one
+	require.NotEmpty(t, created.GetEpoch())
+	resumed, err := client.Watch(ctx, &vlanv1.WatchRequest{LastEventId: created.GetId(), Epoch: created.GetEpoch()})
+	require.NoError(t, err)
+	replayed, err := resumed.Recv()
+	require.NoError(t, err)
+	require.Equal(t, deleted.String(), replayed.String())
+
+	// Unknown events cannot be resumed from, and neither can events of another epoch, e.g. from before a restart
+	for _, req := range []*vlanv1.WatchRequest{
+		{LastEventId: 100, Epoch: created.GetEpoch()},
+		{LastEventId: created.GetId(), Epoch: "0123456789abcdef"},
+		{LastEventId: created.GetId()},
+	} {
+		unknown, err := client.Watch(ctx, req)
+		require.NoError(t, err)
+		_, err = unknown.Recv()
+		require.Equal(t, codes.OutOfRange, status.Code(err))
+	}
+}
+
+func TestVLANService_ClientCertificates(t *testing.T) {
+	t.Parallel()
+	ca := tlstest.NewCA(t, "test-ca")
+	certPath, keyPath, caPath := ca.WriteFiles(t, t.TempDir(), "localhost")
+	reloader, err := tlsconfig.New(tlsconfig.Options{CertFile: certPath, KeyFile: keyPath, ClientCAFile: caPath})
+	require.NoError(t, err)
+	opts := []Option{WithTLS(reloader.Config())}
+
+	anonymous, _ := newTestClientWithCredentials(t, credentials.NewTLS(&tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"}), opts...)
+	_, err = anonymous.ListVLANs(context.Background(), &vlanv1.ListVLANsRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	alice, _ := newTestClientWithCredentials(t, credentials.NewTLS(&tls.Config{
+		RootCAs:      ca.Pool(),
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{ca.Issue(t, "alice")},
+	}), opts...)
+	_, err = alice.ListVLANs(context.Background(), &vlanv1.ListVLANsRequest{})
+	require.NoError(t, err)
+}
+
+func newTestClient(t *testing.T, opts ...Option) (vlanv1.VLANServiceClient, *vlan.Store) {
+	t.Helper()
+	return newTestClientWithCredentials(t, insecure.NewCredentials(), opts...)
+}
+
+func newTestClientWithCredentials(t *testing.T, creds credentials.TransportCredentials, opts ...Option) (vlanv1.VLANServiceClient, *vlan.Store) {
+	t.Helper()
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := NewServer(vlanStore, opts...)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return vlanv1.NewVLANServiceClient(conn), vlanStore
+}
+
+func newVLAN(vid uint32, name, subnet, gateway string) *vlanv1.VLAN {
+	return &vlanv1.VLAN{Vid: vid, Name: name, Subnet: subnet, Gateway: gateway, Status: "active"}
+}
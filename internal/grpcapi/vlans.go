@@ -0,0 +1,243 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/netip"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	vlanv1 "net-admin-api/api/vlan/v1"
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/vlan"
+)
+
+var eventTypes = map[vlan.EventType]vlanv1.EventType{
+	vlan.EventCreated: vlanv1.EventType_EVENT_TYPE_CREATED,
+	vlan.EventUpdated: vlanv1.EventType_EVENT_TYPE_UPDATED,
+	vlan.EventDeleted: vlanv1.EventType_EVENT_TYPE_DELETED,
+}
+
+func (s *Server) ListVLANs(context.Context, *vlanv1.ListVLANsRequest) (*vlanv1.ListVLANsResponse, error) {
+	vlans, err := s.vlanStore.List()
+	if err != nil {
+		log.Printf("failed to read vlans: %v", err)
+		return nil, status.Error(codes.Internal, "failed to read vlans")
+	}
+	resp := &vlanv1.ListVLANsResponse{Vlans: make([]*vlanv1.VLAN, len(vlans))}
+	for i, v := range vlans {
+		resp.Vlans[i] = toProto(v)
+	}
+	return resp, nil
+}
+
+func (s *Server) GetVLAN(_ context.Context, req *vlanv1.GetVLANRequest) (*vlanv1.VLAN, error) {
+	vlanID, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	v := s.vlanStore.Get(vlanID)
+	if v == nil {
+		return nil, status.Error(codes.NotFound, "vlan not found")
+	}
+	return toProto(*v), nil
+}
+
+func (s *Server) CreateVLAN(_ context.Context, req *vlanv1.CreateVLANRequest) (*vlanv1.VLAN, error) {
+	v, err := fromProto(req.GetVlan())
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate new ID and save, managed VLANs can only be created by their owner
+	v.ID = uuid.New()
+	v.ManagedBy = ""
+	if err := s.vlanStore.Save(v); err != nil {
+		if err := ruleError(err); err != nil {
+			return nil, err
+		}
+		log.Printf("failed to save vlan: %v", err)
+		return nil, status.Error(codes.Internal, "failed to save vlan")
+	}
+	return toProto(v), nil
+}
+
+func (s *Server) UpdateVLAN(_ context.Context, req *vlanv1.UpdateVLANRequest) (*vlanv1.VLAN, error) {
+	vlanID, err := parseID(req.GetVlan().GetId())
+	if err != nil {
+		return nil, err
+	}
+	v, err := fromProto(req.GetVlan())
+	if err != nil {
+		return nil, err
+	}
+	v.ID = vlanID
+
+	v.ManagedBy = ""
+	if err := s.vlanStore.Update(v, vlan.ExpectUnmanaged()); err != nil {
+		if errors.Is(err, vlan.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "vlan not found")
+		}
+		if err := ruleError(err); err != nil {
+			return nil, err
+		}
+		log.Printf("failed to update vlan: %v", err)
+		return nil, status.Error(codes.Internal, "failed to update vlan")
+	}
+	return toProto(v), nil
+}
+
+func (s *Server) DeleteVLAN(ctx context.Context, req *vlanv1.DeleteVLANRequest) (*emptypb.Empty, error) {
+	vlanID, err := parseID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.vlanStore.Delete(vlanID, auth.ActorFrom(ctx).Name, vlan.ExpectUnmanaged()); err != nil {
+		if errors.Is(err, vlan.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "vlan not found")
+		}
+		if err := ruleError(err); err != nil {
+			return nil, err
+		}
+		log.Printf("failed to delete vlan: %v", err)
+		return nil, status.Error(codes.Internal, "failed to delete vlan")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Watch(req *vlanv1.WatchRequest, stream grpc.ServerStreamingServer[vlanv1.Event]) error {
+	// Subscribe before reading the backlog, so that no events are missed in between
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+	// Headers tell the client that changes from now on are streamed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	lastEventID := req.GetLastEventId()
+	if lastEventID != 0 {
+		backlog, ok := s.events.Since(req.GetEpoch(), lastEventID)
+		if !ok {
+			return status.Errorf(codes.OutOfRange, "events after %s-%d are no longer available", req.GetEpoch(), lastEventID)
+		}
+		for _, event := range backlog {
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+			lastEventID = event.ID
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Errorf(codes.ResourceExhausted, "too slow to keep up, resume after event %d", lastEventID)
+			}
+			if event.ID <= lastEventID {
+				continue
+			}
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+			lastEventID = event.ID
+		}
+	}
+}
+
+// ruleError returns the status of an error of a VLAN that breaks the rules of its VRF or the custom field schema,
+// or that another component owns, or nil for other errors.
+func ruleError(err error) error {
+	switch {
+	case errors.Is(err, vlan.ErrOverlap), errors.Is(err, vlan.ErrDuplicateVID), errors.Is(err, vlan.ErrManaged):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, vlan.ErrUnknownVRF), errors.Is(err, vlan.ErrInvalidFields):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func parseID(id string) (uuid.UUID, error) {
+	vlanID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid vlan id")
+	}
+	return vlanID, nil
+}
+
+// fromProto converts and validates a VLAN of a request, its ID is not parsed.
+func fromProto(pb *vlanv1.VLAN) (vlan.VLAN, error) {
+	if pb == nil {
+		return vlan.VLAN{}, status.Error(codes.InvalidArgument, "vlan is required")
+	}
+	if pb.GetVid() > math.MaxUint16 {
+		return vlan.VLAN{}, status.Errorf(codes.InvalidArgument, "invalid VLAN ID %v (expected range 1..4094)", pb.GetVid())
+	}
+	subnet, err := netip.ParsePrefix(pb.GetSubnet())
+	if err != nil {
+		return vlan.VLAN{}, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid subnet: %v", err))
+	}
+	gateway, err := netip.ParseAddr(pb.GetGateway())
+	if err != nil {
+		return vlan.VLAN{}, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid gateway: %v", err))
+	}
+
+	v := vlan.VLAN{
+		VID:     uint16(pb.GetVid()),
+		Name:    pb.GetName(),
+		Subnet:  subnet,
+		Gateway: gateway,
+		Status:  pb.GetStatus(),
+		VRF:     pb.GetVrf(),
+		Tags:    pb.GetTags(),
+	}
+	if pb.GetFields() != nil {
+		v.Fields = pb.GetFields().AsMap()
+	}
+	if errors := v.Validate(); len(errors) > 0 {
+		return vlan.VLAN{}, status.Error(codes.InvalidArgument, strings.Join(errors, ", "))
+	}
+	return v, nil
+}
+
+func toProto(v vlan.VLAN) *vlanv1.VLAN {
+	pb := &vlanv1.VLAN{
+		Id:        v.ID.String(),
+		Vid:       uint32(v.VID),
+		Name:      v.Name,
+		Subnet:    v.Subnet.String(),
+		Gateway:   v.Gateway.String(),
+		Status:    v.Status,
+		ManagedBy: v.ManagedBy,
+		Vrf:       v.VRF,
+		Tags:      v.Tags,
+	}
+	// Custom field values are strings, numbers or booleans, which are always valid
+	if len(v.Fields) > 0 {
+		pb.Fields, _ = structpb.NewStruct(v.Fields)
+	}
+	return pb
+}
+
+func eventToProto(event vlan.Event) *vlanv1.Event {
+	return &vlanv1.Event{
+		Id:    event.ID,
+		Epoch: event.Epoch,
+		Type:  eventTypes[event.Type],
+		Time:  timestamppb.New(event.Time),
+		Vlan:  toProto(event.VLAN),
+	}
+}
@@ -0,0 +1,41 @@
+// Package idempotency stores the responses of requests made with an Idempotency-Key header, so that retries
+// of a request return the original response instead of repeating its effect.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"net-admin-api/internal/encryption"
+)
+
+// Record is the response of a request made with an idempotency key. Keys are scoped to the actor who made
+// the request, so that users cannot read each other's responses.
+type Record struct {
+	Actor string `json:"actor"`
+	Key   string `json:"key"`
+	// Fingerprint of the request, a retry must have the same method, path and body.
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	// Body encrypted with the keyring of the store, instead of Body, see WithEncryption.
+	SealedBody *encryption.Sealed `json:"sealedBody,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt"`
+}
+
+// additionalData returns the additional data of the sealed body of a record, so that it cannot be passed off as
+// the body of another record.
+func (r *Record) additionalData() []byte {
+	return []byte("net-admin-api idempotency response\x00" + r.Actor + "\x00" + r.Key + "\x00" + r.Fingerprint)
+}
+
+// Fingerprint returns the fingerprint of a request.
+func Fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
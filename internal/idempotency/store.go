@@ -0,0 +1,204 @@
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"net-admin-api/internal/encryption"
+	"net-admin-api/internal/jsonfile"
+)
+
+// DefaultTTL is the default time after which idempotency keys expire and can be used again.
+const DefaultTTL = 24 * time.Hour
+
+var (
+	// ErrMismatch is returned for a key that was used for a different request.
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+	// ErrInProgress is returned for a key whose first request has not completed yet.
+	ErrInProgress = errors.New("a request with the idempotency key is in progress")
+)
+
+// Store manages a JSON file to persist the responses of requests with idempotency keys until they expire.
+type Store struct {
+	path string
+	ttl  time.Duration
+	now  func() time.Time
+	mu   sync.Mutex
+
+	recordsByKey map[recordKey]Record
+	// Fingerprints of the requests in progress, by key.
+	pending map[recordKey]string
+	keyring *encryption.Keyring
+}
+
+type StoreOption func(*Store)
+
+// WithEncryption encrypts the response bodies with the current key of the keyring, since they may describe
+// the same resources as encrypted stores. Plaintext bodies of older records are encrypted on startup.
+func WithEncryption(keyring *encryption.Keyring) StoreOption {
+	return func(s *Store) {
+		s.keyring = keyring
+	}
+}
+
+// recordKey is the key of a record, which does not collide between actors.
+type recordKey struct {
+	actor string
+	key   string
+}
+
+func NewStore(path string, ttl time.Duration, opts ...StoreOption) (*Store, error) {
+	store := &Store{
+		path:    path,
+		ttl:     ttl,
+		now:     time.Now,
+		pending: map[recordKey]string{},
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	// store file does not exist
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		store.recordsByKey = make(map[recordKey]Record, 0)
+		if err := store.writeRecords(); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	// store file exists
+	if err := store.readRecords(); err != nil {
+		return nil, err
+	}
+	// Bodies recorded before encryption was enabled are encrypted right away
+	sealed := 0
+	for scopedKey, record := range store.recordsByKey {
+		if store.keyring == nil || record.Body == nil {
+			continue
+		}
+		if err := store.seal(&record); err != nil {
+			return nil, err
+		}
+		store.recordsByKey[scopedKey] = record
+		sealed++
+	}
+	if sealed > 0 {
+		if err := store.writeRecords(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// Begin starts a request with an idempotency key. It returns the record of the first request with the key if
+// there is one, which is to be replayed. Otherwise the request proceeds, and must be followed by Complete or
+// Abort.
+func (s *Store) Begin(actor, key, fingerprint string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scopedKey := recordKey{actor, key}
+	if record, ok := s.recordsByKey[scopedKey]; ok && !s.expired(record) {
+		if record.Fingerprint != fingerprint {
+			return nil, ErrMismatch
+		}
+		if err := s.open(&record); err != nil {
+			return nil, err
+		}
+		return &record, nil
+	}
+	if pending, ok := s.pending[scopedKey]; ok {
+		if pending != fingerprint {
+			return nil, ErrMismatch
+		}
+		return nil, ErrInProgress
+	}
+	s.pending[scopedKey] = fingerprint
+	return nil, nil
+}
+
+// Complete records the response of a request started with Begin, and drops expired records.
+func (s *Store) Complete(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scopedKey := recordKey{record.Actor, record.Key}
+	delete(s.pending, scopedKey)
+	record.CreatedAt = s.now().UTC()
+	if err := s.seal(&record); err != nil {
+		return err
+	}
+	s.recordsByKey[scopedKey] = record
+	maps.DeleteFunc(s.recordsByKey, func(_ recordKey, record Record) bool {
+		return s.expired(record)
+	})
+	return s.writeRecords()
+}
+
+// Abort releases the key of a request started with Begin without recording its response, so that the
+// request can be retried with the same key.
+func (s *Store) Abort(actor, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, recordKey{actor, key})
+}
+
+// seal replaces the body of a record with the sealed body if the store is encrypted.
+func (s *Store) seal(record *Record) error {
+	if s.keyring == nil || record.Body == nil {
+		return nil
+	}
+	sealed, err := s.keyring.Seal(record.Body, record.additionalData())
+	if err != nil {
+		return fmt.Errorf("failed to encrypt idempotency key record: %w", err)
+	}
+	record.Body, record.SealedBody = nil, &sealed
+	return nil
+}
+
+// open replaces the sealed body of a record with its plaintext.
+func (s *Store) open(record *Record) error {
+	if record.SealedBody == nil {
+		return nil
+	}
+	if s.keyring == nil {
+		return errors.New("idempotency key record is encrypted, but no encryption key is configured")
+	}
+	body, err := s.keyring.Open(*record.SealedBody, record.additionalData())
+	if err != nil {
+		return fmt.Errorf("failed to decrypt idempotency key record: %w", err)
+	}
+	record.Body, record.SealedBody = body, nil
+	return nil
+}
+
+func (s *Store) expired(record Record) bool {
+	return !s.now().Before(record.CreatedAt.Add(s.ttl))
+}
+
+func (s *Store) readRecords() error {
+	records := []Record{}
+	if err := jsonfile.Read(s.path, &records); err != nil {
+		return err
+	}
+
+	recordsByKey := make(map[recordKey]Record, len(records))
+	for _, record := range records {
+		if record.Key == "" || record.Fingerprint == "" {
+			return fmt.Errorf("invalid idempotency key record in %s", s.path)
+		}
+		recordsByKey[recordKey{record.Actor, record.Key}] = record
+	}
+	s.recordsByKey = recordsByKey
+	return nil
+}
+
+func (s *Store) writeRecords() error {
+	return jsonfile.Write(s.path, slices.Collect(maps.Values(s.recordsByKey)))
+}
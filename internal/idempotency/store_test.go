@@ -0,0 +1,125 @@
+package idempotency
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/encryption"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "idempotency-keys.json")
+	store, err := NewStore(path, time.Hour)
+	require.NoError(t, err)
+	fingerprint := Fingerprint("POST", "/api/v1/vlans", []byte(`{"vid": 10}`))
+
+	record, err := store.Begin("alice", "key-1", fingerprint)
+	require.NoError(t, err)
+	require.Nil(t, record)
+	// Concurrent retries wait for the first request
+	_, err = store.Begin("alice", "key-1", fingerprint)
+	require.ErrorIs(t, err, ErrInProgress)
+	_, err = store.Begin("alice", "key-1", Fingerprint("POST", "/api/v1/vlans", []byte(`{"vid": 20}`)))
+	require.ErrorIs(t, err, ErrMismatch)
+
+	require.NoError(t, store.Complete(Record{
+		Actor:       "alice",
+		Key:         "key-1",
+		Fingerprint: fingerprint,
+		Status:      http.StatusCreated,
+		Header:      http.Header{"Location": {"/api/v1/vlans/1"}},
+	}))
+	record, err = store.Begin("alice", "key-1", fingerprint)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, record.Status)
+	require.Equal(t, "/api/v1/vlans/1", record.Header.Get("Location"))
+	_, err = store.Begin("alice", "key-1", Fingerprint("POST", "/api/v1/vrfs", []byte(`{"vid": 10}`)))
+	require.ErrorIs(t, err, ErrMismatch)
+
+	// Keys are scoped to actors
+	record, err = store.Begin("bob", "key-1", fingerprint)
+	require.NoError(t, err)
+	require.Nil(t, record)
+	store.Abort("bob", "key-1")
+	record, err = store.Begin("bob", "key-1", fingerprint)
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	// Records survive restarts until they expire
+	reopened, err := NewStore(path, time.Hour)
+	require.NoError(t, err)
+	record, err = reopened.Begin("alice", "key-1", fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+
+	reopened.now = func() time.Time { return time.Now().Add(time.Hour) }
+	record, err = reopened.Begin("alice", "key-1", Fingerprint("POST", "/api/v1/vrfs", nil))
+	require.NoError(t, err)
+	require.Nil(t, record)
+}
+
+func TestStore_Encryption(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "idempotency-keys.json")
+	keyring := encryption.NewKeyring(newKey(t))
+	body := []byte(`{"results":[{"status":"applied"}]}`)
+	fingerprint := Fingerprint("POST", "/api/v1/transactions", body)
+	complete := func(store *Store, key string) {
+		t.Helper()
+		_, err := store.Begin("alice", key, fingerprint)
+		require.NoError(t, err)
+		require.NoError(t, store.Complete(Record{Actor: "alice", Key: key, Fingerprint: fingerprint, Status: http.StatusOK, Body: body}))
+	}
+
+	// Bodies recorded before encryption was enabled are encrypted on startup
+	plaintext, err := NewStore(path, time.Hour)
+	require.NoError(t, err)
+	complete(plaintext, "key-1")
+	require.Contains(t, readFile(t, path), base64.StdEncoding.EncodeToString(body))
+	store, err := NewStore(path, time.Hour, WithEncryption(keyring))
+	require.NoError(t, err)
+	complete(store, "key-2")
+	require.NotContains(t, readFile(t, path), base64.StdEncoding.EncodeToString(body))
+
+	for _, key := range []string{"key-1", "key-2"} {
+		record, err := store.Begin("alice", key, fingerprint)
+		require.NoError(t, err)
+		require.Equal(t, body, record.Body)
+		require.Nil(t, record.SealedBody)
+	}
+
+	// Encrypted bodies cannot be read without the key
+	reopened, err := NewStore(path, time.Hour)
+	require.NoError(t, err)
+	_, err = reopened.Begin("alice", "key-1", fingerprint)
+	require.ErrorContains(t, err, "no encryption key is configured")
+	reopened, err = NewStore(path, time.Hour, WithEncryption(encryption.NewKeyring(newKey(t))))
+	require.NoError(t, err)
+	_, err = reopened.Begin("alice", "key-1", fingerprint)
+	require.ErrorIs(t, err, encryption.ErrUnknownKey)
+}
+
+func newKey(t *testing.T) encryption.Key {
+	t.Helper()
+	key := make([]byte, encryption.KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	parsed, err := encryption.ParseKey(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	return parsed
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,97 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"net-admin-api/internal/encryption"
+)
+
+// Read decodes the JSON file at path into v.
+func Read(path string, v any) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", path, err)
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %v: %w", path, err)
+	}
+	return nil
+}
+
+// Write atomically replaces the file at path with the JSON encoding of v.
+func Write(path string, v any) error {
+	dir := filepath.Dir(path)
+	fileTmp, err := os.CreateTemp(dir, filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file in %v: %w", dir, err)
+	}
+	defer func() {
+		_ = os.Remove(fileTmp.Name())
+	}()
+
+	if err := json.NewEncoder(fileTmp).Encode(v); err != nil {
+		_ = fileTmp.Close()
+		return fmt.Errorf("failed to encode %v: %w", path, err)
+	}
+
+	if err := fileTmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(fileTmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %v: %w", path, err)
+	}
+
+	return nil
+}
+
+// ReadSealed decodes the JSON file at path into v like Read, but the file may be sealed with any key of keyring. It
+// reports whether the file is stale, i.e. plaintext or sealed with a previous key, and is to be written again. Without
+// a keyring it is Read, and sealed files are an error.
+func ReadSealed(path string, keyring *encryption.Keyring, additionalData []byte, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %v: %w", path, err)
+	}
+	stale := keyring != nil
+	if encryption.IsSealed(data) {
+		if keyring == nil {
+			return false, fmt.Errorf("%v is encrypted, but no encryption key is configured", path)
+		}
+		sealed := encryption.Sealed{}
+		if err := json.Unmarshal(data, &sealed); err != nil {
+			return false, fmt.Errorf("failed to decode %v: %w", path, err)
+		}
+		if data, err = keyring.Open(sealed, additionalData); err != nil {
+			return false, fmt.Errorf("failed to decrypt %v: %w", path, err)
+		}
+		stale = sealed.KeyID != keyring.CurrentKeyID()
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode %v: %w", path, err)
+	}
+	return stale, nil
+}
+
+// WriteSealed atomically replaces the file at path with the JSON encoding of v like Write, sealed with the current
+// key of keyring. Without a keyring it is Write.
+func WriteSealed(path string, keyring *encryption.Keyring, additionalData []byte, v any) error {
+	if keyring == nil {
+		return Write(path, v)
+	}
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %v: %w", path, err)
+	}
+	sealed, err := keyring.Seal(plaintext, additionalData)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %v: %w", path, err)
+	}
+	return Write(path, sealed)
+}
@@ -0,0 +1,115 @@
+// Package ratelimit implements token bucket rate limits per client.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Buckets that have been full for this long are forgotten.
+const sweepInterval = time.Minute
+
+// Limit allows Burst requests at once, refilled at Rate requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limiter keeps a token bucket per key, e.g. per client.
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiters are the limiters of API calls. They are shared by the REST and gRPC APIs, so that a client has the same
+// budget on both.
+type Limiters struct {
+	Reads  *Limiter
+	Writes *Limiter
+	// FailedAuth limits failed authentications per IP address like writes, so that tokens cannot be guessed.
+	FailedAuth *Limiter
+}
+
+// NewLimiters returns limiters of reads and writes per client, with separate limits.
+func NewLimiters(reads, writes Limit) *Limiters {
+	return &Limiters{
+		Reads:      New(reads),
+		Writes:     New(writes),
+		FailedAuth: New(writes),
+	}
+}
+
+func New(limit Limit) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the bucket of key. If the bucket is empty, it returns false and how long
+// until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.take(key, true)
+}
+
+// Check reports whether the bucket of key has a token, like Allow, without taking it.
+func (l *Limiter) Check(key string) (bool, time.Duration) {
+	return l.take(key, false)
+}
+
+func (l *Limiter) take(key string, consume bool) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	if b.tokens >= 1 {
+		if consume {
+			b.tokens--
+		}
+		return true, 0
+	}
+	if l.limit.Rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	wait := (1 - b.tokens) / l.limit.Rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.last).Seconds()
+	return min(float64(l.limit.Burst), b.tokens+elapsed*l.limit.Rate)
+}
+
+// sweep drops full buckets, which behave the same as new ones, the caller must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
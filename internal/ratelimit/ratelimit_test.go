@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	limiter := New(Limit{Rate: 2, Burst: 3})
+	limiter.now = func() time.Time { return now }
+
+	// The burst is available at once
+	for range 3 {
+		ok, _ := limiter.Allow("a")
+		require.True(t, ok)
+	}
+	ok, retryAfter := limiter.Allow("a")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Keys have separate buckets
+	ok, _ = limiter.Allow("b")
+	require.True(t, ok)
+
+	// Tokens are refilled at the rate
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.Allow("a")
+	require.True(t, ok)
+	ok, _ = limiter.Allow("a")
+	require.False(t, ok)
+
+	// Up to the burst
+	now = now.Add(time.Hour)
+	for range 3 {
+		ok, _ := limiter.Allow("a")
+		require.True(t, ok)
+	}
+	ok, _ = limiter.Allow("a")
+	require.False(t, ok)
+}
+
+func TestLimiter_Check(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	limiter := New(Limit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+
+	// Checks do not take tokens
+	for range 2 {
+		ok, _ := limiter.Check("a")
+		require.True(t, ok)
+	}
+	ok, _ := limiter.Allow("a")
+	require.True(t, ok)
+	ok, retryAfter := limiter.Check("a")
+	require.False(t, ok)
+	require.Equal(t, time.Second, retryAfter)
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	limiter := New(Limit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	limiter.Allow("b")
+	require.Len(t, limiter.buckets, 2)
+
+	// Refilled buckets are dropped, the bucket of the current key is recreated
+	now = now.Add(2 * sweepInterval)
+	ok, _ := limiter.Allow("a")
+	require.True(t, ok)
+	require.Len(t, limiter.buckets, 1)
+}
@@ -0,0 +1,391 @@
+package reconcile
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/vlan"
+)
+
+// Owner is the vlan.VLAN.ManagedBy value of VLANs owned by the reconciler.
+const Owner = "reconciler"
+
+type Action string
+
+const (
+	ActionCreate   Action = "create"
+	ActionUpdate   Action = "update"
+	ActionDelete   Action = "delete"
+	ActionAdopt    Action = "adopt"
+	ActionConflict Action = "conflict"
+)
+
+// Drift is a single difference found between the YAML directory and the store.
+type Drift struct {
+	Action Action `json:"action"`
+	VRF    string `json:"vrf,omitempty"`
+	VID    uint16 `json:"vid"`
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Status is the outcome of the most recent reconciliation run.
+type Status struct {
+	Dir     string    `json:"dir"`
+	Prune   bool      `json:"prune"`
+	DryRun  bool      `json:"dryRun"`
+	LastRun time.Time `json:"lastRun"`
+	Drift   []Drift   `json:"drift"`
+	Error   string    `json:"error,omitempty"`
+}
+
+type Options struct {
+	// Directory containing *.yml / *.yaml VLAN definitions.
+	Dir string
+	// How often the directory is re-read and reconciled.
+	Interval time.Duration
+	// Delete unmanaged VLANs that are not declared, and adopt unmanaged VLANs with a declared VID.
+	Prune bool
+	// Only report drift, never modify the store.
+	DryRun bool
+}
+
+// Reconciler makes a vlan.Store match the VLANs declared in a directory of YAML files.
+type Reconciler struct {
+	store  *vlan.Store
+	opts   Options
+	status Status
+	mu     sync.RWMutex
+}
+
+// definitionsFile is the YAML representation of a single file in the reconciled directory.
+type definitionsFile struct {
+	VLANs []definition `yaml:"vlans"`
+}
+
+type definition struct {
+	VID     uint16         `yaml:"vid"`
+	Name    string         `yaml:"name"`
+	Subnet  string         `yaml:"subnet"`
+	Gateway string         `yaml:"gateway"`
+	Status  string         `yaml:"status"`
+	VRF     string         `yaml:"vrf"`
+	Tags    []string       `yaml:"tags"`
+	Fields  map[string]any `yaml:"fields"`
+}
+
+func New(store *vlan.Store, opts Options) *Reconciler {
+	return &Reconciler{
+		store: store,
+		opts:  opts,
+		status: Status{
+			Dir:    opts.Dir,
+			Prune:  opts.Prune,
+			DryRun: opts.DryRun,
+			Drift:  []Drift{},
+		},
+	}
+}
+
+// Run reconciles immediately and then on every interval until ctx is cancelled.
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		status := r.Reconcile()
+		if status.Error != "" {
+			log.Printf("reconcile %v failed: %s", r.opts.Dir, status.Error)
+		} else if len(status.Drift) > 0 {
+			log.Printf("reconcile %v found %d differences", r.opts.Dir, len(status.Drift))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the outcome of the most recent reconciliation run.
+func (r *Reconciler) Status() Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status
+}
+
+// Reconcile performs a single reconciliation run and returns its outcome.
+func (r *Reconciler) Reconcile() Status {
+	status := Status{
+		Dir:     r.opts.Dir,
+		Prune:   r.opts.Prune,
+		DryRun:  r.opts.DryRun,
+		LastRun: time.Now().UTC(),
+		Drift:   []Drift{},
+	}
+
+	drift, err := r.reconcile()
+	if drift != nil {
+		status.Drift = drift
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+	return status
+}
+
+func (r *Reconciler) reconcile() ([]Drift, error) {
+	desired, err := loadDir(r.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vlans: %w", err)
+	}
+	slices.SortFunc(current, func(a, b vlan.VLAN) int {
+		return cmp.Or(cmp.Compare(a.VRF, b.VRF), cmp.Compare(a.VID, b.VID), cmp.Compare(a.Name, b.Name))
+	})
+	currentByKey := make(map[key]vlan.VLAN, len(current))
+	for _, v := range current {
+		// Prefer VLANs already owned by the reconciler when older store files have duplicate VIDs
+		if existing, ok := currentByKey[keyOf(v)]; ok && existing.ManagedBy == Owner {
+			continue
+		}
+		currentByKey[keyOf(v)] = v
+	}
+	desiredKeys := make(map[key]bool, len(desired))
+	for _, want := range desired {
+		desiredKeys[keyOf(want)] = true
+	}
+
+	// VLANs are deleted first, so that the declared VLANs can take over their VIDs and subnets
+	drift := []Drift{}
+	for _, have := range current {
+		if desiredKeys[keyOf(have)] && currentByKey[keyOf(have)].ID == have.ID {
+			continue
+		}
+		if have.ManagedBy != Owner && !r.opts.Prune {
+			continue
+		}
+		drift = append(drift, Drift{Action: ActionDelete, VRF: have.VRF, VID: have.VID, Name: have.Name})
+		if !r.opts.DryRun {
+			if err := r.store.Delete(have.ID, Owner); err != nil {
+				return drift, fmt.Errorf("failed to delete vlan %s: %w", keyOf(have), err)
+			}
+		}
+	}
+
+	// The VLAN of a VID is looked up and replaced atomically, so that it cannot change in the meantime
+	for _, want := range desired {
+		var change *Drift
+		_, _, err := r.store.SaveByVID(want, func(existing *vlan.VLAN) error {
+			change = r.plan(existing, want)
+			if change == nil || change.Action == ActionConflict || r.opts.DryRun {
+				return errUnchanged
+			}
+			return nil
+		})
+		if change != nil {
+			drift = append(drift, *change)
+		}
+		switch {
+		case err == nil, errors.Is(err, errUnchanged):
+		case errors.Is(err, vlan.ErrDuplicateVID):
+			drift = append(drift, Drift{Action: ActionConflict, VRF: want.VRF, VID: want.VID, Name: want.Name, Detail: err.Error()})
+		default:
+			return drift, fmt.Errorf("failed to save vlan %s: %w", keyOf(want), err)
+		}
+	}
+
+	return drift, nil
+}
+
+// errUnchanged stops SaveByVID from saving a declared VLAN that is unchanged, or that the reconciler may not take
+// over.
+var errUnchanged = errors.New("unchanged")
+
+// plan returns the drift between the stored VLAN of a VID, nil if there is none, and the declared VLAN, or nil if
+// there is no drift.
+func (r *Reconciler) plan(existing *vlan.VLAN, want vlan.VLAN) *Drift {
+	switch {
+	case existing == nil:
+		return &Drift{Action: ActionCreate, VRF: want.VRF, VID: want.VID, Name: want.Name}
+	case existing.ManagedBy != Owner && !r.opts.Prune:
+		return &Drift{
+			Action: ActionConflict,
+			VRF:    want.VRF,
+			VID:    want.VID,
+			Name:   existing.Name,
+			Detail: fmt.Sprintf("VID is used by unmanaged vlan %s", existing.ID),
+		}
+	case existing.ManagedBy != Owner || !sameSpec(*existing, want):
+		action := ActionUpdate
+		if existing.ManagedBy != Owner {
+			action = ActionAdopt
+		}
+		return &Drift{Action: action, VRF: want.VRF, VID: want.VID, Name: want.Name, Detail: diff(*existing, want)}
+	}
+	return nil
+}
+
+// key identifies a VLAN for the reconciler, VIDs are unique per VRF.
+type key struct {
+	vrf string
+	vid uint16
+}
+
+func keyOf(v vlan.VLAN) key {
+	return key{vrf: v.VRF, vid: v.VID}
+}
+
+func (k key) String() string {
+	if k.vrf == "" {
+		return fmt.Sprint(k.vid)
+	}
+	return fmt.Sprintf("%d in vrf %s", k.vid, k.vrf)
+}
+
+// loadDir reads and validates all VLAN definitions in dir, ordered by file name.
+func loadDir(dir string) ([]vlan.VLAN, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", dir, err)
+	}
+
+	vlans := []vlan.VLAN{}
+	fileByKey := map[key]string{}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		fileVLANs, err := loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range fileVLANs {
+			if other, ok := fileByKey[keyOf(v)]; ok {
+				return nil, fmt.Errorf("duplicate VID %s in %v and %v", keyOf(v), other, path)
+			}
+			fileByKey[keyOf(v)] = path
+			vlans = append(vlans, v)
+		}
+	}
+	return vlans, nil
+}
+
+func loadFile(path string) ([]vlan.VLAN, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", path, err)
+	}
+
+	file := definitionsFile{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %w", path, err)
+	}
+
+	vlans := make([]vlan.VLAN, 0, len(file.VLANs))
+	for i, def := range file.VLANs {
+		v, err := def.toVLAN()
+		if err != nil {
+			return nil, fmt.Errorf("invalid VLAN #%d in %v: %w", i+1, path, err)
+		}
+		if errors := v.Validate(); len(errors) > 0 {
+			return nil, fmt.Errorf("invalid VLAN #%d in %v: %s", i+1, path, strings.Join(errors, ", "))
+		}
+		vlans = append(vlans, v)
+	}
+	return vlans, nil
+}
+
+func (d definition) toVLAN() (vlan.VLAN, error) {
+	subnet, err := netip.ParsePrefix(d.Subnet)
+	if err != nil {
+		return vlan.VLAN{}, err
+	}
+	gateway, err := netip.ParseAddr(d.Gateway)
+	if err != nil {
+		return vlan.VLAN{}, err
+	}
+	return vlan.VLAN{
+		VID:       d.VID,
+		Name:      d.Name,
+		Subnet:    subnet,
+		Gateway:   gateway,
+		Status:    d.Status,
+		VRF:       d.VRF,
+		Tags:      d.Tags,
+		Fields:    customfield.Normalize(d.Fields),
+		ManagedBy: Owner,
+	}, nil
+}
+
+func sameSpec(a, b vlan.VLAN) bool {
+	return a.VID == b.VID && a.Name == b.Name && a.Subnet == b.Subnet && a.Gateway == b.Gateway && a.Status == b.Status &&
+		a.VRF == b.VRF && slices.Equal(a.Tags, b.Tags) && maps.Equal(a.Fields, b.Fields)
+}
+
+// diff describes the fields that differ between the stored and the desired VLAN.
+func diff(have, want vlan.VLAN) string {
+	changes := []string{}
+	if have.Name != want.Name {
+		changes = append(changes, fmt.Sprintf("name %q -> %q", have.Name, want.Name))
+	}
+	if have.Subnet != want.Subnet {
+		changes = append(changes, fmt.Sprintf("subnet %s -> %s", have.Subnet, want.Subnet))
+	}
+	if have.Gateway != want.Gateway {
+		changes = append(changes, fmt.Sprintf("gateway %s -> %s", have.Gateway, want.Gateway))
+	}
+	if have.Status != want.Status {
+		changes = append(changes, fmt.Sprintf("status %q -> %q", have.Status, want.Status))
+	}
+	if have.VRF != want.VRF {
+		changes = append(changes, fmt.Sprintf("vrf %q -> %q", have.VRF, want.VRF))
+	}
+	if !slices.Equal(have.Tags, want.Tags) {
+		changes = append(changes, fmt.Sprintf("tags %q -> %q", have.Tags, want.Tags))
+	}
+	fields := map[string]any{}
+	maps.Copy(fields, have.Fields)
+	maps.Copy(fields, want.Fields)
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		haveValue, haveOK := have.Fields[name]
+		wantValue, wantOK := want.Fields[name]
+		if haveOK != wantOK || haveValue != wantValue {
+			changes = append(changes, fmt.Sprintf("field %s %q -> %q", name, formatField(haveValue, haveOK), formatField(wantValue, wantOK)))
+		}
+	}
+	return strings.Join(changes, ", ")
+}
+
+func formatField(value any, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return customfield.Format(value)
+}
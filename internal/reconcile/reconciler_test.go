@@ -0,0 +1,232 @@
+package reconcile
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/vlan"
+)
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+	store, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	manual := saveVLAN(t, store, 30, "manual", "")
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yml", `
+vlans:
+  - {vid: 10, name: users, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled}
+  - {vid: 30, name: servers, subnet: 10.0.30.0/24, gateway: 10.0.30.1, status: enabled}
+`)
+	writeFile(t, dir, "b.yaml", `
+vlans:
+  - {vid: 20, name: voice, subnet: 10.0.20.0/24, gateway: 10.0.20.1, status: enabled}
+`)
+	writeFile(t, dir, "README.md", "ignored")
+
+	// Unmanaged VLAN with a declared VID is reported, but not touched
+	reconciler := New(store, Options{Dir: dir})
+	status := reconciler.Reconcile()
+	require.Empty(t, status.Error)
+	require.Equal(t, []Drift{
+		{Action: ActionCreate, VID: 10, Name: "users"},
+		{Action: ActionConflict, VID: 30, Name: "manual", Detail: "VID is used by unmanaged vlan " + manual.ID.String()},
+		{Action: ActionCreate, VID: 20, Name: "voice"},
+	}, status.Drift)
+	require.Equal(t, status, reconciler.Status())
+	require.Equal(t, manual, *store.Get(manual.ID))
+	requireVIDs(t, store, 10, 20, 30)
+
+	// Nothing to do on the second run, apart from the conflict
+	status = reconciler.Reconcile()
+	require.Len(t, status.Drift, 1)
+	require.Equal(t, ActionConflict, status.Drift[0].Action)
+
+	// Changed and removed definitions are applied, VLANs that move to another VID or VRF are replaced
+	writeFile(t, dir, "a.yml", `
+vlans:
+  - {vid: 10, name: staff, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled}
+  - {vid: 21, name: voice, subnet: 10.0.20.0/24, gateway: 10.0.20.1, status: enabled}
+`)
+	require.NoError(t, os.Remove(filepath.Join(dir, "b.yaml")))
+	status = reconciler.Reconcile()
+	require.Empty(t, status.Error)
+	require.Equal(t, []Drift{
+		{Action: ActionDelete, VID: 20, Name: "voice"},
+		{Action: ActionUpdate, VID: 10, Name: "staff", Detail: `name "users" -> "staff"`},
+		{Action: ActionCreate, VID: 21, Name: "voice"},
+	}, status.Drift)
+	requireVIDs(t, store, 10, 21, 30)
+}
+
+func TestReconcile_VRFs(t *testing.T) {
+	t.Parallel()
+	store, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	// An unmanaged VLAN with the same VID in another VRF is neither adopted nor a conflict
+	manual := saveVLAN(t, store, 10, "manual", "")
+	manual.VRF = "blue"
+	require.NoError(t, store.Update(manual))
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yml", `
+vlans:
+  - {vid: 10, name: users, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled}
+  - {vid: 10, name: red-users, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled, vrf: red}
+`)
+	reconciler := New(store, Options{Dir: dir})
+	status := reconciler.Reconcile()
+	require.Empty(t, status.Error)
+	require.Equal(t, []Drift{
+		{Action: ActionCreate, VID: 10, Name: "users"},
+		{Action: ActionCreate, VRF: "red", VID: 10, Name: "red-users"},
+	}, status.Drift)
+	require.Empty(t, reconciler.Reconcile().Drift)
+	require.Equal(t, manual, *store.Get(manual.ID))
+
+	// Only the VLAN of the VRF it was removed from is deleted
+	writeFile(t, dir, "a.yml", `
+vlans:
+  - {vid: 10, name: red-users, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled, vrf: red}
+`)
+	status = reconciler.Reconcile()
+	require.Empty(t, status.Error)
+	require.Equal(t, []Drift{{Action: ActionDelete, VID: 10, Name: "users"}}, status.Drift)
+	require.Len(t, store.GetByVID("red", 10), 1)
+	require.Equal(t, []vlan.VLAN{manual}, store.GetByVID("blue", 10))
+	require.Empty(t, store.GetByVID("", 10))
+}
+
+func TestReconcile_TagsAndFields(t *testing.T) {
+	t.Parallel()
+	schema := customfield.Schema{Fields: []customfield.Field{
+		{Name: "site", Type: customfield.TypeString},
+		{Name: "rack", Type: customfield.TypeInt},
+	}}
+	store, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"),
+		vlan.WithFieldSchema(func() customfield.Schema { return schema }))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yml", `
+vlans:
+  - {vid: 10, name: users, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled, tags: [prod], fields: {site: ber1, rack: 12}}
+`)
+	reconciler := New(store, Options{Dir: dir})
+	status := reconciler.Reconcile()
+	require.Empty(t, status.Error)
+	require.Len(t, status.Drift, 1)
+
+	// Stored values compare equal to the definitions
+	require.Empty(t, reconciler.Reconcile().Drift)
+
+	writeFile(t, dir, "a.yml", `
+vlans:
+  - {vid: 10, name: users, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled, tags: [prod, voip], fields: {rack: 13}}
+`)
+	status = reconciler.Reconcile()
+	require.Empty(t, status.Error)
+	require.Equal(t, []Drift{
+		{Action: ActionUpdate, VID: 10, Name: "users", Detail: `tags ["prod"] -> ["prod" "voip"], field rack "12" -> "13", field site "ber1" -> ""`},
+	}, status.Drift)
+}
+
+func TestReconcile_Prune(t *testing.T) {
+	t.Parallel()
+	store, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	adopted := saveVLAN(t, store, 10, "users", "")
+	pruned := saveVLAN(t, store, 20, "voice", "")
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yml", `
+vlans:
+  - {vid: 10, name: users, subnet: 10.0.10.0/24, gateway: 10.0.10.1, status: enabled}
+`)
+
+	// Dry run reports, but does not change anything
+	status := New(store, Options{Dir: dir, Prune: true, DryRun: true}).Reconcile()
+	require.Equal(t, []Drift{
+		{Action: ActionDelete, VID: 20, Name: "voice"},
+		{Action: ActionAdopt, VID: 10, Name: "users", Detail: "subnet 192.168.10.0/24 -> 10.0.10.0/24, gateway 192.168.10.1 -> 10.0.10.1"},
+	}, status.Drift)
+	requireVIDs(t, store, 10, 20)
+
+	status = New(store, Options{Dir: dir, Prune: true}).Reconcile()
+	require.Empty(t, status.Error)
+	require.Len(t, status.Drift, 2)
+	requireVIDs(t, store, 10)
+	require.Equal(t, Owner, store.Get(adopted.ID).ManagedBy)
+	require.Nil(t, store.Get(pruned.ID))
+}
+
+func TestReconcile_InvalidDefinitions(t *testing.T) {
+	t.Parallel()
+	store, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	saveVLAN(t, store, 10, "managed", Owner)
+
+	for name, content := range map[string]string{
+		"invalid yaml":   "vlans: [",
+		"invalid subnet": "vlans: [{vid: 20, name: a, subnet: invalid, gateway: 10.0.20.1}]",
+		"invalid vlan":   "vlans: [{vid: 5000, name: a, subnet: 10.0.20.0/24, gateway: 10.0.20.1}]",
+		"duplicate vid": `vlans:
+  - {vid: 20, name: a, subnet: 10.0.20.0/24, gateway: 10.0.20.1}
+  - {vid: 20, name: b, subnet: 10.0.20.0/24, gateway: 10.0.20.1}`,
+		"duplicate vid in vrf": `vlans:
+  - {vid: 20, name: a, subnet: 10.0.20.0/24, gateway: 10.0.20.1, vrf: red}
+  - {vid: 20, name: b, subnet: 10.0.21.0/24, gateway: 10.0.21.1, vrf: red}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "a.yml", content)
+
+			// Invalid input never results in changes, even for managed VLANs
+			status := New(store, Options{Dir: dir}).Reconcile()
+			require.NotEmpty(t, status.Error)
+			require.Empty(t, status.Drift)
+			requireVIDs(t, store, 10)
+		})
+	}
+
+	status := New(store, Options{Dir: filepath.Join(t.TempDir(), "missing")}).Reconcile()
+	require.NotEmpty(t, status.Error)
+}
+
+func saveVLAN(t *testing.T, store *vlan.Store, vid uint16, name, managedBy string) vlan.VLAN {
+	t.Helper()
+	v := vlan.VLAN{
+		ID:        uuid.New(),
+		VID:       vid,
+		Name:      name,
+		Subnet:    netip.PrefixFrom(netip.AddrFrom4([4]byte{192, 168, byte(vid), 0}), 24),
+		Gateway:   netip.AddrFrom4([4]byte{192, 168, byte(vid), 1}),
+		Status:    "enabled",
+		ManagedBy: managedBy,
+	}
+	require.NoError(t, store.Save(v))
+	return v
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func requireVIDs(t *testing.T, store *vlan.Store, vids ...uint16) {
+	t.Helper()
+	vlans, err := store.List()
+	require.NoError(t, err)
+	actual := []uint16{}
+	for _, v := range vlans {
+		actual = append(actual, v.VID)
+	}
+	require.ElementsMatch(t, vids, actual)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/netip"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/change"
+	"net-admin-api/internal/dhcp"
+	"net-admin-api/internal/encryption"
+	"net-admin-api/internal/idempotency"
+	"net-admin-api/internal/vlan"
+)
+
+func TestEncryptionAtRest(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	key := make([]byte, encryption.KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	keyring, err := encryption.ParseKeyring(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+
+	vlanStore, err := vlan.NewStore(filepath.Join(dir, "vlans.json"), vlan.WithEncryption(keyring))
+	require.NoError(t, err)
+	t.Cleanup(func() { vlanStore.Close() })
+	changeRequests, err := change.NewStore(filepath.Join(dir, "change-requests.json"), change.WithEncryption(keyring))
+	require.NoError(t, err)
+	idempotencyKeys, err := idempotency.NewStore(filepath.Join(dir, "idempotency-keys.json"), time.Hour,
+		idempotency.WithEncryption(keyring))
+	require.NoError(t, err)
+	scopes, err := dhcp.NewStore(filepath.Join(dir, "dhcp-scopes.json"), dhcp.WithEncryption(keyring))
+	require.NoError(t, err)
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: tokenAlice}})
+	require.NoError(t, err)
+	server := newHTTPServerWithStore(t, vlanStore, WithUsers(users), WithChangeRequests(changeRequests),
+		WithIdempotencyKeys(idempotencyKeys), WithDHCP(scopes))
+
+	// Every store that describes a VLAN gets to persist it
+	vlan1 := newVLAN(t, 10, "users", "10.99.10.0/24", "10.99.10.1")
+	resp := doIdempotentRequest(t, server, "/api/v1/vlans", tokenAlice, "create-1", vlan1)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	vlan1.ID = uuid.MustParse(path.Base(resp.Header.Get("Location")))
+	vlan2 := newVLAN(t, 20, "voice", "10.99.20.0/24", "10.99.20.1")
+	submitChangeRequest(t, server, tokenAlice, TransactionOperation{Op: vlan.OperationCreate, VLAN: vlan2})
+	resp = doRequest(t, server, "PUT", "/api/v1/vlans/"+vlan1.ID.String()+"/dhcp", tokenAlice, dhcp.Scope{
+		Pools: []dhcp.Pool{{Start: netip.MustParseAddr("10.99.10.100"), End: netip.MustParseAddr("10.99.10.199")}},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// No file on disk reveals a subnet or an address of them
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "10.99.", entry.Name())
+	}
+}
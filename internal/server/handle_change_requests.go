@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/change"
+	"net-admin-api/internal/vlan"
+)
+
+type ChangeRequestReview struct {
+	Comment string `json:"comment"`
+}
+
+func (s *Server) HandleListChangeRequests(respWriter http.ResponseWriter, req *http.Request) {
+	if s.changeRequests == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	if err := s.invalidateStaleChangeRequests(); err != nil {
+		log.Printf("failed to invalidate change requests: %v", err)
+		internalError(respWriter, "failed to read change requests")
+		return
+	}
+
+	status := change.Status(req.URL.Query().Get("status"))
+	changeReqs := []change.Request{}
+	for _, changeReq := range s.changeRequests.List() {
+		if status == "" || changeReq.Status == status {
+			changeReqs = append(changeReqs, changeReq)
+		}
+	}
+	writeJSONResponse(respWriter, changeReqs)
+}
+
+func (s *Server) HandleCreateChangeRequest(respWriter http.ResponseWriter, req *http.Request) {
+	if s.changeRequests == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+
+	defer req.Body.Close()
+	txOp := TransactionOperation{}
+	if err := json.NewDecoder(req.Body).Decode(&txOp); err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse change request: %v", err))
+		return
+	}
+	op, status, code, err := s.validateOperation(txOp)
+	if err != nil {
+		writeError(respWriter, status, code, err.Error())
+		return
+	}
+
+	changeReq := change.Request{
+		ID:          uuid.New(),
+		Op:          op.Type,
+		VLAN:        op.VLAN,
+		Status:      change.StatusPending,
+		SubmittedBy: auth.ActorFrom(req.Context()).Name,
+		SubmittedAt: time.Now().UTC(),
+	}
+	if op.Type != vlan.OperationCreate {
+		changeReq.Base = s.vlanStore.Get(op.VLAN.ID)
+	}
+	if err := s.changeRequests.Save(changeReq); err != nil {
+		log.Printf("failed to save change request: %v", err)
+		internalError(respWriter, "failed to save change request")
+		return
+	}
+
+	respWriter.Header().Set("Location", fmt.Sprintf("%s/%s", req.URL.RequestURI(), changeReq.ID.String()))
+	respWriter.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) HandleReadChangeRequest(respWriter http.ResponseWriter, req *http.Request) {
+	if s.changeRequests == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	changeReqID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		invalidInput(respWriter, "invalid change request id")
+		return
+	}
+	if err := s.invalidateStaleChangeRequests(); err != nil {
+		log.Printf("failed to invalidate change requests: %v", err)
+		internalError(respWriter, "failed to read change request")
+		return
+	}
+
+	changeReq := s.changeRequests.Get(changeReqID)
+	if changeReq == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, changeReq)
+}
+
+func (s *Server) HandleApproveChangeRequest(respWriter http.ResponseWriter, req *http.Request) {
+	s.reviewChangeRequest(respWriter, req, true)
+}
+
+func (s *Server) HandleRejectChangeRequest(respWriter http.ResponseWriter, req *http.Request) {
+	s.reviewChangeRequest(respWriter, req, false)
+}
+
+func (s *Server) reviewChangeRequest(respWriter http.ResponseWriter, req *http.Request, approve bool) {
+	if s.changeRequests == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	changeReqID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		invalidInput(respWriter, "invalid change request id")
+		return
+	}
+
+	defer req.Body.Close()
+	review := ChangeRequestReview{}
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil && err != io.EOF {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse review: %v", err))
+		return
+	}
+
+	// Without authentication reviews are as open as every other endpoint, and there is no second user to review
+	actor := auth.ActorFrom(req.Context())
+	if s.authEnabled() && !actor.HasRole(auth.RoleAdmin) {
+		forbidden(respWriter, "reviewing change requests requires the admin role")
+		return
+	}
+
+	changeReq, err := s.changeRequests.Review(changeReqID, change.Review{
+		Reviewer:        actor.Name,
+		Approve:         approve,
+		Comment:         review.Comment,
+		AllowSelfReview: !s.authEnabled(),
+	}, s.applyChangeRequest)
+	switch {
+	case errors.Is(err, change.ErrNotFound):
+		http.NotFound(respWriter, req)
+	case errors.Is(err, change.ErrSelfReview):
+		forbidden(respWriter, err.Error())
+	case errors.Is(err, change.ErrNotPending), errors.Is(err, change.ErrStale):
+		conflict(respWriter, err.Error())
+	case errors.Is(err, vlan.ErrOverlap), errors.Is(err, vlan.ErrDuplicateVID), errors.Is(err, vlan.ErrUnknownVRF),
+		errors.Is(err, vlan.ErrInvalidFields), errors.Is(err, vlan.ErrManaged):
+		conflict(respWriter, err.Error())
+	case err != nil:
+		log.Printf("failed to review change request: %v", err)
+		internalError(respWriter, "failed to review change request")
+	default:
+		writeJSONResponse(respWriter, changeReq)
+	}
+}
+
+func (s *Server) applyChangeRequest(changeReq change.Request) error {
+	op := changeReq.Operation()
+	if changeReq.IsStale(s.vlanStore.Get(op.VLAN.ID)) {
+		return change.ErrStale
+	}
+	if err := s.vlanStore.Apply([]vlan.Operation{op}, vlan.ExpectUnmanaged()); err != nil {
+		if errors.Is(err, vlan.ErrNotFound) || errors.Is(err, vlan.ErrAlreadyExists) {
+			return change.ErrStale
+		}
+		if opErr := (&vlan.OperationError{}); errors.As(err, &opErr) {
+			return opErr.Err
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Server) invalidateStaleChangeRequests() error {
+	return s.changeRequests.Invalidate(func(changeReq change.Request) bool {
+		return changeReq.IsStale(s.vlanStore.Get(changeReq.VLAN.ID))
+	})
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/change"
+	"net-admin-api/internal/vlan"
+)
+
+const (
+	tokenAlice = "alice-token"
+	tokenBob   = "bob-token"
+	tokenCarol = "carol-token"
+)
+
+func TestHandleChangeRequests_OK(t *testing.T) {
+	t.Parallel()
+	server := newChangeRequestServer(t)
+
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	resp := doRequest(t, server, "POST", "/api/v1/vlans", tokenAlice, vlan1)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	vlan1.ID = uuid.MustParse(path.Base(resp.Header.Get("Location")))
+
+	// Proposed update is not applied before approval
+	updated := *vlan1
+	updated.Name = "renamed"
+	updateID := submitChangeRequest(t, server, tokenAlice, TransactionOperation{Op: vlan.OperationUpdate, VLAN: &updated})
+	changeReq := readChangeRequest(t, server, updateID)
+	require.Equal(t, change.StatusPending, changeReq.Status)
+	require.Equal(t, "alice", changeReq.SubmittedBy)
+	require.Equal(t, vlan1, changeReq.Base)
+	require.Equal(t, "test1", readVLANWithToken(t, server, vlan1.ID).Name)
+
+	// Approved update is applied
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests/"+updateID.String()+"/approve", tokenBob, ChangeRequestReview{Comment: "lgtm"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	changeReq = readChangeRequest(t, server, updateID)
+	require.Equal(t, change.StatusApproved, changeReq.Status)
+	require.Equal(t, "bob", changeReq.ReviewedBy)
+	require.Equal(t, "lgtm", changeReq.Comment)
+	require.Equal(t, updated, *readVLANWithToken(t, server, vlan1.ID))
+
+	// Rejected create is not applied
+	vlan2 := newVLAN(t, 2, "test2", "192.168.2.0/24", "192.168.2.1")
+	createID := submitChangeRequest(t, server, tokenAlice, TransactionOperation{Op: vlan.OperationCreate, VLAN: vlan2})
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests/"+createID.String()+"/reject", tokenCarol, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, change.StatusRejected, readChangeRequest(t, server, createID).Status)
+
+	// Approved create and delete are applied
+	createID = submitChangeRequest(t, server, tokenAlice, TransactionOperation{Op: vlan.OperationCreate, VLAN: vlan2})
+	deleteID := submitChangeRequest(t, server, tokenAlice, TransactionOperation{Op: vlan.OperationDelete, ID: vlan1.ID})
+	require.Len(t, listChangeRequests(t, server, "?status=pending"), 2)
+	for _, id := range []uuid.UUID{createID, deleteID} {
+		resp = doRequest(t, server, "POST", "/api/v1/change-requests/"+id.String()+"/approve", tokenCarol, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	vlan2.ID = readChangeRequest(t, server, createID).VLAN.ID
+	require.Equal(t, *vlan2, *readVLANWithToken(t, server, vlan2.ID))
+	resp = doRequest(t, server, "GET", "/api/v1/vlans/"+vlan1.ID.String(), tokenAlice, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	require.Len(t, listChangeRequests(t, server, ""), 4)
+	require.Len(t, listChangeRequests(t, server, "?status=pending"), 0)
+}
+
+func TestHandleChangeRequests_NOK(t *testing.T) {
+	t.Parallel()
+	server := newChangeRequestServer(t)
+
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	resp := doRequest(t, server, "POST", "/api/v1/vlans", tokenAlice, vlan1)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	vlan1.ID = uuid.MustParse(path.Base(resp.Header.Get("Location")))
+
+	// Missing and invalid tokens
+	resp = doRequest(t, server, "GET", "/api/v1/change-requests", "", nil)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/change-requests", "invalid", nil)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Invalid proposals are rejected immediately
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests", tokenAlice, TransactionOperation{
+		Op:   vlan.OperationCreate,
+		VLAN: newVLAN(t, 5000, "test2", "192.168.2.0/24", "192.168.2.1"),
+	})
+	requireInvalidInputResponse(t, resp)
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests", tokenAlice, TransactionOperation{Op: vlan.OperationDelete, ID: uuid.New()})
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/change-requests/invalid", tokenAlice, nil)
+	requireInvalidInputResponse(t, resp)
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests/"+uuid.NewString()+"/approve", tokenBob, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	updated := *vlan1
+	updated.Name = "renamed"
+	updateID := submitChangeRequest(t, server, tokenBob, TransactionOperation{Op: vlan.OperationUpdate, VLAN: &updated})
+	approvePath := "/api/v1/change-requests/" + updateID.String() + "/approve"
+
+	// Reviewer must be an admin, and not the submitter
+	resp = doRequest(t, server, "POST", approvePath, tokenAlice, nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doRequest(t, server, "POST", approvePath, tokenBob, nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Change request is invalidated when its target VLAN changes
+	vlan1.Status = "disabled"
+	resp = doRequest(t, server, "PUT", "/api/v1/vlans/"+vlan1.ID.String(), tokenAlice, vlan1)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	changeReq := readChangeRequest(t, server, updateID)
+	require.Equal(t, change.StatusInvalidated, changeReq.Status)
+	require.NotEmpty(t, changeReq.Reason)
+	resp = doRequest(t, server, "POST", approvePath, tokenCarol, nil)
+	requireConflictResponse(t, resp)
+	require.Equal(t, *vlan1, *readVLANWithToken(t, server, vlan1.ID))
+
+	// Change request is invalidated when approved after its target VLAN changed
+	updateID = submitChangeRequest(t, server, tokenBob, TransactionOperation{Op: vlan.OperationUpdate, VLAN: &updated})
+	resp = doRequest(t, server, "DELETE", "/api/v1/vlans/"+vlan1.ID.String(), tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests/"+updateID.String()+"/approve", tokenCarol, nil)
+	requireConflictResponse(t, resp)
+
+	// Reviewed change requests cannot be reviewed again
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests/"+updateID.String()+"/reject", tokenCarol, nil)
+	requireConflictResponse(t, resp)
+}
+
+func TestHandleChangeRequests_Anonymous(t *testing.T) {
+	t.Parallel()
+	changeRequests, err := change.NewStore(filepath.Join(t.TempDir(), "change-requests.json"))
+	require.NoError(t, err)
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithChangeRequests(changeRequests))
+
+	// Without users, everyone is the same anonymous actor and may review any change request
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	vlan2 := newVLAN(t, 2, "test2", "192.168.2.0/24", "192.168.2.1")
+	createID := submitChangeRequest(t, server, "", TransactionOperation{Op: vlan.OperationCreate, VLAN: vlan1})
+	rejectID := submitChangeRequest(t, server, "", TransactionOperation{Op: vlan.OperationCreate, VLAN: vlan2})
+	resp := doRequest(t, server, "POST", "/api/v1/change-requests/"+createID.String()+"/approve", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "POST", "/api/v1/change-requests/"+rejectID.String()+"/reject", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	approved := readChangeRequest(t, server, createID)
+	require.Equal(t, change.StatusApproved, approved.Status)
+	require.Equal(t, auth.Anonymous.Name, approved.ReviewedBy)
+	require.Equal(t, change.StatusRejected, readChangeRequest(t, server, rejectID).Status)
+	vlan1.ID = approved.VLAN.ID
+	require.Equal(t, vlan1, readVLAN(t, server, vlan1.ID))
+}
+
+func TestHandleChangeRequests_NotConfigured(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	resp, err := server.Client().Get(server.URL + "/api/v1/change-requests")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func newChangeRequestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	users, err := auth.NewUsers([]auth.User{
+		{Name: "alice", Token: tokenAlice},
+		{Name: "bob", Token: tokenBob, Roles: []string{auth.RoleAdmin}},
+		{Name: "carol", Token: tokenCarol, Roles: []string{auth.RoleAdmin}},
+	})
+	require.NoError(t, err)
+	changeRequests, err := change.NewStore(filepath.Join(t.TempDir(), "change-requests.json"))
+	require.NoError(t, err)
+
+	return newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithUsers(users), WithChangeRequests(changeRequests))
+}
+
+// Sends a request with an optional bearer token and JSON body, the response body is closed on cleanup.
+func doRequest(t *testing.T, server *httptest.Server, method, path, token string, body any) *http.Response {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		buf := &bytes.Buffer{}
+		require.NoError(t, json.NewEncoder(buf).Encode(body))
+		reqBody = buf
+	}
+	req, err := http.NewRequest(method, server.URL+path, reqBody)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func submitChangeRequest(t *testing.T, server *httptest.Server, token string, op TransactionOperation) uuid.UUID {
+	t.Helper()
+	resp := doRequest(t, server, "POST", "/api/v1/change-requests", token, op)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	return uuid.MustParse(path.Base(resp.Header.Get("Location")))
+}
+
+func readChangeRequest(t *testing.T, server *httptest.Server, id uuid.UUID) change.Request {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/change-requests/"+id.String(), tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	changeReq := change.Request{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&changeReq))
+	return changeReq
+}
+
+func listChangeRequests(t *testing.T, server *httptest.Server, query string) []change.Request {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/change-requests"+query, tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	changeReqs := []change.Request{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&changeReqs))
+	return changeReqs
+}
+
+func readVLANWithToken(t *testing.T, server *httptest.Server, id uuid.UUID) *vlan.VLAN {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/vlans/"+id.String(), tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	v := vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+	return &v
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/vlan"
+)
+
+func (s *Server) HandleReadCustomFields(respWriter http.ResponseWriter, req *http.Request) {
+	if s.customFields == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, s.customFields.Get())
+}
+
+func (s *Server) HandleUpdateCustomFields(respWriter http.ResponseWriter, req *http.Request) {
+	if s.customFields == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	// Without authentication everyone may change the schema, like everything else
+	if s.authEnabled() && !auth.ActorFrom(req.Context()).HasRole(auth.RoleAdmin) {
+		forbidden(respWriter, "changing custom fields requires the admin role")
+		return
+	}
+
+	defer req.Body.Close()
+	schema := customfield.Schema{}
+	if err := json.NewDecoder(req.Body).Decode(&schema); err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse custom fields: %v", err))
+		return
+	}
+	if schema.Fields == nil {
+		schema.Fields = []customfield.Field{}
+	}
+	if errors := schema.Validate(); len(errors) > 0 {
+		invalidInput(respWriter, strings.Join(errors, ", "))
+		return
+	}
+
+	// Existing VLANs must remain valid, e.g. a field cannot become required while VLANs do not have it
+	err := s.vlanStore.ChangeFieldSchema(schema, func() error {
+		return s.customFields.Save(schema)
+	})
+	if err != nil {
+		if errors.Is(err, vlan.ErrInvalidFields) {
+			conflict(respWriter, err.Error())
+			return
+		}
+		log.Printf("failed to save custom fields: %v", err)
+		internalError(respWriter, "failed to save custom fields")
+		return
+	}
+	writeJSONResponse(respWriter, schema)
+}
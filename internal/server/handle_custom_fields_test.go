@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/vlan"
+)
+
+func TestHandleCustomFields_OK(t *testing.T) {
+	t.Parallel()
+	server := newCustomFieldServer(t)
+	requireCustomFields(t, server, customfield.Schema{Fields: []customfield.Field{}})
+
+	schema := customfield.Schema{Fields: []customfield.Field{
+		{Name: "site", Type: customfield.TypeEnum, Values: []string{"ber1", "fra1"}},
+		{Name: "rack", Type: customfield.TypeInt},
+	}}
+	updateCustomFields(t, server, schema)
+	requireCustomFields(t, server, schema)
+
+	ber := newVLAN(t, 10, "ber-users", "10.0.10.0/24", "10.0.10.1")
+	ber.Tags = []string{"prod", "site:ber1"}
+	ber.Fields = map[string]any{"site": "ber1", "rack": float64(12)}
+	createVLAN(t, server, ber)
+	fra := newVLAN(t, 20, "fra-users", "10.0.20.0/24", "10.0.20.1")
+	fra.Tags = []string{"prod"}
+	fra.Fields = map[string]any{"site": "fra1"}
+	createVLAN(t, server, fra)
+	lab := newVLAN(t, 30, "lab", "10.0.30.0/24", "10.0.30.1")
+	createVLAN(t, server, lab)
+	require.Equal(t, *ber, *readVLAN(t, server, ber.ID))
+
+	requireVLANList(t, server, "?tag=prod", ber, fra)
+	requireVLANList(t, server, "?tag=prod&tag=site:ber1", ber)
+	requireVLANList(t, server, "?field=site:fra1", fra)
+	requireVLANList(t, server, "?field=rack:12&tag=prod", ber)
+	requireVLANList(t, server, "?field=rack:13")
+
+	// A field can become required once all VLANs have it
+	schema.Fields[0].Required = true
+	requireConflictResponse(t, doRequest(t, server, "PUT", "/api/v1/custom-fields", "", schema))
+	lab.Fields = map[string]any{"site": "ber1"}
+	updateVLAN(t, server, lab)
+	updateCustomFields(t, server, schema)
+	requireVLANList(t, server, "?field=site:ber1", ber, lab)
+}
+
+func TestHandleCustomFields_NOK(t *testing.T) {
+	t.Parallel()
+	server := newCustomFieldServer(t)
+	updateCustomFields(t, server, customfield.Schema{Fields: []customfield.Field{
+		{Name: "site", Type: customfield.TypeEnum, Values: []string{"ber1", "fra1"}, Required: true},
+	}})
+
+	for name, fields := range map[string]map[string]any{
+		"missing required field": nil,
+		"invalid enum value":     {"site": "muc1"},
+		"unknown field":          {"site": "ber1", "rack": 12},
+		"nested value":           {"site": map[string]any{"name": "ber1"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+			v.Fields = fields
+			requireInvalidInputResponse(t, doRequest(t, server, "POST", "/api/v1/vlans", "", v))
+		})
+	}
+	duplicateTag := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+	duplicateTag.Fields = map[string]any{"site": "ber1"}
+	duplicateTag.Tags = []string{"prod", "prod"}
+	requireInvalidInputResponse(t, doRequest(t, server, "POST", "/api/v1/vlans", "", duplicateTag))
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/vlans?field=site", "", nil))
+
+	invalid := customfield.Schema{Fields: []customfield.Field{{Name: "site", Type: customfield.TypeEnum}}}
+	requireInvalidInputResponse(t, doRequest(t, server, "PUT", "/api/v1/custom-fields", "", invalid))
+}
+
+func TestHandleCustomFields_RequiresAdmin(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{
+		{Name: "alice", Token: tokenAlice},
+		{Name: "bob", Token: tokenBob, Roles: []string{auth.RoleAdmin}},
+		// A user that happens to have the name of the anonymous actor is no admin either
+		{Name: auth.Anonymous.Name, Token: tokenCarol},
+	})
+	require.NoError(t, err)
+	server := newCustomFieldServer(t, WithUsers(users))
+	schema := customfield.Schema{Fields: []customfield.Field{{Name: "site", Type: customfield.TypeString}}}
+
+	resp := doRequest(t, server, "PUT", "/api/v1/custom-fields", tokenAlice, schema)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doRequest(t, server, "PUT", "/api/v1/custom-fields", tokenCarol, schema)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doRequest(t, server, "PUT", "/api/v1/custom-fields", tokenBob, schema)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/custom-fields", tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestHandleCustomFields_NotConfigured(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+	resp := doRequest(t, server, "GET", "/api/v1/custom-fields", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// Without a schema, VLANs cannot have custom fields
+	v := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+	v.Fields = map[string]any{"site": "ber1"}
+	requireInvalidInputResponse(t, doRequest(t, server, "POST", "/api/v1/vlans", "", v))
+}
+
+func newCustomFieldServer(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+	customFields, err := customfield.NewStore(filepath.Join(t.TempDir(), "custom-fields.json"))
+	require.NoError(t, err)
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"), vlan.WithFieldSchema(customFields.Get))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = vlanStore.Close() })
+	return newHTTPServerWithStore(t, vlanStore, append(opts, WithCustomFields(customFields))...)
+}
+
+func updateCustomFields(t *testing.T, server *httptest.Server, schema customfield.Schema) {
+	t.Helper()
+	resp := doRequest(t, server, "PUT", "/api/v1/custom-fields", "", schema)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func requireCustomFields(t *testing.T, server *httptest.Server, expected customfield.Schema) {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/custom-fields", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	schema := customfield.Schema{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&schema))
+	require.Equal(t, expected, schema)
+}
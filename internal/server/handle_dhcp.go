@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/dhcp"
+	"net-admin-api/internal/vlan"
+)
+
+func (s *Server) HandleListDHCPScopes(respWriter http.ResponseWriter, req *http.Request) {
+	if s.dhcpScopes == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	scopes := []dhcp.Scope{}
+	for _, subnet := range s.dhcpSubnets() {
+		scopes = append(scopes, subnet.Scope)
+	}
+	writeJSONResponse(respWriter, scopes)
+}
+
+func (s *Server) HandleReadDHCPScope(respWriter http.ResponseWriter, req *http.Request) {
+	v := s.dhcpVLANFromPath(respWriter, req)
+	if v == nil {
+		return
+	}
+	scope := s.dhcpScopes.Get(v.ID)
+	if scope == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, scope)
+}
+
+func (s *Server) HandleUpdateDHCPScope(respWriter http.ResponseWriter, req *http.Request) {
+	v := s.dhcpVLANFromPath(respWriter, req)
+	if v == nil {
+		return
+	}
+
+	defer req.Body.Close()
+	scope := &dhcp.Scope{}
+	if err := json.NewDecoder(req.Body).Decode(&scope); err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse dhcp scope: %v", err))
+		return
+	}
+	if scope.VLANID != uuid.Nil && scope.VLANID != v.ID {
+		invalidInput(respWriter, "mismatching vlan id in request body")
+		return
+	}
+	if errors := scope.Validate(*v); len(errors) > 0 {
+		invalidInput(respWriter, strings.Join(errors, ", "))
+		return
+	}
+
+	scope.VLANID = v.ID
+	scope.UpdatedAt = time.Now().UTC()
+	if err := s.dhcpScopes.Save(*scope); err != nil {
+		log.Printf("failed to save dhcp scope: %v", err)
+		internalError(respWriter, "failed to save dhcp scope")
+		return
+	}
+	writeJSONResponse(respWriter, scope)
+}
+
+func (s *Server) HandleDeleteDHCPScope(respWriter http.ResponseWriter, req *http.Request) {
+	v := s.dhcpVLANFromPath(respWriter, req)
+	if v == nil {
+		return
+	}
+
+	if err := s.dhcpScopes.Delete(v.ID); err != nil {
+		if errors.Is(err, dhcp.ErrNotFound) {
+			http.NotFound(respWriter, req)
+			return
+		}
+		log.Printf("failed to delete dhcp scope: %v", err)
+		internalError(respWriter, "failed to delete dhcp scope")
+		return
+	}
+}
+
+func (s *Server) HandleKeaConfig(respWriter http.ResponseWriter, req *http.Request) {
+	if s.dhcpScopes == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	family := dhcp.Family(req.PathValue("family"))
+	if family != dhcp.FamilyDHCP4 && family != dhcp.FamilyDHCP6 {
+		invalidInput(respWriter, fmt.Sprintf("unknown family %q (expected one of %v)", family, dhcp.Families))
+		return
+	}
+	// Each VRF is served by its own Kea server, the global routing table by default
+	vrfName, _, err := s.vrfFromQuery(req)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return
+	}
+
+	// VLANs may have changed since their scopes were saved
+	subnets := slices.DeleteFunc(s.dhcpSubnets(), func(subnet dhcp.Subnet) bool { return subnet.VLAN.VRF != vrfName })
+	for _, subnet := range subnets {
+		if errors := subnet.Scope.Validate(subnet.VLAN); len(errors) > 0 {
+			conflict(respWriter, fmt.Sprintf("dhcp scope of vlan %s is no longer valid: %s", subnet.VLAN.Name, strings.Join(errors, ", ")))
+			return
+		}
+	}
+	config, err := dhcp.RenderKea(family, subnets)
+	if err != nil {
+		conflict(respWriter, err.Error())
+		return
+	}
+
+	respWriter.Header().Set("Content-Type", "application/json")
+	if _, err := respWriter.Write(config); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// dhcpSubnets returns the scopes of existing VLANs. Scopes of deleted VLANs are ignored.
+func (s *Server) dhcpSubnets() []dhcp.Subnet {
+	subnets := []dhcp.Subnet{}
+	for _, scope := range s.dhcpScopes.List() {
+		if v := s.vlanStore.Get(scope.VLANID); v != nil {
+			subnets = append(subnets, dhcp.Subnet{VLAN: *v, Scope: scope})
+		}
+	}
+	return subnets
+}
+
+// dhcpVLANFromPath returns the VLAN identified by the request path, or writes an error response.
+func (s *Server) dhcpVLANFromPath(respWriter http.ResponseWriter, req *http.Request) *vlan.VLAN {
+	if s.dhcpScopes == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	vlanID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		invalidInput(respWriter, "invalid vlan id")
+		return nil
+	}
+	v := s.vlanStore.Get(vlanID)
+	if v == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	return v
+}
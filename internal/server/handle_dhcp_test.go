@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/dhcp"
+)
+
+func TestHandleDHCP_OK(t *testing.T) {
+	t.Parallel()
+	server := newDHCPServer(t)
+	vlan1 := newVLAN(t, 10, "users", "192.168.10.0/24", "192.168.10.1")
+	createVLAN(t, server, vlan1)
+	vlan2 := newVLAN(t, 20, "lab", "2001:db8:20::/64", "2001:db8:20::1")
+	createVLAN(t, server, vlan2)
+
+	scope := dhcp.Scope{
+		Pools:        []dhcp.Pool{{Start: netip.MustParseAddr("192.168.10.100"), End: netip.MustParseAddr("192.168.10.199")}},
+		Reservations: []dhcp.Reservation{{MAC: "00:11:22:33:44:55", IP: netip.MustParseAddr("192.168.10.10"), Hostname: "printer"}},
+		Options:      dhcp.Options{DNSServers: []netip.Addr{netip.MustParseAddr("192.168.10.2")}, Domain: "users.example.com"},
+	}
+	saved := updateDHCPScope(t, server, vlan1.ID, scope)
+	require.Equal(t, vlan1.ID, saved.VLANID)
+	require.False(t, saved.UpdatedAt.IsZero())
+	require.Equal(t, saved, readDHCPScope(t, server, vlan1.ID))
+	updateDHCPScope(t, server, vlan2.ID, dhcp.Scope{
+		Pools: []dhcp.Pool{{Start: netip.MustParseAddr("2001:db8:20::1000"), End: netip.MustParseAddr("2001:db8:20::1fff")}},
+	})
+
+	resp := doRequest(t, server, "GET", "/api/v1/dhcp/scopes", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	scopes := []dhcp.Scope{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&scopes))
+	require.Len(t, scopes, 2)
+
+	// Each family only renders its own VLANs
+	config := readKeaConfig(t, server, dhcp.FamilyDHCP4)
+	subnets := config["Dhcp4"]["subnet4"].([]any)
+	require.Len(t, subnets, 1)
+	require.Equal(t, "192.168.10.0/24", subnets[0].(map[string]any)["subnet"])
+	config = readKeaConfig(t, server, dhcp.FamilyDHCP6)
+	subnets = config["Dhcp6"]["subnet6"].([]any)
+	require.Len(t, subnets, 1)
+	require.Equal(t, "2001:db8:20::/64", subnets[0].(map[string]any)["subnet"])
+
+	// Scopes of deleted VLANs are ignored
+	deleteVLAN(t, server, vlan2.ID)
+	config = readKeaConfig(t, server, dhcp.FamilyDHCP6)
+	require.Empty(t, config["Dhcp6"]["subnet6"])
+
+	resp = doRequest(t, server, "DELETE", "/api/v1/vlans/"+vlan1.ID.String()+"/dhcp", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/vlans/"+vlan1.ID.String()+"/dhcp", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandleDHCP_NOK(t *testing.T) {
+	t.Parallel()
+	server := newDHCPServer(t)
+	vlan1 := newVLAN(t, 10, "users", "192.168.10.0/24", "192.168.10.1")
+	createVLAN(t, server, vlan1)
+	path := "/api/v1/vlans/" + vlan1.ID.String() + "/dhcp"
+
+	for _, scope := range []dhcp.Scope{
+		{Pools: []dhcp.Pool{{Start: netip.MustParseAddr("192.168.11.1"), End: netip.MustParseAddr("192.168.11.10")}}},
+		{Pools: []dhcp.Pool{{Start: netip.MustParseAddr("192.168.10.1"), End: netip.MustParseAddr("192.168.10.10")}}},
+		{VLANID: uuid.New(), Pools: []dhcp.Pool{}},
+		{Pools: []dhcp.Pool{}, Reservations: []dhcp.Reservation{{MAC: "invalid", IP: netip.MustParseAddr("192.168.10.10")}}},
+	} {
+		resp := doRequest(t, server, "PUT", path, "", scope)
+		requireInvalidInputResponse(t, resp)
+	}
+
+	resp := doRequest(t, server, "GET", path, "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "DELETE", path, "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "PUT", "/api/v1/vlans/"+uuid.NewString()+"/dhcp", "", dhcp.Scope{Pools: []dhcp.Pool{}})
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/vlans/invalid/dhcp", "", nil)
+	requireInvalidInputResponse(t, resp)
+	resp = doRequest(t, server, "GET", "/api/v1/dhcp/kea/dhcp5", "", nil)
+	requireInvalidInputResponse(t, resp)
+
+	// Scopes that are no longer valid after a VLAN change are not exported
+	updateDHCPScope(t, server, vlan1.ID, dhcp.Scope{
+		Pools: []dhcp.Pool{{Start: netip.MustParseAddr("192.168.10.100"), End: netip.MustParseAddr("192.168.10.199")}},
+	})
+	vlan1.Subnet = netip.MustParsePrefix("192.168.10.0/25")
+	updateVLAN(t, server, vlan1)
+	resp = doRequest(t, server, "GET", "/api/v1/dhcp/kea/dhcp4", "", nil)
+	requireConflictResponse(t, resp)
+}
+
+func TestHandleDHCP_NotConfigured(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	resp := doRequest(t, server, "GET", "/api/v1/dhcp/scopes", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/dhcp/kea/dhcp4", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func newDHCPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	scopes, err := dhcp.NewStore(filepath.Join(t.TempDir(), "dhcp-scopes.json"))
+	require.NoError(t, err)
+	return newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithDHCP(scopes))
+}
+
+func updateDHCPScope(t *testing.T, server *httptest.Server, vlanID uuid.UUID, scope dhcp.Scope) dhcp.Scope {
+	t.Helper()
+	resp := doRequest(t, server, "PUT", "/api/v1/vlans/"+vlanID.String()+"/dhcp", "", scope)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	saved := dhcp.Scope{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&saved))
+	return saved
+}
+
+func readDHCPScope(t *testing.T, server *httptest.Server, vlanID uuid.UUID) dhcp.Scope {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/vlans/"+vlanID.String()+"/dhcp", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	scope := dhcp.Scope{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&scope))
+	return scope
+}
+
+func readKeaConfig(t *testing.T, server *httptest.Server, family dhcp.Family) map[string]map[string]any {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/dhcp/kea/"+string(family), "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	config := map[string]map[string]any{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&config))
+	return config
+}
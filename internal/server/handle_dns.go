@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"net-admin-api/internal/dns"
+)
+
+// DNSZone summarizes a generated DNS zone.
+type DNSZone struct {
+	Name    string `json:"name"`
+	Records int    `json:"records"`
+}
+
+func (s *Server) HandleListDNSZones(respWriter http.ResponseWriter, req *http.Request) {
+	zones := s.dnsZones(respWriter, req)
+	if zones == nil {
+		return
+	}
+	summaries := make([]DNSZone, len(zones))
+	for i, zone := range zones {
+		summaries[i] = DNSZone{Name: zone.Name, Records: len(zone.Records)}
+	}
+	writeJSONResponse(respWriter, summaries)
+}
+
+func (s *Server) HandleReadDNSZone(respWriter http.ResponseWriter, req *http.Request) {
+	zone := s.dnsZoneFromPath(respWriter, req)
+	if zone == nil {
+		return
+	}
+	respWriter.Header().Set("Content-Type", "text/dns")
+	if _, err := respWriter.Write(zone.ZoneFile()); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// HandleDNSZoneUpdate compares the current content of a zone on a DNS server, e.g. a zone transfer, to the
+// generated zone, and returns the dynamic update that makes them equal as nsupdate input.
+func (s *Server) HandleDNSZoneUpdate(respWriter http.ResponseWriter, req *http.Request) {
+	zone := s.dnsZoneFromPath(respWriter, req)
+	if zone == nil {
+		return
+	}
+
+	defer req.Body.Close()
+	current, err := dns.ParseZone(req.Body, zone.Name)
+	if err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse zone: %v", err))
+		return
+	}
+	update, err := dns.Diff(current, *zone)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return
+	}
+
+	respWriter.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := respWriter.Write(update.Script()); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// dnsZones generates the DNS zones of the VLANs of the VRF given by the vrf parameter, the global routing table by
+// default, and their DHCP reservations, or writes an error response. The serial of the zones is the current Unix
+// time.
+func (s *Server) dnsZones(respWriter http.ResponseWriter, req *http.Request) []dns.Zone {
+	if s.dns == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	vrfName, _, err := s.vrfFromQuery(req)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return nil
+	}
+	vlans, err := s.vlanStore.List()
+	if err != nil {
+		log.Printf("failed to list vlans: %v", err)
+		internalError(respWriter, "failed to list vlans")
+		return nil
+	}
+
+	// The addresses of VRFs may overlap, so each VRF has its own zones, e.g. in a view of the DNS server
+	networks := []dns.Network{}
+	for _, v := range vlans {
+		if v.VRF != vrfName {
+			continue
+		}
+		network := dns.Network{VLAN: v}
+		if s.dhcpScopes != nil {
+			if scope := s.dhcpScopes.Get(v.ID); scope != nil {
+				for _, reservation := range scope.Reservations {
+					if reservation.Hostname != "" {
+						network.Hosts = append(network.Hosts, dns.Host{Name: reservation.Hostname, Addr: reservation.IP})
+					}
+				}
+			}
+		}
+		networks = append(networks, network)
+	}
+
+	opts := *s.dns
+	opts.Serial = uint32(time.Now().Unix())
+	zones, err := dns.Generate(opts, networks)
+	if err != nil {
+		conflict(respWriter, err.Error())
+		return nil
+	}
+	return zones
+}
+
+// dnsZoneFromPath returns the generated zone named by the request path, or writes an error response.
+func (s *Server) dnsZoneFromPath(respWriter http.ResponseWriter, req *http.Request) *dns.Zone {
+	zones := s.dnsZones(respWriter, req)
+	if zones == nil {
+		return nil
+	}
+	zone := dns.FindZone(zones, req.PathValue("zone"))
+	if zone == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	return zone
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/dhcp"
+	"net-admin-api/internal/dns"
+)
+
+func TestHandleDNS_OK(t *testing.T) {
+	t.Parallel()
+	server := newDNSServer(t)
+	vlan1 := newVLAN(t, 10, "users", "192.168.10.0/24", "192.168.10.1")
+	createVLAN(t, server, vlan1)
+	vlan2 := newVLAN(t, 11, "cameras", "192.168.20.64/26", "192.168.20.65")
+	createVLAN(t, server, vlan2)
+	updateDHCPScope(t, server, vlan1.ID, dhcp.Scope{
+		Pools:        []dhcp.Pool{},
+		Reservations: []dhcp.Reservation{{MAC: "00:11:22:33:44:55", IP: netip.MustParseAddr("192.168.10.10"), Hostname: "printer"}},
+	})
+
+	resp := doRequest(t, server, "GET", "/api/v1/dns/zones", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	zones := []DNSZone{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&zones))
+	require.Equal(t, []DNSZone{
+		{Name: "site.example.", Records: 5},
+		{Name: "10.168.192.in-addr.arpa.", Records: 4},
+		{Name: "20.168.192.in-addr.arpa.", Records: 4},
+		{Name: "64-26.20.168.192.in-addr.arpa.", Records: 3},
+	}, zones)
+
+	zoneFile := readDNSZone(t, server, "site.example")
+	require.Contains(t, zoneFile, "gw.vlan10.site.example.\t300\tIN\tA\t192.168.10.1\n")
+	require.Contains(t, zoneFile, "printer.vlan10.site.example.\t300\tIN\tA\t192.168.10.10\n")
+	zoneFile = readDNSZone(t, server, "20.168.192.in-addr.arpa.")
+	require.Contains(t, zoneFile, "65.20.168.192.in-addr.arpa.\t300\tIN\tCNAME\t65.64-26.20.168.192.in-addr.arpa.\n")
+
+	// Nothing to update once the generated zone is loaded
+	zoneFile = readDNSZone(t, server, "site.example")
+	script := postDNSZoneUpdate(t, server, "site.example.", zoneFile)
+	require.Equal(t, http.StatusOK, script.StatusCode)
+	require.Equal(t, "text/plain; charset=utf-8", script.Header.Get("Content-Type"))
+	body, err := io.ReadAll(script.Body)
+	require.NoError(t, err)
+	require.Regexp(t, `^zone site.example.\nprereq yxrrset site.example. IN SOA .*\nsend\n$`, string(body))
+
+	// Records of deleted VLANs are deleted
+	deleteVLAN(t, server, vlan2.ID)
+	script = postDNSZoneUpdate(t, server, "site.example.", zoneFile)
+	require.Equal(t, http.StatusOK, script.StatusCode)
+	body, err = io.ReadAll(script.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "\nupdate delete gw.vlan11.site.example. 300 IN A 192.168.20.65\n")
+}
+
+func TestHandleDNS_NOK(t *testing.T) {
+	t.Parallel()
+	server := newDNSServer(t)
+	createVLAN(t, server, newVLAN(t, 10, "users", "192.168.10.0/24", "192.168.10.1"))
+
+	resp := doRequest(t, server, "GET", "/api/v1/dns/zones/other.example", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = postDNSZoneUpdate(t, server, "site.example", "gw.vlan10 300 IN A")
+	requireInvalidInputResponse(t, resp)
+	resp = postDNSZoneUpdate(t, server, "site.example", "gw.vlan10.other.example. 300 IN A 192.168.10.1")
+	requireInvalidInputResponse(t, resp)
+	resp = postDNSZoneUpdate(t, server, "other.example", "")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	server = newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+	resp = doRequest(t, server, "GET", "/api/v1/dns/zones", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func newDNSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	scopes, err := dhcp.NewStore(filepath.Join(t.TempDir(), "dhcp-scopes.json"))
+	require.NoError(t, err)
+	return newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithDHCP(scopes), WithDNS(dns.Options{
+		Domain:      "site.example",
+		Nameservers: []string{"ns1.site.example."},
+		TTL:         300,
+	}))
+}
+
+func readDNSZone(t *testing.T, server *httptest.Server, zone string) string {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/dns/zones/"+zone, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/dns", resp.Header.Get("Content-Type"))
+	zoneFile, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(zoneFile)
+}
+
+func postDNSZoneUpdate(t *testing.T, server *httptest.Server, zone, current string) *http.Response {
+	t.Helper()
+	resp, err := server.Client().Post(server.URL+"/api/v1/dns/zones/"+zone+"/update", "text/plain", strings.NewReader(current))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
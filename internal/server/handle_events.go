@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"net-admin-api/internal/vlan"
+)
+
+// EventReset is sent instead of missed events when a stream cannot be resumed from Last-Event-ID,
+// clients should then re-read the full VLAN list.
+const EventReset = "reset"
+
+// HandleEvents streams VLAN events. Event IDs are "<epoch>-<sequence number>", so that IDs from before a
+// restart, whose sequence numbers are reused, are not mistaken for current ones.
+func (s *Server) HandleEvents(respWriter http.ResponseWriter, req *http.Request) {
+	var lastEventEpoch string
+	var lastEventID uint64
+	resume := false
+	if lastEventIDStr := req.Header.Get("Last-Event-ID"); lastEventIDStr != "" {
+		// IDs without an epoch are from before epochs were added, and cannot be resumed
+		epoch, seq, found := strings.Cut(lastEventIDStr, "-")
+		if !found {
+			epoch, seq = "", epoch
+		}
+		var err error
+		if lastEventID, err = strconv.ParseUint(seq, 10, 64); err != nil {
+			invalidInput(respWriter, "invalid Last-Event-ID")
+			return
+		}
+		lastEventEpoch = epoch
+		resume = true
+	}
+
+	// Subscribe before reading the backlog, so that no events are missed in between
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	respController := http.NewResponseController(respWriter)
+	respWriter.Header().Set("Content-Type", "text/event-stream")
+	respWriter.Header().Set("Cache-Control", "no-cache")
+	respWriter.Header().Set("X-Accel-Buffering", "no")
+	respWriter.WriteHeader(http.StatusOK)
+
+	write := func(format string, args ...any) bool {
+		// Extend the server's WriteTimeout for as long as the stream is alive
+		_ = respController.SetWriteDeadline(time.Now().Add(2 * s.eventHeartbeat))
+		if _, err := fmt.Fprintf(respWriter, format, args...); err != nil {
+			return false
+		}
+		return respController.Flush() == nil
+	}
+	writeEvent := func(event vlan.Event) bool {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("failed to encode event: %v", err)
+			return false
+		}
+		lastEventID = event.ID
+		return write("id: %s-%d\nevent: %s\ndata: %s\n\n", event.Epoch, event.ID, event.Type, data)
+	}
+
+	if resume {
+		backlog, ok := s.events.Since(lastEventEpoch, lastEventID)
+		if !ok {
+			lastEventID = 0
+			if !write("event: %s\ndata: {}\n\n", EventReset) {
+				return
+			}
+		}
+		for _, event := range backlog {
+			if !writeEvent(event) {
+				return
+			}
+		}
+	} else if !write(": connected\n\n") {
+		return
+	}
+
+	heartbeat := time.NewTicker(s.eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// Too slow to keep up, the client reconnects with Last-Event-ID
+				return
+			}
+			if event.ID <= lastEventID {
+				continue
+			}
+			if !writeEvent(event) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": heartbeat\n\n") {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+)
+
+func TestHandleEvents_OK(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithEventStream(3, time.Hour))
+
+	// New events are streamed
+	stream := openEventStream(t, server, "")
+	require.Equal(t, sseMessage{comment: "connected"}, stream.next(t))
+
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	createVLAN(t, server, vlan1)
+	vlan1.Name = "renamed"
+	updateVLAN(t, server, vlan1)
+	created := requireEventMessage(t, stream.next(t), 1, vlan.EventCreated, vlan1.ID.String())
+	requireEventMessage(t, stream.next(t), 2, vlan.EventUpdated, vlan1.ID.String())
+	stream.close()
+	epoch := created.Epoch
+	require.NotEmpty(t, epoch)
+
+	// Missed events are replayed when resuming
+	vlan2 := newVLAN(t, 2, "test2", "192.168.2.0/24", "192.168.2.1")
+	createVLAN(t, server, vlan2)
+	deleteVLAN(t, server, vlan1.ID)
+
+	stream = openEventStream(t, server, epoch+"-2")
+	requireEventMessage(t, stream.next(t), 3, vlan.EventCreated, vlan2.ID.String())
+	requireEventMessage(t, stream.next(t), 4, vlan.EventDeleted, vlan1.ID.String())
+	deleteVLAN(t, server, vlan2.ID)
+	requireEventMessage(t, stream.next(t), 5, vlan.EventDeleted, vlan2.ID.String())
+	stream.close()
+
+	// Events older than the log size cannot be replayed, and neither can unknown events, or events of another
+	// epoch, whose sequence numbers are unrelated
+	for i, lastEventID := range []string{epoch + "-1", epoch + "-100", "0123456789abcdef-4", "4"} {
+		stream = openEventStream(t, server, lastEventID)
+		require.Equal(t, sseMessage{event: EventReset, data: "{}"}, stream.next(t))
+		subnet, gateway := fmt.Sprintf("192.168.%d.0/24", 3+i), fmt.Sprintf("192.168.%d.1", 3+i)
+		createVLAN(t, server, newVLAN(t, uint16(3+i), "test3", subnet, gateway))
+		require.Equal(t, string(vlan.EventCreated), stream.next(t).event)
+		stream.close()
+	}
+}
+
+func TestHandleEvents_Heartbeat(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithEventStream(3, 10*time.Millisecond))
+
+	stream := openEventStream(t, server, "")
+	require.Equal(t, sseMessage{comment: "connected"}, stream.next(t))
+	require.Equal(t, sseMessage{comment: "heartbeat"}, stream.next(t))
+	require.Equal(t, sseMessage{comment: "heartbeat"}, stream.next(t))
+}
+
+func TestHandleEvents_NOK(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	req, err := http.NewRequest("GET", server.URL+"/api/v1/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "0123456789abcdef-invalid")
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	requireInvalidInputResponse(t, resp)
+}
+
+type sseMessage struct {
+	id      string
+	event   string
+	data    string
+	comment string
+}
+
+type eventStream struct {
+	resp     *http.Response
+	messages chan sseMessage
+}
+
+func openEventStream(t *testing.T, server *httptest.Server, lastEventID string) *eventStream {
+	t.Helper()
+	req, err := http.NewRequest("GET", server.URL+"/api/v1/events", nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	stream := &eventStream{resp: resp, messages: make(chan sseMessage, 100)}
+	t.Cleanup(stream.close)
+	go func() {
+		defer close(stream.messages)
+		scanner := bufio.NewScanner(resp.Body)
+		message := sseMessage{}
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "":
+				if value == "" {
+					stream.messages <- message
+					message = sseMessage{}
+				} else {
+					message.comment = value
+				}
+			case "id":
+				message.id = value
+			case "event":
+				message.event = value
+			case "data":
+				message.data = value
+			}
+		}
+	}()
+	return stream
+}
+
+func (s *eventStream) next(t *testing.T) sseMessage {
+	t.Helper()
+	select {
+	case message, ok := <-s.messages:
+		require.True(t, ok, "event stream closed")
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+		return sseMessage{}
+	}
+}
+
+func (s *eventStream) close() {
+	s.resp.Body.Close()
+}
+
+func requireEventMessage(t *testing.T, message sseMessage, id uint64, eventType vlan.EventType, vlanID string) vlan.Event {
+	t.Helper()
+	require.Equal(t, string(eventType), message.event)
+
+	event := vlan.Event{}
+	require.NoError(t, json.Unmarshal([]byte(message.data), &event))
+	require.Equal(t, fmt.Sprintf("%s-%d", event.Epoch, id), message.id)
+	require.Equal(t, id, event.ID)
+	require.Equal(t, eventType, event.Type)
+	require.Equal(t, vlanID, event.VLAN.ID.String())
+	return event
+}
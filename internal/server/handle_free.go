@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"net-admin-api/internal/freespace"
+	"net-admin-api/internal/vlan"
+)
+
+// FreeSubnet is a free block found for a requested prefix length.
+type FreeSubnet struct {
+	Subnet netip.Prefix `json:"subnet"`
+}
+
+// HandleFreeVIDs returns the VID ranges that are not used by the VLANs matching the filters of the VLAN list.
+func (s *Server) HandleFreeVIDs(respWriter http.ResponseWriter, req *http.Request) {
+	from, err := vidFromQuery(req, "from", vlan.MinVID)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return
+	}
+	to, err := vidFromQuery(req, "to", vlan.MaxVID)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return
+	}
+	if from > to {
+		invalidInput(respWriter, fmt.Sprintf("from %d must not be greater than to %d", from, to))
+		return
+	}
+	vlans := s.filteredVLANs(respWriter, req)
+	if vlans == nil {
+		return
+	}
+
+	used := make([]uint16, len(vlans))
+	for i, v := range vlans {
+		used[i] = v.VID
+	}
+	writeJSONResponse(respWriter, freespace.VIDs(used, from, to))
+}
+
+// HandleFreeSubnets returns the blocks of a supernet that are not used by the VLANs of a VRF, as the minimal set of
+// prefixes.
+func (s *Server) HandleFreeSubnets(respWriter http.ResponseWriter, req *http.Request) {
+	supernet, err := netip.ParsePrefix(req.URL.Query().Get("supernet"))
+	if err != nil {
+		invalidInput(respWriter, "invalid supernet")
+		return
+	}
+	blocks := s.freeBlocks(respWriter, req, supernet)
+	if blocks == nil {
+		return
+	}
+	writeJSONResponse(respWriter, blocks)
+}
+
+// HandleFindFreeSubnet returns a free block of a supernet with the requested prefix length, taken from the
+// smallest free block that fits it.
+func (s *Server) HandleFindFreeSubnet(respWriter http.ResponseWriter, req *http.Request) {
+	supernet, err := netip.ParsePrefix(req.URL.Query().Get("supernet"))
+	if err != nil {
+		invalidInput(respWriter, "invalid supernet")
+		return
+	}
+	bits, err := strconv.Atoi(req.URL.Query().Get("length"))
+	if err != nil || bits < supernet.Bits() || bits > supernet.Addr().BitLen() {
+		invalidInput(respWriter, fmt.Sprintf("invalid length (expected range %d..%d)", supernet.Bits(), supernet.Addr().BitLen()))
+		return
+	}
+	blocks := s.freeBlocks(respWriter, req, supernet)
+	if blocks == nil {
+		return
+	}
+
+	subnet, ok := freespace.Find(blocks, bits)
+	if !ok {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, FreeSubnet{Subnet: subnet})
+}
+
+// freeBlocks returns the blocks of a supernet that are not used by the VLANs of the VRF of the vrf query parameter,
+// or of the global routing table, or writes an error response. Unlike VIDs, blocks are free only if no VLAN of the
+// VRF uses them, so tag and field filters are rejected.
+func (s *Server) freeBlocks(respWriter http.ResponseWriter, req *http.Request, supernet netip.Prefix) []netip.Prefix {
+	if req.URL.Query().Has("tag") || req.URL.Query().Has("field") {
+		invalidInput(respWriter, "free subnets cannot be filtered by tags or fields, since subnets must not overlap any vlan of the vrf")
+		return nil
+	}
+	vrfName, _, err := s.vrfFromQuery(req)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return nil
+	}
+	vlans, err := s.vlanStore.List()
+	if err != nil {
+		log.Printf("failed to read vlans: %v", err)
+		internalError(respWriter, "failed to read vlans")
+		return nil
+	}
+
+	used := []netip.Prefix{}
+	for _, v := range vlans {
+		if v.VRF == vrfName {
+			used = append(used, v.Subnet)
+		}
+	}
+	return freespace.Prefixes(supernet, used)
+}
+
+// vidFromQuery returns a VID query parameter, or the default value if it is missing.
+func vidFromQuery(req *http.Request, name string, defaultVID uint16) (uint16, error) {
+	if !req.URL.Query().Has(name) {
+		return defaultVID, nil
+	}
+	vid, err := strconv.ParseUint(req.URL.Query().Get(name), 10, 16)
+	if err != nil || vid < vlan.MinVID || vid > vlan.MaxVID {
+		return 0, fmt.Errorf("invalid %s (expected range %d..%d)", name, vlan.MinVID, vlan.MaxVID)
+	}
+	return uint16(vid), nil
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/freespace"
+)
+
+func TestHandleFree_OK(t *testing.T) {
+	t.Parallel()
+	server := newCustomFieldServer(t)
+	updateCustomFields(t, server, customfield.Schema{Fields: []customfield.Field{{Name: "site", Type: customfield.TypeString}}})
+
+	ber := newVLAN(t, 10, "ber-users", "10.0.0.0/24", "10.0.0.1")
+	ber.Fields = map[string]any{"site": "ber1"}
+	createVLAN(t, server, ber)
+	berVoice := newVLAN(t, 12, "ber-voice", "10.0.2.0/23", "10.0.2.1")
+	berVoice.Fields = map[string]any{"site": "ber1"}
+	createVLAN(t, server, berVoice)
+	fra := newVLAN(t, 11, "fra-users", "10.0.8.0/21", "10.0.8.1")
+	fra.Fields = map[string]any{"site": "fra1"}
+	createVLAN(t, server, fra)
+	red := newVLAN(t, 13, "red-users", "10.0.4.0/22", "10.0.4.1")
+	red.VRF = "red"
+	red.Fields = map[string]any{"site": "ber1"}
+	createVLAN(t, server, red)
+
+	requireFreeVIDs(t, server, "", freespace.VIDRange{From: 1, To: 9}, freespace.VIDRange{From: 14, To: 4094})
+	requireFreeVIDs(t, server, "?from=10&to=20", freespace.VIDRange{From: 14, To: 20})
+	requireFreeVIDs(t, server, "?from=10&to=20&field=site:ber1", freespace.VIDRange{From: 11, To: 11}, freespace.VIDRange{From: 14, To: 20})
+	requireFreeVIDs(t, server, "?from=10&to=20&vrf=global", freespace.VIDRange{From: 13, To: 20})
+
+	// Subnets are free in one VRF, the global routing table by default
+	requireFreeSubnets(t, server, "?supernet=10.0.0.0/20", "10.0.1.0/24", "10.0.4.0/22")
+	requireFreeSubnets(t, server, "?supernet=10.0.0.0/20&vrf=global", "10.0.1.0/24", "10.0.4.0/22")
+	requireFreeSubnets(t, server, "?supernet=10.0.0.0/20&vrf=red", "10.0.0.0/22", "10.0.8.0/21")
+	requireFreeSubnets(t, server, "?supernet=10.0.8.0/22")
+
+	requireFreeSubnet(t, server, "?supernet=10.0.0.0/20&length=24", "10.0.1.0/24")
+	requireFreeSubnet(t, server, "?supernet=10.0.0.0/20&length=23", "10.0.4.0/23")
+	requireFreeSubnet(t, server, "?supernet=10.0.0.0/16&length=20", "10.0.16.0/20")
+	resp := doRequest(t, server, "GET", "/api/v1/free/subnets/find?supernet=10.0.0.0/20&length=21", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandleFree_NOK(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	for _, path := range []string{
+		"/api/v1/free/vids?from=0",
+		"/api/v1/free/vids?to=4095",
+		"/api/v1/free/vids?from=20&to=10",
+		"/api/v1/free/vids?field=site",
+		"/api/v1/free/subnets",
+		"/api/v1/free/subnets?supernet=10.0.0.0",
+		"/api/v1/free/subnets?supernet=10.0.0.0/16&field=site:ber1",
+		"/api/v1/free/subnets?supernet=10.0.0.0/16&tag=prod",
+		"/api/v1/free/subnets/find?supernet=10.0.0.0/16&length=24&tag=prod",
+		"/api/v1/free/subnets/find?supernet=10.0.0.0/16",
+		"/api/v1/free/subnets/find?supernet=10.0.0.0/16&length=8",
+		"/api/v1/free/subnets/find?supernet=10.0.0.0/16&length=33",
+	} {
+		t.Run(path, func(t *testing.T) {
+			requireInvalidInputResponse(t, doRequest(t, server, "GET", path, "", nil))
+		})
+	}
+}
+
+func requireFreeVIDs(t *testing.T, server *httptest.Server, query string, expected ...freespace.VIDRange) {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/free/vids"+query, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	ranges := []freespace.VIDRange{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ranges))
+	require.Equal(t, append([]freespace.VIDRange{}, expected...), ranges)
+}
+
+func requireFreeSubnets(t *testing.T, server *httptest.Server, query string, expected ...string) {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/free/subnets"+query, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	subnets := []string{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&subnets))
+	require.Equal(t, append([]string{}, expected...), subnets)
+}
+
+func requireFreeSubnet(t *testing.T, server *httptest.Server, query, expected string) {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/free/subnets/find"+query, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	found := FreeSubnet{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&found))
+	require.Equal(t, expected, found.Subnet.String())
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"net/netip"
+
+	"net-admin-api/internal/vlan"
+)
+
+// HandleLookup returns the VLANs with the longest subnet that contains an address, in the given VRF or, without
+// one, in every VRF.
+func (s *Server) HandleLookup(respWriter http.ResponseWriter, req *http.Request) {
+	addr, err := netip.ParseAddr(req.URL.Query().Get("ip"))
+	if err != nil {
+		invalidInput(respWriter, "invalid ip address")
+		return
+	}
+	vrfName, filtered, err := s.vrfFromQuery(req)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return
+	}
+
+	var vlans []vlan.VLAN
+	addr = addr.Unmap().WithZone("")
+	if filtered {
+		vlans = s.vlanStore.Lookup(vrfName, addr)
+	} else {
+		vlans = s.vlanStore.LookupAll(addr)
+	}
+	if len(vlans) == 0 {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, vlans)
+}
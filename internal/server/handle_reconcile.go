@@ -0,0 +1,13 @@
+package server
+
+import (
+	"net/http"
+)
+
+func (s *Server) HandleReconcileStatus(respWriter http.ResponseWriter, req *http.Request) {
+	if s.reconciler == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, s.reconciler.Status())
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/reconcile"
+	"net-admin-api/internal/vlan"
+)
+
+func TestHandleReconcile_ReadOnly(t *testing.T) {
+	t.Parallel()
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+
+	reconcileDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(reconcileDir, "site.yml"), []byte(`
+vlans:
+  - vid: 10
+    name: users
+    subnet: 10.0.10.0/24
+    gateway: 10.0.10.1
+    status: enabled
+`), 0o644))
+	reconciler := reconcile.New(vlanStore, reconcile.Options{Dir: reconcileDir})
+	server := newHTTPServerWithStore(t, vlanStore, WithReconciler(reconciler))
+
+	// Manually created VLAN is not touched by the reconciler
+	manual := newVLAN(t, 20, "manual", "10.0.20.0/24", "10.0.20.1")
+	createVLAN(t, server, manual)
+	require.Empty(t, reconciler.Reconcile().Error)
+
+	vlansFromAPI := readVLANs(t, server)
+	require.Len(t, vlansFromAPI, 2)
+	require.Equal(t, *manual, vlansFromAPI[manual.ID])
+
+	var managed vlan.VLAN
+	for _, v := range vlansFromAPI {
+		if v.VID == 10 {
+			managed = v
+		}
+	}
+	require.Equal(t, reconcile.Owner, managed.ManagedBy)
+
+	// Managed VLAN cannot be updated or deleted through the API
+	managed.Name = "renamed"
+	req, err := http.NewRequest("PUT", server.URL+"/api/v1/vlans/"+managed.ID.String(), encodeVLAN(t, &managed))
+	require.NoError(t, err)
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	requireConflictResponse(t, resp)
+
+	req, err = http.NewRequest("DELETE", server.URL+"/api/v1/vlans/"+managed.ID.String(), nil)
+	require.NoError(t, err)
+	resp, err = server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	requireConflictResponse(t, resp)
+
+	// Created VLANs cannot claim to be managed
+	claimed := newVLAN(t, 30, "claimed", "10.0.30.0/24", "10.0.30.1")
+	claimed.ManagedBy = reconcile.Owner
+	createVLAN(t, server, claimed)
+	require.Empty(t, readVLAN(t, server, claimed.ID).ManagedBy)
+
+	// Status reports the drift of the last run
+	resp, err = server.Client().Get(server.URL + "/api/v1/reconcile/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	status := reconcile.Status{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.Equal(t, []reconcile.Drift{{Action: reconcile.ActionCreate, VID: 10, Name: "users"}}, status.Drift)
+}
+
+func TestHandleReconcileStatus_NotConfigured(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	resp, err := server.Client().Get(server.URL + "/api/v1/reconcile/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func requireConflictResponse(t *testing.T, resp *http.Response) {
+	t.Helper()
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	errResp := ErrorResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	require.Equal(t, ErrCodeConflict, errResp.Code)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"net-admin-api/internal/freespace"
+	"net-admin-api/internal/stats"
+	"net-admin-api/internal/vlan"
+)
+
+// HandleStats summarizes the VLANs matching the filters of the VLAN list, with the utilization of the VID
+// ranges given as range=from-to, or of the default ranges.
+func (s *Server) HandleStats(respWriter http.ResponseWriter, req *http.Request) {
+	vidRanges := stats.DefaultVIDRanges
+	if req.URL.Query().Has("range") {
+		vidRanges = []freespace.VIDRange{}
+		for _, param := range req.URL.Query()["range"] {
+			vidRange, err := parseVIDRange(param)
+			if err != nil {
+				invalidInput(respWriter, err.Error())
+				return
+			}
+			vidRanges = append(vidRanges, vidRange)
+		}
+	}
+	vlans := s.filteredVLANs(respWriter, req)
+	if vlans == nil {
+		return
+	}
+	writeJSONResponse(respWriter, stats.Compute(vlans, vidRanges))
+}
+
+// HandleMetrics returns the stats of all VLANs for Prometheus. Like the health check it is served outside of
+// /api, so that scrapers need no token.
+func (s *Server) HandleMetrics(respWriter http.ResponseWriter, req *http.Request) {
+	vlans, err := s.vlanStore.List()
+	if err != nil {
+		log.Printf("failed to read vlans: %v", err)
+		internalError(respWriter, "failed to read vlans")
+		return
+	}
+	summary := stats.Compute(vlans, stats.DefaultVIDRanges)
+
+	respWriter.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := respWriter.Write(summary.Metrics(s.perVLANMetrics)); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// parseVIDRange parses a range of VLAN IDs given as from-to.
+func parseVIDRange(param string) (freespace.VIDRange, error) {
+	invalid := fmt.Errorf("invalid range %q (expected from-to within %d..%d)", param, vlan.MinVID, vlan.MaxVID)
+	fromStr, toStr, ok := strings.Cut(param, "-")
+	if !ok {
+		return freespace.VIDRange{}, invalid
+	}
+	from, fromErr := strconv.ParseUint(fromStr, 10, 16)
+	to, toErr := strconv.ParseUint(toStr, 10, 16)
+	if fromErr != nil || toErr != nil || from < vlan.MinVID || to > vlan.MaxVID || from > to {
+		return freespace.VIDRange{}, invalid
+	}
+	return freespace.VIDRange{From: uint16(from), To: uint16(to)}, nil
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/stats"
+	"net-admin-api/internal/vlan"
+)
+
+func TestHandleStats(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+	createVLAN(t, server, newVLAN(t, 10, "users", "192.168.10.0/24", "192.168.10.1"))
+	disabled := newVLAN(t, 2000, "old", "192.168.20.0/23", "192.168.20.1")
+	disabled.Status = "disabled"
+	createVLAN(t, server, disabled)
+
+	resp := doRequest(t, server, "GET", "/api/v1/stats", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	summary := stats.Stats{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+	require.Equal(t, 2, summary.VLANs)
+	require.Equal(t, map[string]int{"enabled": 1, "disabled": 1}, summary.ByStatus)
+	require.Equal(t, []int{1, 1}, []int{summary.VIDRanges[0].Used, summary.VIDRanges[1].Used})
+	require.Equal(t, stats.Capacity{VLANs: 2, Addresses: 768, Usable: 762}, summary.IPv4)
+	require.Equal(t, "users", summary.PerVLAN[0].Name)
+
+	// Custom ranges, and the filters of the VLAN list
+	resp = doRequest(t, server, "GET", "/api/v1/stats?range=1-99&range=100-4094&vrf=global", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	summary = stats.Stats{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+	require.Len(t, summary.VIDRanges, 2)
+	require.Equal(t, uint16(99), summary.VIDRanges[0].To)
+	require.Equal(t, 3995, summary.VIDRanges[1].Total)
+
+	for _, query := range []string{"?range=10", "?range=0-10", "?range=10-4095", "?range=20-10"} {
+		requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/stats"+query, "", nil))
+	}
+
+}
+
+func TestHandleMetrics(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: tokenAlice}})
+	require.NoError(t, err)
+	for _, perVLAN := range []bool{false, true} {
+		opts := []Option{WithUsers(users)}
+		if perVLAN {
+			opts = append(opts, WithPerVLANMetrics())
+		}
+		server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), opts...)
+		disabled := newVLAN(t, 2000, "old", "192.168.20.0/23", "192.168.20.1")
+		disabled.Status = "disabled"
+		for _, v := range []*vlan.VLAN{newVLAN(t, 10, "users", "192.168.10.0/24", "192.168.10.1"), disabled} {
+			require.Equal(t, http.StatusCreated, doRequest(t, server, "POST", "/api/v1/vlans", tokenAlice, v).StatusCode)
+		}
+
+		// Scrapers need no token
+		resp := doRequest(t, server, "GET", "/metrics", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+		metrics, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(metrics), `netadmin_vlans{status="disabled"} 1`)
+		require.Contains(t, string(metrics), `netadmin_vids_used{range="1006-4094"} 1`)
+		require.Contains(t, string(metrics), `netadmin_usable_addresses{family="ipv4"} 762`)
+		require.Equal(t, perVLAN, strings.Contains(string(metrics), `name="users"`))
+	}
+}
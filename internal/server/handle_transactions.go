@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/vlan"
+)
+
+const (
+	OperationApplied = "applied"
+	OperationFailed  = "failed"
+	OperationAborted = "aborted"
+)
+
+type TransactionRequest struct {
+	Operations []TransactionOperation `json:"operations"`
+}
+
+// TransactionOperation is a VLAN create or update with a VLAN body, or a delete by ID.
+type TransactionOperation struct {
+	Op   vlan.OperationType `json:"op"`
+	ID   uuid.UUID          `json:"id"`
+	VLAN *vlan.VLAN         `json:"vlan,omitempty"`
+}
+
+type OperationResult struct {
+	Index  int                `json:"index"`
+	Op     vlan.OperationType `json:"op"`
+	ID     uuid.UUID          `json:"id"`
+	Status string             `json:"status"`
+	Error  string             `json:"error,omitempty"`
+}
+
+// TransactionResponse has the same code and message as ErrorResponse when the transaction fails.
+type TransactionResponse struct {
+	Code    string            `json:"code,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Results []OperationResult `json:"results"`
+}
+
+func (s *Server) HandleTransaction(respWriter http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	txReq := TransactionRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&txReq); err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse transaction: %v", err))
+		return
+	}
+	if len(txReq.Operations) == 0 {
+		invalidInput(respWriter, "transaction must contain at least one operation")
+		return
+	}
+
+	ops := make([]vlan.Operation, len(txReq.Operations))
+	results := make([]OperationResult, len(txReq.Operations))
+	failedStatus, failedCode := 0, ""
+	for i, txOp := range txReq.Operations {
+		op, status, code, err := s.validateOperation(txOp)
+		op.By = auth.ActorFrom(req.Context()).Name
+		ops[i] = op
+		results[i] = OperationResult{Index: i, Op: txOp.Op, ID: op.VLAN.ID, Status: OperationAborted}
+		if err != nil {
+			results[i].Status = OperationFailed
+			results[i].Error = err.Error()
+			if failedStatus == 0 {
+				failedStatus, failedCode = status, code
+			}
+		}
+	}
+	if failedStatus != 0 {
+		writeTransactionError(respWriter, failedStatus, failedCode, results)
+		return
+	}
+
+	if err := s.vlanStore.Apply(ops, vlan.ExpectUnmanaged()); err != nil {
+		opErr := &vlan.OperationError{}
+		if errors.As(err, &opErr) && errors.Is(err, vlan.ErrNotFound) {
+			results[opErr.Index].Status = OperationFailed
+			results[opErr.Index].Error = "vlan not found"
+			writeTransactionError(respWriter, http.StatusNotFound, ErrCodeNotFound, results)
+			return
+		}
+		if errors.As(err, &opErr) && (errors.Is(err, vlan.ErrOverlap) || errors.Is(err, vlan.ErrDuplicateVID) ||
+			errors.Is(err, vlan.ErrManaged) || errors.Is(err, vlan.ErrUnknownVRF) || errors.Is(err, vlan.ErrInvalidFields)) {
+			results[opErr.Index].Status = OperationFailed
+			results[opErr.Index].Error = opErr.Err.Error()
+			if errors.Is(err, vlan.ErrOverlap) || errors.Is(err, vlan.ErrDuplicateVID) || errors.Is(err, vlan.ErrManaged) {
+				writeTransactionError(respWriter, http.StatusConflict, ErrCodeConflict, results)
+			} else {
+				writeTransactionError(respWriter, http.StatusBadRequest, ErrCodeInvalidInput, results)
+			}
+			return
+		}
+
+		log.Printf("failed to apply transaction: %v", err)
+		internalError(respWriter, "failed to apply transaction")
+		return
+	}
+
+	for i := range results {
+		results[i].Status = OperationApplied
+	}
+	writeJSONResponse(respWriter, TransactionResponse{Results: results})
+}
+
+// validateOperation validates a requested operation the same way as the single VLAN endpoints do.
+func (s *Server) validateOperation(txOp TransactionOperation) (vlan.Operation, int, string, error) {
+	op := vlan.Operation{Type: txOp.Op}
+	switch txOp.Op {
+	case vlan.OperationCreate, vlan.OperationUpdate:
+		if txOp.VLAN == nil {
+			return op, http.StatusBadRequest, ErrCodeInvalidInput, errors.New("vlan is required")
+		}
+		op.VLAN = *txOp.VLAN
+		if txOp.Op == vlan.OperationCreate {
+			op.VLAN.ID = uuid.New()
+		}
+		op.VLAN.ManagedBy = ""
+		if errors := op.VLAN.Validate(); len(errors) > 0 {
+			return op, http.StatusBadRequest, ErrCodeInvalidInput, fmt.Errorf("%s", strings.Join(errors, ", "))
+		}
+	case vlan.OperationDelete:
+		op.VLAN.ID = txOp.ID
+	default:
+		return op, http.StatusBadRequest, ErrCodeInvalidInput, fmt.Errorf("unknown operation %q", txOp.Op)
+	}
+
+	// Whether the VLAN is managed by another component is checked when the operations are applied
+	if txOp.Op != vlan.OperationCreate && s.vlanStore.Get(op.VLAN.ID) == nil {
+		return op, http.StatusNotFound, ErrCodeNotFound, errors.New("vlan not found")
+	}
+	return op, 0, "", nil
+}
+
+func writeTransactionError(respWriter http.ResponseWriter, status int, code string, results []OperationResult) {
+	respWriter.Header().Set("Content-Type", "application/json")
+	respWriter.WriteHeader(status)
+	writeJSONResponse(respWriter, TransactionResponse{
+		Code:    code,
+		Message: "transaction was not applied",
+		Results: results,
+	})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+)
+
+func TestHandleTransaction_OK(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	vlan2 := newVLAN(t, 2, "test2", "192.168.2.0/24", "192.168.2.1")
+	createVLAN(t, server, vlan1)
+	createVLAN(t, server, vlan2)
+
+	vlan1.Name = "renumbered"
+	vlan3 := newVLAN(t, 3, "test3", "192.168.3.0/24", "192.168.3.1")
+	status, txResp := postTransaction(t, server, TransactionRequest{Operations: []TransactionOperation{
+		{Op: vlan.OperationUpdate, VLAN: vlan1},
+		{Op: vlan.OperationDelete, ID: vlan2.ID},
+		{Op: vlan.OperationCreate, VLAN: vlan3},
+	}})
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, txResp.Results, 3)
+	for i, result := range txResp.Results {
+		require.Equal(t, i, result.Index)
+		require.Equal(t, OperationApplied, result.Status)
+	}
+	vlan3.ID = txResp.Results[2].ID
+
+	vlansFromAPI := readVLANs(t, server)
+	require.Len(t, vlansFromAPI, 2)
+	require.Equal(t, *vlan1, vlansFromAPI[vlan1.ID])
+	require.Equal(t, *vlan3, vlansFromAPI[vlan3.ID])
+}
+
+func TestHandleTransaction_NOK(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	createVLAN(t, server, vlan1)
+	renamed := *vlan1
+	renamed.Name = "renamed"
+
+	// Invalid JSON
+	resp, err := server.Client().Post(server.URL+"/api/v1/transactions", "application/json", bytes.NewBufferString("invalid"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	requireInvalidInputResponse(t, resp)
+
+	// No operations
+	status, txResp := postTransaction(t, server, TransactionRequest{})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, ErrCodeInvalidInput, txResp.Code)
+
+	// Operations not matching the API spec are rejected before they are validated individually
+	status, txResp = postTransaction(t, server, TransactionRequest{Operations: []TransactionOperation{
+		{Op: vlan.OperationCreate, VLAN: newVLAN(t, 5000, "test2", "192.168.2.0/24", "192.168.2.1")},
+	}})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, ErrCodeInvalidInput, txResp.Code)
+
+	// Invalid VLAN fails the whole transaction
+	status, txResp = postTransaction(t, server, TransactionRequest{Operations: []TransactionOperation{
+		{Op: vlan.OperationUpdate, VLAN: &renamed},
+		{Op: vlan.OperationCreate, VLAN: newVLAN(t, 2, "test2", "192.168.2.0/24", "192.168.3.1")},
+		{Op: vlan.OperationUpdate},
+	}})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, ErrCodeInvalidInput, txResp.Code)
+	require.Equal(t, []string{OperationAborted, OperationFailed, OperationFailed}, resultStatuses(txResp))
+
+	// Unknown VLAN fails the whole transaction
+	status, txResp = postTransaction(t, server, TransactionRequest{Operations: []TransactionOperation{
+		{Op: vlan.OperationUpdate, VLAN: &renamed},
+		{Op: vlan.OperationDelete, ID: uuid.New()},
+	}})
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, ErrCodeNotFound, txResp.Code)
+	require.Equal(t, []string{OperationAborted, OperationFailed}, resultStatuses(txResp))
+
+	// Operations depending on each other are detected when applied
+	status, txResp = postTransaction(t, server, TransactionRequest{Operations: []TransactionOperation{
+		{Op: vlan.OperationDelete, ID: vlan1.ID},
+		{Op: vlan.OperationUpdate, VLAN: &renamed},
+	}})
+	require.Equal(t, http.StatusNotFound, status)
+	require.Equal(t, []string{OperationAborted, OperationFailed}, resultStatuses(txResp))
+
+	// Nothing was applied
+	require.Equal(t, *vlan1, *readVLAN(t, server, vlan1.ID))
+	require.Len(t, readVLANs(t, server), 1)
+}
+
+func postTransaction(t *testing.T, server *httptest.Server, txReq TransactionRequest) (int, TransactionResponse) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	require.NoError(t, json.NewEncoder(buf).Encode(txReq))
+
+	resp, err := server.Client().Post(server.URL+"/api/v1/transactions", "application/json", buf)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	txResp := TransactionResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&txResp))
+	return resp.StatusCode, txResp
+}
+
+func resultStatuses(txResp TransactionResponse) []string {
+	statuses := []string{}
+	for _, result := range txResp.Results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/vlan"
+)
+
+// HandleListTrash lists the deleted VLANs that have not been purged yet, the most recently deleted first.
+func (s *Server) HandleListTrash(respWriter http.ResponseWriter, req *http.Request) {
+	writeJSONResponse(respWriter, s.vlanStore.Trash())
+}
+
+// HandleRestoreVLAN moves a deleted VLAN back from the trash. VLANs that no longer fit in, e.g. because their
+// subnet was taken or their VRF was deleted in the meantime, are a conflict, and so are VLANs that another component
+// owned, which only their owner may bring back.
+func (s *Server) HandleRestoreVLAN(respWriter http.ResponseWriter, req *http.Request) {
+	vlanID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		invalidInput(respWriter, "invalid vlan id")
+		return
+	}
+
+	restored, err := s.vlanStore.Restore(vlanID, vlan.ExpectUnmanaged())
+	switch {
+	case errors.Is(err, vlan.ErrNotFound):
+		http.NotFound(respWriter, req)
+	case errors.Is(err, vlan.ErrOverlap), errors.Is(err, vlan.ErrDuplicateVID), errors.Is(err, vlan.ErrUnknownVRF),
+		errors.Is(err, vlan.ErrInvalidFields), errors.Is(err, vlan.ErrManaged):
+		conflict(respWriter, err.Error())
+	case err != nil:
+		log.Printf("failed to restore vlan: %v", err)
+		internalError(respWriter, "failed to restore vlan")
+	default:
+		writeJSONResponse(respWriter, restored)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+)
+
+func TestHandleTrash(t *testing.T) {
+	t.Parallel()
+	server := newChangeRequestServer(t)
+
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	resp := doRequest(t, server, "POST", "/api/v1/vlans", tokenAlice, vlan1)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	vlan1.ID = uuid.MustParse(path.Base(resp.Header.Get("Location")))
+	resp = doRequest(t, server, "DELETE", "/api/v1/vlans/"+vlan1.ID.String(), tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Deleted VLANs are only in the trash
+	resp = doRequest(t, server, "GET", "/api/v1/vlans/"+vlan1.ID.String(), tokenAlice, nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/trash", tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	trash := []vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&trash))
+	require.Len(t, trash, 1)
+	require.Equal(t, vlan1.ID, trash[0].ID)
+	require.Equal(t, "alice", trash[0].Deleted.By)
+
+	// The subnet of a deleted VLAN can be taken, which blocks its restore
+	vlan2 := newVLAN(t, 2, "test2", "192.168.1.0/25", "192.168.1.1")
+	resp = doRequest(t, server, "POST", "/api/v1/vlans", tokenAlice, vlan2)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	vlan2.ID = uuid.MustParse(path.Base(resp.Header.Get("Location")))
+	requireConflictResponse(t, doRequest(t, server, "POST", "/api/v1/vlans/"+vlan1.ID.String()+"/restore", tokenAlice, nil))
+
+	resp = doRequest(t, server, "DELETE", "/api/v1/vlans/"+vlan2.ID.String(), tokenBob, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "POST", "/api/v1/vlans/"+vlan1.ID.String()+"/restore", tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	restored := vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&restored))
+	require.Equal(t, *vlan1, restored)
+	require.Equal(t, vlan1, readVLANWithToken(t, server, vlan1.ID))
+
+	resp = doRequest(t, server, "GET", "/api/v1/trash", tokenAlice, nil)
+	trash = []vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&trash))
+	require.Len(t, trash, 1)
+	require.Equal(t, "bob", trash[0].Deleted.By)
+}
+
+func TestHandleRestoreVLAN_NOK(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	createVLAN(t, server, vlan1)
+
+	requireInvalidInputResponse(t, doRequest(t, server, "POST", "/api/v1/vlans/abc/restore", "", nil))
+	// Only deleted VLANs can be restored
+	resp := doRequest(t, server, "POST", "/api/v1/vlans/"+vlan1.ID.String()+"/restore", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "POST", "/api/v1/vlans/"+uuid.NewString()+"/restore", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
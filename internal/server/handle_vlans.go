@@ -6,17 +6,18 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/customfield"
 	"net-admin-api/internal/vlan"
 )
 
 func (s *Server) HandleListVLANs(respWriter http.ResponseWriter, req *http.Request) {
-	vlans, err := s.vlanStore.List()
-	if err != nil {
-		log.Printf("failed to read vlans: %v", err)
-		internalError(respWriter, "failed to read vlans")
+	vlans := s.filteredVLANs(respWriter, req)
+	if vlans == nil {
 		return
 	}
 	writeJSONResponse(respWriter, vlans)
@@ -35,9 +36,13 @@ func (s *Server) HandleCreateVLAN(respWriter http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	// Generate new ID and save
+	// Generate new ID and save, managed VLANs can only be created by their owner
 	vlan.ID = uuid.New()
+	vlan.ManagedBy = ""
 	if err := s.vlanStore.Save(*vlan); err != nil {
+		if writeVLANRuleError(respWriter, err) {
+			return
+		}
 		log.Printf("failed to save vlan: %v", err)
 		internalError(respWriter, "failed to save vlan")
 		return
@@ -83,11 +88,15 @@ func (s *Server) HandleUpdateVLAN(respWriter http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	if err := s.vlanStore.Update(*v); err != nil {
+	v.ManagedBy = ""
+	if err := s.vlanStore.Update(*v, vlan.ExpectUnmanaged()); err != nil {
 		if errors.Is(err, vlan.ErrNotFound) {
 			http.NotFound(respWriter, req)
 			return
 		}
+		if writeVLANRuleError(respWriter, err) {
+			return
+		}
 
 		log.Printf("failed to update vlan: %v", err)
 		internalError(respWriter, "failed to update vlan")
@@ -101,14 +110,61 @@ func (s *Server) HandleDeleteVLAN(respWriter http.ResponseWriter, req *http.Requ
 		invalidInput(respWriter, "invalid vlan id")
 		return
 	}
-
-	if err := s.vlanStore.Delete(vlanID); err != nil {
+	if err := s.vlanStore.Delete(vlanID, auth.ActorFrom(req.Context()).Name, vlan.ExpectUnmanaged()); err != nil {
 		if err == vlan.ErrNotFound {
 			http.NotFound(respWriter, req)
 			return
 		}
+		if errors.Is(err, vlan.ErrManaged) {
+			conflict(respWriter, err.Error())
+			return
+		}
 		log.Printf("failed to delete vlan: %v", err)
 		internalError(respWriter, "failed to delete vlan")
 		return
 	}
 }
+
+// writeVLANRuleError writes the response of a VLAN that was rejected by the store because it would break the
+// rules of its VRF or the custom field schema, or because another component owns it, and reports whether the error
+// was one of them.
+func writeVLANRuleError(respWriter http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, vlan.ErrOverlap), errors.Is(err, vlan.ErrDuplicateVID), errors.Is(err, vlan.ErrManaged):
+		conflict(respWriter, err.Error())
+	case errors.Is(err, vlan.ErrUnknownVRF), errors.Is(err, vlan.ErrInvalidFields):
+		invalidInput(respWriter, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// filteredVLANs returns the VLANs that match the vrf, tag and field query parameters, or writes an error response.
+func (s *Server) filteredVLANs(respWriter http.ResponseWriter, req *http.Request) []vlan.VLAN {
+	vrfName, filtered, err := s.vrfFromQuery(req)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return nil
+	}
+	tags := req.URL.Query()["tag"]
+	fields := map[string]string{}
+	for _, filter := range req.URL.Query()["field"] {
+		name, value, ok := strings.Cut(filter, ":")
+		if !ok || !customfield.ValidName(name) {
+			invalidInput(respWriter, fmt.Sprintf("invalid field filter %q (expected name:value)", filter))
+			return nil
+		}
+		fields[name] = value
+	}
+
+	vlans, err := s.vlanStore.List()
+	if err != nil {
+		log.Printf("failed to read vlans: %v", err)
+		internalError(respWriter, "failed to read vlans")
+		return nil
+	}
+	return slices.DeleteFunc(vlans, func(v vlan.VLAN) bool {
+		return (filtered && v.VRF != vrfName) || !v.HasTags(tags...) || !v.HasFields(fields)
+	})
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/vlan"
+)
+
+// HandleReadVLANByVID returns the VLAN of a VID. The VLANs by VID routes address a VLAN by its VID within the VRF
+// of the vrf parameter, the global routing table by default, so that tools that know VIDs need not look up IDs.
+func (s *Server) HandleReadVLANByVID(respWriter http.ResponseWriter, req *http.Request) {
+	existing, ok := s.vlanFromVID(respWriter, req)
+	if !ok {
+		return
+	}
+	if existing == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, existing)
+}
+
+// HandlePutVLANByVID creates the VLAN of a VID, or replaces it if it exists, so that repeating a request has
+// no further effect.
+func (s *Server) HandlePutVLANByVID(respWriter http.ResponseWriter, req *http.Request) {
+	vid, vrfName, ok := s.vidFromPath(respWriter, req)
+	if !ok {
+		return
+	}
+
+	defer req.Body.Close()
+	v := &vlan.VLAN{}
+	if err := json.NewDecoder(req.Body).Decode(&v); err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse vlan: %v", err))
+		return
+	}
+	if v.VID != vid {
+		invalidInput(respWriter, "mismatching vid in request body")
+		return
+	}
+	if v.VRF == "" {
+		v.VRF = vrfName
+	} else if v.VRF != vrfName {
+		invalidInput(respWriter, "mismatching vrf in request body")
+		return
+	}
+	if errors := v.Validate(); len(errors) > 0 {
+		invalidInput(respWriter, strings.Join(errors, ", "))
+		return
+	}
+
+	// The VLAN of the VID is looked up and replaced atomically, so concurrent requests for a new VID cannot both
+	// create it
+	v.ManagedBy = ""
+	saved, created, err := s.vlanStore.SaveByVID(*v, func(existing *vlan.VLAN) error {
+		if existing != nil && existing.ManagedBy != "" {
+			return fmt.Errorf("%w, managed by %s", vlan.ErrManaged, existing.ManagedBy)
+		}
+		return nil
+	})
+	if err != nil {
+		if writeVLANRuleError(respWriter, err) {
+			return
+		}
+		log.Printf("failed to save vlan: %v", err)
+		internalError(respWriter, "failed to save vlan")
+		return
+	}
+	if created {
+		respWriter.Header().Set("Location", "/api/v1/vlans/"+saved.ID.String())
+		respWriter.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (s *Server) HandleDeleteVLANByVID(respWriter http.ResponseWriter, req *http.Request) {
+	existing, ok := s.vlanFromVID(respWriter, req)
+	if !ok {
+		return
+	}
+	if existing == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	if err := s.vlanStore.Delete(existing.ID, auth.ActorFrom(req.Context()).Name, vlan.ExpectUnmanaged()); err != nil {
+		if errors.Is(err, vlan.ErrNotFound) {
+			http.NotFound(respWriter, req)
+			return
+		}
+		if errors.Is(err, vlan.ErrManaged) {
+			conflict(respWriter, err.Error())
+			return
+		}
+		log.Printf("failed to delete vlan: %v", err)
+		internalError(respWriter, "failed to delete vlan")
+	}
+}
+
+// vlanFromVID returns the VLAN of the VID in the path in the VRF of the vrf parameter, or nil if there is none. ok
+// is false after an error response was written, including for VIDs that more than one VLAN of the VRF uses, which
+// older store files may contain.
+func (s *Server) vlanFromVID(respWriter http.ResponseWriter, req *http.Request) (*vlan.VLAN, bool) {
+	vid, vrfName, ok := s.vidFromPath(respWriter, req)
+	if !ok {
+		return nil, false
+	}
+	switch vlans := s.vlanStore.GetByVID(vrfName, vid); len(vlans) {
+	case 0:
+		return nil, true
+	case 1:
+		return &vlans[0], true
+	default:
+		conflict(respWriter, fmt.Sprintf("more than one vlan has vid %d, use their ids", vid))
+		return nil, false
+	}
+}
+
+// vidFromPath returns the VID in the path and the VRF of the vrf parameter, or writes an error response.
+func (s *Server) vidFromPath(respWriter http.ResponseWriter, req *http.Request) (uint16, string, bool) {
+	vid, err := strconv.ParseUint(req.PathValue("vid"), 10, 16)
+	if err != nil || vid < vlan.MinVID || vid > vlan.MaxVID {
+		invalidInput(respWriter, fmt.Sprintf("invalid vid (expected range %d..%d)", vlan.MinVID, vlan.MaxVID))
+		return 0, "", false
+	}
+	vrfName, _, err := s.vrfFromQuery(req)
+	if err != nil {
+		invalidInput(respWriter, err.Error())
+		return 0, "", false
+	}
+	return uint16(vid), vrfName, true
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+	"net-admin-api/internal/vrf"
+)
+
+func TestHandleVLANsByVID_OK(t *testing.T) {
+	t.Parallel()
+	server, _ := newVRFServer(t)
+	createVRF(t, server, vrf.VRF{Name: "red", RD: "65000:1"})
+
+	// The first PUT creates the VLAN, repeating it changes nothing
+	users := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+	resp := doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/10", "", users)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	users.ID = uuid.MustParse(path.Base(resp.Header.Get("Location")))
+	require.Equal(t, users, readVLAN(t, server, users.ID))
+	resp = doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/10", "", users)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	requireVLANList(t, server, "", users)
+
+	// Later PUTs replace the VLAN, keeping its ID
+	users.Name = "staff"
+	resp = doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/10", "", users)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	requireVLANByVID(t, server, "/api/v1/vlans-by-vid/10", users)
+
+	// VIDs are looked up in the global routing table, or in the VRF of the vrf parameter
+	red := newVLAN(t, 10, "red-users", "10.0.10.0/24", "10.0.10.1")
+	resp = doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/10?vrf=red", "", red)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	red.ID = uuid.MustParse(path.Base(resp.Header.Get("Location")))
+	red.VRF = "red"
+	requireVLANByVID(t, server, "/api/v1/vlans-by-vid/10?vrf=red", red)
+	requireVLANByVID(t, server, "/api/v1/vlans-by-vid/10?vrf=global", users)
+
+	resp = doRequest(t, server, "DELETE", "/api/v1/vlans-by-vid/10?vrf=red", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/vlans-by-vid/10?vrf=red", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "DELETE", "/api/v1/vlans-by-vid/10?vrf=red", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	requireVLANList(t, server, "", users)
+}
+
+func TestHandleVLANsByVID_NOK(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+	users := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/vlans-by-vid/4095", "", nil))
+	requireInvalidInputResponse(t, doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/11", "", users))
+	red := *users
+	red.VRF = "red"
+	requireInvalidInputResponse(t, doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/10?vrf=blue", "", red))
+	invalid := *users
+	invalid.Gateway = invalid.Gateway.Prev().Prev()
+	requireInvalidInputResponse(t, doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/10", "", invalid))
+
+	// Subnets are checked like for other writes
+	createVLAN(t, server, users)
+	overlap := newVLAN(t, 20, "overlap", "10.0.10.0/25", "10.0.10.1")
+	requireConflictResponse(t, doRequest(t, server, "PUT", "/api/v1/vlans-by-vid/20", "", overlap))
+
+	// VIDs are unique, so a VID is never ambiguous
+	duplicate := newVLAN(t, 10, "users-2", "10.0.11.0/24", "10.0.11.1")
+	requireConflictResponse(t, doRequest(t, server, "POST", "/api/v1/vlans", "", duplicate))
+	moved := newVLAN(t, 11, "users-2", "10.0.11.0/24", "10.0.11.1")
+	createVLAN(t, server, moved)
+	moved.VID = 10
+	requireConflictResponse(t, doRequest(t, server, "PUT", "/api/v1/vlans/"+moved.ID.String(), "", moved))
+	requireVLANByVID(t, server, "/api/v1/vlans-by-vid/10", users)
+}
+
+func requireVLANByVID(t *testing.T, server *httptest.Server, vidPath string, expected *vlan.VLAN) {
+	t.Helper()
+	resp := doRequest(t, server, "GET", vidPath, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	actual := vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+	require.Equal(t, *expected, actual)
+}
@@ -222,9 +222,18 @@ func TestHandleDeleteVLAN_NOK(t *testing.T) {
 	require.Equal(t, resp.StatusCode, http.StatusNotFound)
 }
 
-func newHTTPServer(t *testing.T, vlanStorePath string) *httptest.Server {
+func newHTTPServer(t *testing.T, vlanStorePath string, opts ...Option) *httptest.Server {
 	t.Helper()
-	apiServer, err := NewServer(0, vlanStorePath)
+	vlanStore, err := vlan.NewStore(vlanStorePath)
+	if err != nil {
+		t.Fatalf("error creating VLAN store: %v", err)
+	}
+	return newHTTPServerWithStore(t, vlanStore, opts...)
+}
+
+func newHTTPServerWithStore(t *testing.T, vlanStore *vlan.Store, opts ...Option) *httptest.Server {
+	t.Helper()
+	apiServer, err := NewServer(0, vlanStore, opts...)
 	if err != nil {
 		t.Fatalf("error creating API server: %v", err)
 	}
@@ -340,6 +349,11 @@ func newVLAN(t *testing.T, vid uint16, name, subnetStr, gatewayStr string) *vlan
 }
 
 func requireInvalidInputResponse(t *testing.T, resp *http.Response) {
+	t.Helper()
+	requireInvalidInputResponseBody(t, resp)
+}
+
+func requireInvalidInputResponseBody(t *testing.T, resp *http.Response) ErrorResponse {
 	t.Helper()
 	require.Equal(t, resp.StatusCode, http.StatusBadRequest)
 	require.Equal(t, resp.Header.Get("Content-Type"), "application/json")
@@ -347,4 +361,5 @@ func requireInvalidInputResponse(t *testing.T, resp *http.Response) {
 	errResp := ErrorResponse{}
 	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
 	require.Equal(t, errResp.Code, ErrCodeInvalidInput)
+	return errResp
 }
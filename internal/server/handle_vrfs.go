@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/vlan"
+	"net-admin-api/internal/vrf"
+)
+
+func (s *Server) HandleListVRFs(respWriter http.ResponseWriter, req *http.Request) {
+	if s.vrfs == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, s.vrfs.List())
+}
+
+func (s *Server) HandleCreateVRF(respWriter http.ResponseWriter, req *http.Request) {
+	if s.vrfs == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+
+	defer req.Body.Close()
+	v := &vrf.VRF{}
+	if err := json.NewDecoder(req.Body).Decode(&v); err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse vrf: %v", err))
+		return
+	}
+	if errors := v.Validate(); len(errors) > 0 {
+		invalidInput(respWriter, strings.Join(errors, ", "))
+		return
+	}
+
+	v.ID = uuid.New()
+	v.CreatedAt = time.Now().UTC()
+	if err := s.vrfs.Save(*v); err != nil {
+		if errors.Is(err, vrf.ErrAlreadyExists) {
+			conflict(respWriter, err.Error())
+			return
+		}
+		log.Printf("failed to save vrf: %v", err)
+		internalError(respWriter, "failed to save vrf")
+		return
+	}
+
+	respWriter.Header().Set("Location", fmt.Sprintf("%s/%s", req.URL.RequestURI(), v.ID.String()))
+	respWriter.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) HandleReadVRF(respWriter http.ResponseWriter, req *http.Request) {
+	v := s.vrfFromPath(respWriter, req)
+	if v == nil {
+		return
+	}
+	writeJSONResponse(respWriter, v)
+}
+
+func (s *Server) HandleDeleteVRF(respWriter http.ResponseWriter, req *http.Request) {
+	v := s.vrfFromPath(respWriter, req)
+	if v == nil {
+		return
+	}
+
+	err := s.vlanStore.DeleteVRF(v.Name, func() error {
+		return s.vrfs.Delete(v.ID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, vlan.ErrVRFInUse):
+			conflict(respWriter, err.Error())
+			return
+		case errors.Is(err, vrf.ErrNotFound):
+			http.NotFound(respWriter, req)
+			return
+		}
+		log.Printf("failed to delete vrf: %v", err)
+		internalError(respWriter, "failed to delete vrf")
+		return
+	}
+}
+
+// vrfFromPath returns the VRF identified by the request path, or writes an error response.
+func (s *Server) vrfFromPath(respWriter http.ResponseWriter, req *http.Request) *vrf.VRF {
+	if s.vrfs == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	vrfID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		invalidInput(respWriter, "invalid vrf id")
+		return nil
+	}
+	v := s.vrfs.Get(vrfID)
+	if v == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	return v
+}
+
+// vrfFromQuery returns the VRF named by the vrf query parameter, which is empty for the global routing table.
+// The second result is false if the parameter is missing, and an error is returned for unknown VRFs.
+func (s *Server) vrfFromQuery(req *http.Request) (string, bool, error) {
+	if !req.URL.Query().Has("vrf") {
+		return "", false, nil
+	}
+	name := req.URL.Query().Get("vrf")
+	if name == "" || name == vrf.Global {
+		return "", true, nil
+	}
+	if s.vrfs != nil && !s.vrfs.Exists(name) {
+		return "", true, fmt.Errorf("unknown vrf %q", name)
+	}
+	return name, true, nil
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/dhcp"
+	"net-admin-api/internal/dns"
+	"net-admin-api/internal/vlan"
+	"net-admin-api/internal/vrf"
+)
+
+func TestHandleVRFs_OK(t *testing.T) {
+	t.Parallel()
+	server, vlanStore := newVRFServer(t)
+	redID := createVRF(t, server, vrf.VRF{Name: "red", RD: "65000:1"})
+	createVRF(t, server, vrf.VRF{Name: "blue", RD: "192.0.2.1:1", Description: "guests"})
+
+	resp := doRequest(t, server, "GET", "/api/v1/vrfs", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	vrfs := []vrf.VRF{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&vrfs))
+	require.Len(t, vrfs, 2)
+	require.Equal(t, "blue", vrfs[0].Name)
+	require.Equal(t, "guests", vrfs[0].Description)
+	require.Equal(t, redID, vrfs[1].ID)
+
+	// The same subnet may be used once per VRF
+	global := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+	createVLAN(t, server, global)
+	red := newVLAN(t, 10, "red-users", "10.0.10.0/24", "10.0.10.1")
+	red.VRF = "red"
+	createVLAN(t, server, red)
+	blue := newVLAN(t, 20, "blue-users", "10.0.0.0/16", "10.0.0.1")
+	blue.VRF = "blue"
+	createVLAN(t, server, blue)
+	require.Equal(t, *red, *readVLAN(t, server, red.ID))
+
+	requireVLANList(t, server, "?vrf=red", red)
+	requireVLANList(t, server, "?vrf=global", global)
+	requireVLANList(t, server, "", global, red, blue)
+
+	// Without a VRF, the address is looked up in every VRF
+	requireLookup(t, server, "?ip=10.0.10.20&vrf=global", global)
+	requireLookup(t, server, "?ip=10.0.10.20&vrf=red", red)
+	requireLookup(t, server, "?ip=::ffff:10.0.10.20&vrf=red", red)
+	requireLookup(t, server, "?ip=10.0.10.20", global, blue, red)
+	requireLookup(t, server, "?ip=10.0.11.1", blue)
+	resp = doRequest(t, server, "GET", "/api/v1/lookup?ip=10.0.11.1&vrf=global", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// VRFs in use cannot be deleted, also by VLANs in the trash
+	resp = doRequest(t, server, "DELETE", "/api/v1/vrfs/"+redID.String(), "", nil)
+	requireConflictResponse(t, resp)
+	deleteVLAN(t, server, red.ID)
+	resp = doRequest(t, server, "DELETE", "/api/v1/vrfs/"+redID.String(), "", nil)
+	requireConflictResponse(t, resp)
+	_, err := vlanStore.Purge(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	resp = doRequest(t, server, "DELETE", "/api/v1/vrfs/"+redID.String(), "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/vrfs/"+redID.String(), "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandleVRFs_Overlap(t *testing.T) {
+	t.Parallel()
+	server, _ := newVRFServer(t)
+	createVRF(t, server, vrf.VRF{Name: "red"})
+	global := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+	createVLAN(t, server, global)
+
+	resp := doRequest(t, server, "POST", "/api/v1/vlans", "", newVLAN(t, 11, "overlapping", "10.0.0.0/16", "10.0.0.1"))
+	requireConflictResponse(t, resp)
+
+	// Moving a VLAN into a VRF frees its subnet in the global routing table
+	global.VRF = "red"
+	updateVLAN(t, server, global)
+	createVLAN(t, server, newVLAN(t, 11, "overlapping", "10.0.0.0/16", "10.0.0.1"))
+	global.VRF = ""
+	resp = doRequest(t, server, "PUT", "/api/v1/vlans/"+global.ID.String(), "", global)
+	requireConflictResponse(t, resp)
+
+	// Transactions report the operation that overlaps
+	resp = doRequest(t, server, "POST", "/api/v1/transactions", "", TransactionRequest{Operations: []TransactionOperation{
+		{Op: vlan.OperationCreate, VLAN: newVLAN(t, 12, "a", "192.168.0.0/24", "192.168.0.1")},
+		{Op: vlan.OperationCreate, VLAN: newVLAN(t, 13, "b", "192.168.0.128/25", "192.168.0.129")},
+	}})
+	require.Equal(t, http.StatusConflict, resp.StatusCode)
+	txResp := TransactionResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&txResp))
+	require.Equal(t, OperationAborted, txResp.Results[0].Status)
+	require.Equal(t, OperationFailed, txResp.Results[1].Status)
+	require.Equal(t, "overlapping subnet: 192.168.0.128/25 overlaps 192.168.0.0/24 of vlan a", txResp.Results[1].Error)
+}
+
+func TestHandleVRFs_Exports(t *testing.T) {
+	t.Parallel()
+	scopes, err := dhcp.NewStore(filepath.Join(t.TempDir(), "dhcp-scopes.json"))
+	require.NoError(t, err)
+	server, _ := newVRFServer(t, WithDHCP(scopes), WithDNS(dns.Options{Domain: "site.example", TTL: 300}))
+	createVRF(t, server, vrf.VRF{Name: "red"})
+
+	// The same VID and subnet in two VRFs end up in separate configurations
+	global := newVLAN(t, 10, "users", "192.168.10.0/24", "192.168.10.1")
+	red := newVLAN(t, 10, "red-users", "192.168.10.0/24", "192.168.10.254")
+	red.VRF = "red"
+	for _, v := range []*vlan.VLAN{global, red} {
+		createVLAN(t, server, v)
+		updateDHCPScope(t, server, v.ID, dhcp.Scope{
+			Pools: []dhcp.Pool{{Start: netip.MustParseAddr("192.168.10.100"), End: netip.MustParseAddr("192.168.10.199")}},
+		})
+	}
+	for query, expected := range map[string]*vlan.VLAN{"": global, "?vrf=global": global, "?vrf=red": red} {
+		resp := doRequest(t, server, "GET", "/api/v1/dhcp/kea/dhcp4"+query, "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		config := struct {
+			Dhcp4 struct {
+				Subnet4 []struct {
+					UserContext struct {
+						VLAN string `json:"vlan"`
+					} `json:"user-context"`
+				} `json:"subnet4"`
+			}
+		}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&config))
+		require.Len(t, config.Dhcp4.Subnet4, 1)
+		require.Equal(t, expected.Name, config.Dhcp4.Subnet4[0].UserContext.VLAN)
+
+		zoneFile := readDNSZone(t, server, "site.example"+query)
+		require.Contains(t, zoneFile, "gw.vlan10.site.example.\t300\tIN\tA\t"+expected.Gateway.String()+"\n")
+		require.Equal(t, 1, strings.Count(zoneFile, "gw.vlan10"))
+	}
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/dhcp/kea/dhcp4?vrf=green", "", nil))
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/dns/zones?vrf=green", "", nil))
+}
+
+func TestHandleVRFs_NOK(t *testing.T) {
+	t.Parallel()
+	server, _ := newVRFServer(t)
+	createVRF(t, server, vrf.VRF{Name: "red", RD: "65000:1"})
+
+	for name, v := range map[string]vrf.VRF{
+		"reserved name": {Name: "global"},
+		"invalid name":  {Name: "-red"},
+		"invalid rd":    {Name: "blue", RD: "65000:blue"},
+		"rd too long":   {Name: "blue", RD: "4200000000:70000"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := doRequest(t, server, "POST", "/api/v1/vrfs", "", v)
+			requireInvalidInputResponse(t, resp)
+		})
+	}
+	requireConflictResponse(t, doRequest(t, server, "POST", "/api/v1/vrfs", "", vrf.VRF{Name: "red"}))
+	requireConflictResponse(t, doRequest(t, server, "POST", "/api/v1/vrfs", "", vrf.VRF{Name: "blue", RD: "65000:1"}))
+
+	unknown := newVLAN(t, 10, "users", "10.0.10.0/24", "10.0.10.1")
+	unknown.VRF = "green"
+	requireInvalidInputResponse(t, doRequest(t, server, "POST", "/api/v1/vlans", "", unknown))
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/vlans?vrf=green", "", nil))
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/lookup?ip=10.0.10.1&vrf=green", "", nil))
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/lookup?ip=10.0.10", "", nil))
+	requireInvalidInputResponse(t, doRequest(t, server, "GET", "/api/v1/vrfs/red", "", nil))
+	resp := doRequest(t, server, "DELETE", "/api/v1/vrfs/"+uuid.NewString(), "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandleVRFs_NotConfigured(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+	resp := doRequest(t, server, "GET", "/api/v1/vrfs", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "POST", "/api/v1/vrfs", "", vrf.VRF{Name: "red"})
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func newVRFServer(t *testing.T, opts ...Option) (*httptest.Server, *vlan.Store) {
+	t.Helper()
+	vrfs, err := vrf.NewStore(filepath.Join(t.TempDir(), "vrfs.json"))
+	require.NoError(t, err)
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"), vlan.WithVRFs(vrfs.Exists))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = vlanStore.Close() })
+	return newHTTPServerWithStore(t, vlanStore, append(opts, WithVRFs(vrfs))...), vlanStore
+}
+
+func createVRF(t *testing.T, server *httptest.Server, v vrf.VRF) uuid.UUID {
+	t.Helper()
+	resp := doRequest(t, server, "POST", "/api/v1/vrfs", "", v)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	return uuid.MustParse(path.Base(resp.Header.Get("Location")))
+}
+
+func requireVLANList(t *testing.T, server *httptest.Server, query string, expected ...*vlan.VLAN) {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/vlans"+query, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	vlans := []*vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&vlans))
+	require.ElementsMatch(t, expected, vlans)
+}
+
+func requireLookup(t *testing.T, server *httptest.Server, query string, expected ...*vlan.VLAN) {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/lookup"+query, "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	found := []*vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&found))
+	require.Equal(t, expected, found)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/webhook"
+)
+
+func (s *Server) HandleListWebhooks(respWriter http.ResponseWriter, req *http.Request) {
+	if s.webhooks == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	subscriptions := s.webhooks.List()
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	writeJSONResponse(respWriter, subscriptions)
+}
+
+func (s *Server) HandleCreateWebhook(respWriter http.ResponseWriter, req *http.Request) {
+	if s.webhooks == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	// The server sends requests to the URL of a webhook, which must not reach internal services for any user
+	if s.authEnabled() && !auth.ActorFrom(req.Context()).HasRole(auth.RoleAdmin) {
+		forbidden(respWriter, "creating webhooks requires the admin role")
+		return
+	}
+
+	defer req.Body.Close()
+	subscription := &webhook.Subscription{}
+	if err := json.NewDecoder(req.Body).Decode(&subscription); err != nil {
+		invalidInput(respWriter, fmt.Sprintf("failed to parse webhook: %v", err))
+		return
+	}
+	if errors := subscription.Validate(); len(errors) > 0 {
+		invalidInput(respWriter, strings.Join(errors, ", "))
+		return
+	}
+
+	subscription.ID = uuid.New()
+	subscription.CreatedAt = time.Now().UTC()
+	if err := s.webhooks.Save(*subscription); err != nil {
+		log.Printf("failed to save webhook: %v", err)
+		internalError(respWriter, "failed to save webhook")
+		return
+	}
+
+	respWriter.Header().Set("Location", fmt.Sprintf("%s/%s", req.URL.RequestURI(), subscription.ID.String()))
+	respWriter.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) HandleReadWebhook(respWriter http.ResponseWriter, req *http.Request) {
+	subscription := s.webhookFromPath(respWriter, req)
+	if subscription == nil {
+		return
+	}
+	subscription.Secret = ""
+	writeJSONResponse(respWriter, subscription)
+}
+
+func (s *Server) HandleDeleteWebhook(respWriter http.ResponseWriter, req *http.Request) {
+	subscription := s.webhookFromPath(respWriter, req)
+	if subscription == nil {
+		return
+	}
+	if s.authEnabled() && !auth.ActorFrom(req.Context()).HasRole(auth.RoleAdmin) {
+		forbidden(respWriter, "deleting webhooks requires the admin role")
+		return
+	}
+
+	if err := s.webhooks.Delete(subscription.ID); err != nil {
+		if errors.Is(err, webhook.ErrNotFound) {
+			http.NotFound(respWriter, req)
+			return
+		}
+		log.Printf("failed to delete webhook: %v", err)
+		internalError(respWriter, "failed to delete webhook")
+		return
+	}
+	s.webhookDispatcher.Forget(subscription.ID)
+}
+
+func (s *Server) HandleListWebhookDeliveries(respWriter http.ResponseWriter, req *http.Request) {
+	subscription := s.webhookFromPath(respWriter, req)
+	if subscription == nil {
+		return
+	}
+	writeJSONResponse(respWriter, s.webhookDispatcher.Deliveries(subscription.ID))
+}
+
+func (s *Server) HandleListWebhookDeadLetters(respWriter http.ResponseWriter, req *http.Request) {
+	if s.webhooks == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, s.webhookDispatcher.DeadLetters())
+}
+
+func (s *Server) HandleRetryWebhookDeadLetter(respWriter http.ResponseWriter, req *http.Request) {
+	if s.webhooks == nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	deliveryID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		invalidInput(respWriter, "invalid delivery id")
+		return
+	}
+
+	delivery, err := s.webhookDispatcher.Retry(deliveryID)
+	if err != nil {
+		http.NotFound(respWriter, req)
+		return
+	}
+	writeJSONResponse(respWriter, delivery)
+}
+
+// webhookFromPath returns the subscription identified by the request path, or writes an error response.
+func (s *Server) webhookFromPath(respWriter http.ResponseWriter, req *http.Request) *webhook.Subscription {
+	if s.webhooks == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	subscriptionID, err := uuid.Parse(req.PathValue("id"))
+	if err != nil {
+		invalidInput(respWriter, "invalid webhook id")
+		return nil
+	}
+	subscription := s.webhooks.Get(subscriptionID)
+	if subscription == nil {
+		http.NotFound(respWriter, req)
+		return nil
+	}
+	return subscription
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/vlan"
+	"net-admin-api/internal/webhook"
+)
+
+func TestHandleWebhooks_OK(t *testing.T) {
+	t.Parallel()
+	server := newWebhookServer(t)
+	receiver := newWebhookReceiver(t, 0)
+
+	// Register a webhook for updates and deletes only
+	webhookID := createWebhook(t, server, webhook.Subscription{
+		URL:    receiver.URL,
+		Events: []vlan.EventType{vlan.EventUpdated, vlan.EventDeleted},
+		Secret: "s3cret",
+	})
+	resp := doRequest(t, server, "GET", "/api/v1/webhooks/"+webhookID.String(), "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	subscription := webhook.Subscription{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&subscription))
+	require.Equal(t, receiver.URL, subscription.URL)
+	require.Empty(t, subscription.Secret, "secret must not be returned")
+
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	createVLAN(t, server, vlan1)
+	vlan1.Name = "renamed"
+	updateVLAN(t, server, vlan1)
+	deleteVLAN(t, server, vlan1.ID)
+
+	// Signed payloads are received in order
+	for _, eventType := range []vlan.EventType{vlan.EventUpdated, vlan.EventDeleted} {
+		received := receiver.next(t)
+		require.Equal(t, string(eventType), received.header.Get(webhook.HeaderEvent))
+		timestamp := received.header.Get(webhook.HeaderTimestamp)
+		require.Equal(t, webhook.Sign("s3cret", timestamp, received.body), received.header.Get(webhook.HeaderSignature))
+
+		event := vlan.Event{}
+		require.NoError(t, json.Unmarshal(received.body, &event))
+		require.Equal(t, eventType, event.Type)
+		require.Equal(t, *vlan1, event.VLAN)
+	}
+
+	// Delivery history is available, newest first
+	require.Eventually(t, func() bool {
+		deliveries := listWebhookDeliveries(t, server, webhookID)
+		return len(deliveries) == 2 && deliveries[0].Status == webhook.DeliveryDelivered && deliveries[1].Status == webhook.DeliveryDelivered
+	}, 5*time.Second, 10*time.Millisecond)
+	deliveries := listWebhookDeliveries(t, server, webhookID)
+	require.Equal(t, vlan.EventDeleted, deliveries[0].Event.Type)
+	require.Equal(t, 1, deliveries[0].Attempts)
+
+	// Deleted webhook receives nothing
+	resp = doRequest(t, server, "DELETE", "/api/v1/webhooks/"+webhookID.String(), "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "GET", "/api/v1/webhooks", "", nil)
+	subscriptions := []webhook.Subscription{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&subscriptions))
+	require.Empty(t, subscriptions)
+}
+
+func TestHandleWebhooks_Retry(t *testing.T) {
+	t.Parallel()
+	server := newWebhookServer(t)
+
+	// First two attempts fail, third one succeeds
+	receiver := newWebhookReceiver(t, 2)
+	webhookID := createWebhook(t, server, webhook.Subscription{URL: receiver.URL, Secret: "s3cret"})
+	createVLAN(t, server, newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1"))
+
+	received := receiver.next(t)
+	require.Equal(t, string(vlan.EventCreated), received.header.Get(webhook.HeaderEvent))
+	require.Eventually(t, func() bool {
+		deliveries := listWebhookDeliveries(t, server, webhookID)
+		return len(deliveries) == 1 && deliveries[0].Status == webhook.DeliveryDelivered && deliveries[0].Attempts == 3
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestHandleWebhooks_DeadLetters(t *testing.T) {
+	t.Parallel()
+	server := newWebhookServer(t)
+
+	// Receiver fails for all attempts
+	receiver := newWebhookReceiver(t, webhookTestOptions().MaxAttempts)
+	createWebhook(t, server, webhook.Subscription{URL: receiver.URL, Secret: "s3cret"})
+	createVLAN(t, server, newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1"))
+
+	deadLetters := []webhook.Delivery{}
+	require.Eventually(t, func() bool {
+		resp := doRequest(t, server, "GET", "/api/v1/webhooks/dead-letters", "", nil)
+		deadLetters = []webhook.Delivery{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&deadLetters))
+		return len(deadLetters) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, webhook.DeliveryDead, deadLetters[0].Status)
+	require.Equal(t, http.StatusInternalServerError, deadLetters[0].ResponseStatus)
+
+	// Retried dead letter is delivered
+	resp := doRequest(t, server, "POST", "/api/v1/webhooks/dead-letters/"+deadLetters[0].ID.String()+"/retry", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	received := receiver.next(t)
+	require.Equal(t, deadLetters[0].ID.String(), received.header.Get(webhook.HeaderDelivery))
+}
+
+func TestHandleWebhooks_NOK(t *testing.T) {
+	t.Parallel()
+	server := newWebhookServer(t)
+
+	for _, subscription := range []webhook.Subscription{
+		{URL: "ftp://example.com", Secret: "s3cret"},
+		{URL: "/relative", Secret: "s3cret"},
+		{URL: "http://example.com", Secret: "s3cret", Events: []vlan.EventType{"vlan.renamed"}},
+		{URL: "http://example.com"},
+	} {
+		resp := doRequest(t, server, "POST", "/api/v1/webhooks", "", subscription)
+		requireInvalidInputResponse(t, resp)
+	}
+
+	resp := doRequest(t, server, "GET", "/api/v1/webhooks/invalid", "", nil)
+	requireInvalidInputResponse(t, resp)
+	resp = doRequest(t, server, "GET", "/api/v1/webhooks/"+uuid.NewString()+"/deliveries", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "DELETE", "/api/v1/webhooks/"+uuid.NewString(), "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doRequest(t, server, "POST", "/api/v1/webhooks/dead-letters/"+uuid.NewString()+"/retry", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHandleWebhooks_Admin(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{
+		{Name: "alice", Token: tokenAlice},
+		{Name: "bob", Token: tokenBob, Roles: []string{auth.RoleAdmin}},
+	})
+	require.NoError(t, err)
+	server := newWebhookServer(t, WithUsers(users))
+	subscription := webhook.Subscription{URL: "http://127.0.0.1:8500/v1/kv", Secret: "s3cret"}
+
+	// Only admins choose the URLs the server sends requests to
+	resp := doRequest(t, server, "POST", "/api/v1/webhooks", tokenAlice, subscription)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doRequest(t, server, "POST", "/api/v1/webhooks", tokenBob, subscription)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	webhookPath := resp.Header.Get("Location")
+
+	resp = doRequest(t, server, "GET", webhookPath, tokenAlice, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doRequest(t, server, "DELETE", webhookPath, tokenAlice, nil)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doRequest(t, server, "DELETE", webhookPath, tokenBob, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func webhookTestOptions() webhook.Options {
+	opts := webhook.DefaultOptions()
+	opts.MaxAttempts = 3
+	opts.InitialBackoff = 10 * time.Millisecond
+	opts.MaxBackoff = 50 * time.Millisecond
+	return opts
+}
+
+func newWebhookServer(t *testing.T, opts ...Option) *httptest.Server {
+	t.Helper()
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	webhooks, err := webhook.NewStore(filepath.Join(t.TempDir(), "webhooks.json"))
+	require.NoError(t, err)
+
+	dispatcher := webhook.NewDispatcher(webhooks, webhookTestOptions())
+	vlanStore.Subscribe(dispatcher.Notify)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go dispatcher.Run(ctx)
+
+	return newHTTPServerWithStore(t, vlanStore, append(opts, WithWebhooks(webhooks, dispatcher))...)
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+type webhookReceiver struct {
+	*httptest.Server
+	received chan receivedWebhook
+}
+
+// Starts a webhook receiver that responds with an error to the first failures requests.
+func newWebhookReceiver(t *testing.T, failures int) *webhookReceiver {
+	t.Helper()
+	receiver := &webhookReceiver{received: make(chan receivedWebhook, 100)}
+	mu := sync.Mutex{}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		receiver.received <- receivedWebhook{header: r.Header, body: body}
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) next(t *testing.T) receivedWebhook {
+	t.Helper()
+	select {
+	case received := <-r.received:
+		return received
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for webhook")
+		return receivedWebhook{}
+	}
+}
+
+func createWebhook(t *testing.T, server *httptest.Server, subscription webhook.Subscription) uuid.UUID {
+	t.Helper()
+	resp := doRequest(t, server, "POST", "/api/v1/webhooks", "", subscription)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	return uuid.MustParse(path.Base(resp.Header.Get("Location")))
+}
+
+func listWebhookDeliveries(t *testing.T, server *httptest.Server, webhookID uuid.UUID) []webhook.Delivery {
+	t.Helper()
+	resp := doRequest(t, server, "GET", "/api/v1/webhooks/"+webhookID.String()+"/deliveries", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deliveries := []webhook.Delivery{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deliveries))
+	return deliveries
+}
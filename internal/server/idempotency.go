@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"strings"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/idempotency"
+)
+
+// idempotencyMiddleware replays the response of a POST request that is retried with the same Idempotency-Key
+// header. Only successful responses are recorded, a request that failed can be retried with the same key.
+func (s *Server) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if s.idempotencyKeys == nil || key == "" || r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if maxBytesErr := (&http.MaxBytesError{}); errors.As(err, &maxBytesErr) {
+			payloadTooLarge(w, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		if err != nil {
+			invalidInput(w, fmt.Sprintf("failed to read request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		actor := auth.ActorFrom(r.Context()).Name
+		fingerprint := idempotency.Fingerprint(r.Method, r.URL.RequestURI(), body)
+		record, err := s.idempotencyKeys.Begin(actor, key, fingerprint)
+		if errors.Is(err, idempotency.ErrMismatch) || errors.Is(err, idempotency.ErrInProgress) {
+			conflict(w, err.Error())
+			return
+		} else if err != nil {
+			log.Printf("failed to read idempotency key: %v", err)
+			internalError(w, "failed to read idempotency key")
+			return
+		}
+		if record != nil {
+			maps.Copy(w.Header(), record.Header)
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.Status)
+			if _, err := w.Write(record.Body); err != nil {
+				log.Printf("failed to write response: %v", err)
+			}
+			return
+		}
+
+		// The key is released unless the response is recorded, also if the handler panics
+		recorded := false
+		defer func() {
+			if !recorded {
+				s.idempotencyKeys.Abort(actor, key)
+			}
+		}()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status < 200 || recorder.status > 299 {
+			return
+		}
+		header := http.Header{}
+		for _, name := range []string{"Content-Type", "Location"} {
+			if value := w.Header().Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+		recorded = true
+		err = s.idempotencyKeys.Complete(idempotency.Record{
+			Actor:       actor,
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      recorder.status,
+			Header:      header,
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			log.Printf("failed to record idempotency key: %v", err)
+		}
+	})
+}
+
+// responseRecorder records the status and body of a response while writing it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
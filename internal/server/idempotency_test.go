@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/idempotency"
+	"net-admin-api/internal/vlan"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: tokenAlice}, {Name: "bob", Token: tokenBob}})
+	require.NoError(t, err)
+	keys, err := idempotency.NewStore(filepath.Join(t.TempDir(), "idempotency-keys.json"), time.Hour)
+	require.NoError(t, err)
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithUsers(users), WithIdempotencyKeys(keys))
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+
+	// A retry returns the original response instead of creating another VLAN
+	resp := doIdempotentRequest(t, server, "/api/v1/vlans", tokenAlice, "create-1", vlan1)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	location := resp.Header.Get("Location")
+	require.NotEmpty(t, location)
+	resp = doIdempotentRequest(t, server, "/api/v1/vlans", tokenAlice, "create-1", vlan1)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, location, resp.Header.Get("Location"))
+	require.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+	vlans := []vlan.VLAN{}
+	require.NoError(t, json.NewDecoder(doRequest(t, server, "GET", "/api/v1/vlans", tokenAlice, nil).Body).Decode(&vlans))
+	require.Len(t, vlans, 1)
+
+	// Reusing a key for a different request is a conflict
+	vlan2 := newVLAN(t, 2, "test2", "192.168.2.0/24", "192.168.2.1")
+	requireConflictResponse(t, doIdempotentRequest(t, server, "/api/v1/vlans", tokenAlice, "create-1", vlan2))
+	requireConflictResponse(t, doIdempotentRequest(t, server, "/api/v1/vrfs", tokenAlice, "create-1", vlan1))
+
+	// Keys are scoped to users, and failed requests do not use them up
+	resp = doIdempotentRequest(t, server, "/api/v1/vlans", tokenBob, "create-1", vlan1)
+	requireConflictResponse(t, resp)
+	require.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+	resp = doIdempotentRequest(t, server, "/api/v1/vlans", tokenBob, "create-1", vlan2)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NotEqual(t, location, resp.Header.Get("Location"))
+
+	// Response bodies are replayed as well
+	tx := TransactionRequest{Operations: []TransactionOperation{{Op: vlan.OperationCreate, VLAN: newVLAN(t, 3, "test3", "192.168.3.0/24", "192.168.3.1")}}}
+	resp = doIdempotentRequest(t, server, "/api/v1/transactions", tokenAlice, "tx-1", tx)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp = doIdempotentRequest(t, server, "/api/v1/transactions", tokenAlice, "tx-1", tx)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	replayed, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, string(body), string(replayed))
+
+	// Requests without a key are not deduplicated
+	resp = doRequest(t, server, "POST", "/api/v1/transactions", tokenAlice, tx)
+	requireConflictResponse(t, resp)
+}
+
+func TestIdempotencyKeys_Panic(t *testing.T) {
+	t.Parallel()
+	keys, err := idempotency.NewStore(filepath.Join(t.TempDir(), "idempotency-keys.json"), time.Hour)
+	require.NoError(t, err)
+	s := &Server{idempotencyKeys: keys}
+	handler := s.idempotencyMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	// A request whose handler panics releases its key, so that it is not in progress forever
+	req := httptest.NewRequest("POST", "/api/v1/vlans", strings.NewReader(`{"vid": 10}`))
+	req.Header.Set("Idempotency-Key", "create-1")
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	})
+	record, err := keys.Begin(auth.Anonymous.Name, "create-1", idempotency.Fingerprint("POST", "/api/v1/vlans", []byte(`{"vid": 10}`)))
+	require.NoError(t, err)
+	require.Nil(t, record)
+}
+
+// Sends a POST request with an Idempotency-Key header, the response body is closed on cleanup.
+func doIdempotentRequest(t *testing.T, server *httptest.Server, path, token, key string, body any) *http.Response {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, json.NewEncoder(buf).Encode(body))
+	req, err := http.NewRequest("POST", server.URL+path, buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Idempotency-Key", key)
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/ratelimit"
+)
+
+func TestRateLimits(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: tokenAlice}, {Name: "bob", Token: tokenBob}})
+	require.NoError(t, err)
+	// Practically no refill during the test
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"),
+		WithUsers(users),
+		WithRateLimits(ratelimit.NewLimiters(ratelimit.Limit{Rate: 0.01, Burst: 2}, ratelimit.Limit{Rate: 0.01, Burst: 1})))
+
+	// Reads are limited per actor
+	for range 2 {
+		require.Equal(t, http.StatusOK, doRequest(t, server, "GET", "/api/v1/vlans", tokenAlice, nil).StatusCode)
+	}
+	resp := doRequest(t, server, "GET", "/api/v1/vlans", tokenAlice, nil)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Greater(t, retryAfter, 0)
+	errResp := ErrorResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	require.Equal(t, ErrCodeRateLimited, errResp.Code)
+	require.Equal(t, http.StatusOK, doRequest(t, server, "GET", "/api/v1/vlans", tokenBob, nil).StatusCode)
+
+	// Writes have a separate budget
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	require.Equal(t, http.StatusCreated, doRequest(t, server, "POST", "/api/v1/vlans", tokenAlice, vlan1).StatusCode)
+	require.Equal(t, http.StatusTooManyRequests, doRequest(t, server, "POST", "/api/v1/vlans", tokenAlice, vlan1).StatusCode)
+
+	// Monitoring endpoints are not limited
+	for range 3 {
+		require.Equal(t, http.StatusOK, doRequest(t, server, "GET", "/health", "", nil).StatusCode)
+	}
+}
+
+func TestRateLimits_Anonymous(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"),
+		WithRateLimits(ratelimit.NewLimiters(ratelimit.Limit{Rate: 0.01, Burst: 1}, ratelimit.Limit{Rate: 0.01, Burst: 1})))
+
+	// Anonymous requests are limited per IP address
+	require.Equal(t, http.StatusOK, doRequest(t, server, "GET", "/api/v1/vlans", "", nil).StatusCode)
+	require.Equal(t, http.StatusTooManyRequests, doRequest(t, server, "GET", "/api/v1/vlans", "", nil).StatusCode)
+}
+
+func TestRateLimits_FailedAuthentication(t *testing.T) {
+	t.Parallel()
+	users, err := auth.NewUsers([]auth.User{{Name: "alice", Token: tokenAlice}})
+	require.NoError(t, err)
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"),
+		WithUsers(users),
+		WithRateLimits(ratelimit.NewLimiters(ratelimit.Limit{Rate: 0.01, Burst: 10}, ratelimit.Limit{Rate: 0.01, Burst: 2})))
+
+	// Failed authentications are limited per IP address, even with a valid token afterwards
+	require.Equal(t, http.StatusOK, doRequest(t, server, "GET", "/api/v1/vlans", tokenAlice, nil).StatusCode)
+	require.Equal(t, http.StatusUnauthorized, doRequest(t, server, "GET", "/api/v1/vlans", "", nil).StatusCode)
+	require.Equal(t, http.StatusUnauthorized, doRequest(t, server, "GET", "/api/v1/vlans", "guess", nil).StatusCode)
+	for _, token := range []string{"guess", tokenAlice} {
+		resp := doRequest(t, server, "GET", "/api/v1/vlans", token, nil)
+		require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		require.NotEmpty(t, resp.Header.Get("Retry-After"))
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"), WithMaxBodySize(256))
+	vlan1 := newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")
+	require.Equal(t, http.StatusCreated, doRequest(t, server, "POST", "/api/v1/vlans", "", vlan1).StatusCode)
+
+	// Rejected by the declared content length
+	vlan2 := newVLAN(t, 2, strings.Repeat("x", 300), "192.168.2.0/24", "192.168.2.1")
+	resp := doRequest(t, server, "POST", "/api/v1/vlans", "", vlan2)
+	requirePayloadTooLarge(t, resp)
+
+	// Rejected while reading a body of unknown length, which is sent chunked
+	body := &bytes.Buffer{}
+	require.NoError(t, json.NewEncoder(body).Encode(vlan2))
+	req, err := http.NewRequest("POST", server.URL+"/api/v1/vlans", io.MultiReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	requirePayloadTooLarge(t, resp)
+}
+
+func requirePayloadTooLarge(t *testing.T, resp *http.Response) {
+	t.Helper()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	errResp := ErrorResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	require.Equal(t, ErrCodeTooLarge, errResp.Code)
+}
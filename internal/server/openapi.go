@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/google/uuid"
+
+	"net-admin-api/api"
+)
+
+var defineFormats sync.Once
+
+// loadOpenAPISpec parses the embedded OpenAPI specification, and returns a router for validating requests.
+func loadOpenAPISpec() (*openapi3.T, routers.Router, error) {
+	defineFormats.Do(func() {
+		openapi3.DefineStringFormatValidator("uuid", openapi3.NewCallbackValidator(func(value string) error {
+			_, err := uuid.Parse(value)
+			return err
+		}))
+		openapi3.DefineStringFormatValidator("ip", openapi3.NewCallbackValidator(func(value string) error {
+			_, err := netip.ParseAddr(value)
+			return err
+		}))
+		openapi3.DefineStringFormatValidator("cidr", openapi3.NewCallbackValidator(func(value string) error {
+			_, err := netip.ParsePrefix(value)
+			return err
+		}))
+	})
+
+	spec, err := openapi3.NewLoader().LoadFromData(api.Spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+	}
+	if err := spec.Validate(context.Background()); err != nil {
+		return nil, nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OpenAPI router: %w", err)
+	}
+	return spec, router, nil
+}
+
+func (s *Server) HandleOpenAPISpec(respWriter http.ResponseWriter, req *http.Request) {
+	respWriter.Header().Set("Content-Type", "application/yaml")
+	respWriter.Write(api.Spec)
+}
+
+func (s *Server) HandleDocs(respWriter http.ResponseWriter, req *http.Request) {
+	respWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	respWriter.Write(api.SwaggerUI)
+}
+
+// HandleDocsAsset serves the Swagger UI files that the docs page loads.
+func (s *Server) HandleDocsAsset(respWriter http.ResponseWriter, req *http.Request) {
+	http.ServeFileFS(respWriter, req, api.SwaggerUIAssets, req.PathValue("file"))
+}
+
+// validationMiddleware rejects requests whose parameters or body do not match the OpenAPI spec.
+// Requests for routes that are not in the spec are passed on as is.
+func (s *Server) validationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := s.specRouter.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Handlers decode bodies as JSON regardless of the content type
+		if r.Header.Get("Content-Type") == "" && r.ContentLength != 0 {
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				ExcludeReadOnlyValidations: true,
+				SkipSettingDefaults:        true,
+				AuthenticationFunc:         openapi3filter.NoopAuthenticationFunc,
+			},
+		})
+		if maxBytesErr := (&http.MaxBytesError{}); errors.As(err, &maxBytesErr) {
+			payloadTooLarge(w, fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit))
+			return
+		}
+		if err != nil {
+			invalidInput(w, validationMessage(err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validationMessage(err error) string {
+	reqErr := &openapi3filter.RequestError{}
+	if !errors.As(err, &reqErr) {
+		return err.Error()
+	}
+
+	schemaErr := &openapi3.SchemaError{}
+	if errors.As(reqErr.Err, &schemaErr) {
+		field := "request body"
+		if reqErr.Parameter != nil {
+			field = fmt.Sprintf("%s parameter %q", reqErr.Parameter.In, reqErr.Parameter.Name)
+		}
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			field = fmt.Sprintf("%s field %q", field, pointer[len(pointer)-1])
+		}
+		return fmt.Sprintf("invalid %s: %s", field, schemaErr.Reason)
+	}
+	return reqErr.Error()
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/api"
+)
+
+func TestOpenAPISpec_MatchesRoutes(t *testing.T) {
+	t.Parallel()
+	spec, _, err := loadOpenAPISpec()
+	require.NoError(t, err)
+
+	specRoutes := []string{}
+	for path, pathItem := range spec.Paths.Map() {
+		for method := range pathItem.Operations() {
+			specRoutes = append(specRoutes, method+" "+path)
+		}
+	}
+	serverRoutes := []string{}
+	for _, route := range (&Server{}).routes() {
+		serverRoutes = append(serverRoutes, route.pattern)
+	}
+
+	slices.Sort(specRoutes)
+	slices.Sort(serverRoutes)
+	require.Equal(t, specRoutes, serverRoutes, "routes in RegisterRoutes and api/openapi.yml disagree")
+}
+
+func TestHandleOpenAPISpec(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	resp := doRequest(t, server, "GET", "/openapi.yml", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, api.Spec, body)
+
+	resp = doRequest(t, server, "GET", "/docs", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `url: "/openapi.yml"`)
+	// Swagger UI is bundled, the page loads nothing from other origins
+	require.NotContains(t, string(body), "https://")
+
+	resp = doRequest(t, server, "GET", "/docs/swagger-ui-bundle.js", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "javascript")
+	resp = doRequest(t, server, "GET", "/docs/swagger-ui.css", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/css")
+	resp = doRequest(t, server, "GET", "/docs/unknown.js", "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestValidationMiddleware(t *testing.T) {
+	t.Parallel()
+	server := newHTTPServer(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	// Bodies, path and query parameters are validated against the spec
+	for _, req := range []struct {
+		method, path, body, message string
+	}{
+		{"POST", "/api/v1/vlans", `{"vid": "ten", "name": "a", "subnet": "10.0.0.0/24", "gateway": "10.0.0.1", "status": "enabled"}`, `"vid"`},
+		{"POST", "/api/v1/vlans", `{"vid": 10, "name": "a", "subnet": "10.0.0.0/24", "gateway": "10.0.0", "status": "enabled"}`, `"gateway"`},
+		{"POST", "/api/v1/vlans", `{"vid": 10, "name": "a", "subnet": "10.0.0.0/24", "gateway": "10.0.0.1"}`, `"status"`},
+		{"GET", "/api/v1/vlans/invalid", "", `"id"`},
+		{"GET", "/api/v1/change-requests?status=unknown", "", `"status"`},
+	} {
+		httpReq, err := http.NewRequest(req.method, server.URL+req.path, strings.NewReader(req.body))
+		require.NoError(t, err)
+		resp, err := server.Client().Do(httpReq)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		errResp := requireInvalidInputResponseBody(t, resp)
+		require.Contains(t, errResp.Message, req.message)
+	}
+
+	// IPv6 VLANs match the spec, and missing content type is treated as JSON
+	resp, err := server.Client().Post(server.URL+"/api/v1/vlans", "",
+		bytes.NewBufferString(`{"vid": 10, "name": "v6", "subnet": "2001:db8::/64", "gateway": "2001:db8::1", "status": "enabled"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// Routes that are not in the spec are left to the router
+	resp, err = server.Client().Get(server.URL + "/api/v2/vlans")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
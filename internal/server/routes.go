@@ -1,23 +1,111 @@
 package server
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
+
+	"net-admin-api/internal/auth"
 )
 
-func (s *Server) RegisterRoutes() http.Handler {
-	mux := http.NewServeMux()
+// route is a ServeMux pattern and its handler. Every route must be described in api/openapi.yml.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		// vlans
+		{"GET /api/v1/vlans", s.HandleListVLANs},
+		{"POST /api/v1/vlans", s.HandleCreateVLAN},
+		{"GET /api/v1/vlans/{id}", s.HandleReadVLAN},
+		{"PUT /api/v1/vlans/{id}", s.HandleUpdateVLAN},
+		{"DELETE /api/v1/vlans/{id}", s.HandleDeleteVLAN},
+		{"GET /api/v1/vlans-by-vid/{vid}", s.HandleReadVLANByVID},
+		{"PUT /api/v1/vlans-by-vid/{vid}", s.HandlePutVLANByVID},
+		{"DELETE /api/v1/vlans-by-vid/{vid}", s.HandleDeleteVLANByVID},
+		{"GET /api/v1/lookup", s.HandleLookup},
+
+		// trash
+		{"GET /api/v1/trash", s.HandleListTrash},
+		{"POST /api/v1/vlans/{id}/restore", s.HandleRestoreVLAN},
+
+		// free space
+		{"GET /api/v1/free/vids", s.HandleFreeVIDs},
+		{"GET /api/v1/free/subnets", s.HandleFreeSubnets},
+		{"GET /api/v1/free/subnets/find", s.HandleFindFreeSubnet},
+
+		// stats
+		{"GET /api/v1/stats", s.HandleStats},
+
+		// custom fields
+		{"GET /api/v1/custom-fields", s.HandleReadCustomFields},
+		{"PUT /api/v1/custom-fields", s.HandleUpdateCustomFields},
+
+		// vrfs
+		{"GET /api/v1/vrfs", s.HandleListVRFs},
+		{"POST /api/v1/vrfs", s.HandleCreateVRF},
+		{"GET /api/v1/vrfs/{id}", s.HandleReadVRF},
+		{"DELETE /api/v1/vrfs/{id}", s.HandleDeleteVRF},
+
+		// events
+		{"GET /api/v1/events", s.HandleEvents},
+
+		// transactions
+		{"POST /api/v1/transactions", s.HandleTransaction},
+
+		// change requests
+		{"GET /api/v1/change-requests", s.HandleListChangeRequests},
+		{"POST /api/v1/change-requests", s.HandleCreateChangeRequest},
+		{"GET /api/v1/change-requests/{id}", s.HandleReadChangeRequest},
+		{"POST /api/v1/change-requests/{id}/approve", s.HandleApproveChangeRequest},
+		{"POST /api/v1/change-requests/{id}/reject", s.HandleRejectChangeRequest},
+
+		// webhooks
+		{"GET /api/v1/webhooks", s.HandleListWebhooks},
+		{"POST /api/v1/webhooks", s.HandleCreateWebhook},
+		{"GET /api/v1/webhooks/{id}", s.HandleReadWebhook},
+		{"DELETE /api/v1/webhooks/{id}", s.HandleDeleteWebhook},
+		{"GET /api/v1/webhooks/{id}/deliveries", s.HandleListWebhookDeliveries},
+		{"GET /api/v1/webhooks/dead-letters", s.HandleListWebhookDeadLetters},
+		{"POST /api/v1/webhooks/dead-letters/{id}/retry", s.HandleRetryWebhookDeadLetter},
+
+		// dhcp
+		{"GET /api/v1/dhcp/scopes", s.HandleListDHCPScopes},
+		{"GET /api/v1/vlans/{id}/dhcp", s.HandleReadDHCPScope},
+		{"PUT /api/v1/vlans/{id}/dhcp", s.HandleUpdateDHCPScope},
+		{"DELETE /api/v1/vlans/{id}/dhcp", s.HandleDeleteDHCPScope},
+		{"GET /api/v1/dhcp/kea/{family}", s.HandleKeaConfig},
+
+		// dns
+		{"GET /api/v1/dns/zones", s.HandleListDNSZones},
+		{"GET /api/v1/dns/zones/{zone}", s.HandleReadDNSZone},
+		{"POST /api/v1/dns/zones/{zone}/update", s.HandleDNSZoneUpdate},
 
-	// vlans
-	mux.HandleFunc("GET /api/v1/vlans", s.HandleListVLANs)
-	mux.HandleFunc("POST /api/v1/vlans", s.HandleCreateVLAN)
-	mux.HandleFunc("GET /api/v1/vlans/{id}", s.HandleReadVLAN)
-	mux.HandleFunc("PUT /api/v1/vlans/{id}", s.HandleUpdateVLAN)
-	mux.HandleFunc("DELETE /api/v1/vlans/{id}", s.HandleDeleteVLAN)
+		// reconciliation
+		{"GET /api/v1/reconcile/status", s.HandleReconcileStatus},
 
-	// monitoring
-	mux.HandleFunc("GET /health", s.HandleHealth)
+		// monitoring
+		{"GET /health", s.HandleHealth},
+		{"GET /metrics", s.HandleMetrics},
 
-	return s.corsMiddleware(mux)
+		// api documentation
+		{"GET /openapi.yml", s.HandleOpenAPISpec},
+		{"GET /docs", s.HandleDocs},
+		{"GET /docs/{file}", s.HandleDocsAsset},
+	}
+}
+
+func (s *Server) RegisterRoutes() http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range s.routes() {
+		mux.HandleFunc(route.pattern, route.handler)
+	}
+
+	return s.corsMiddleware(s.authMiddleware(s.rateLimitMiddleware(s.bodyLimitMiddleware(s.validationMiddleware(s.idempotencyMiddleware(mux))))))
 }
 
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
@@ -25,7 +113,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Idempotency-Key")
 
 		// Handle preflight OPTIONS requests
 		if r.Method == http.MethodOptions {
@@ -37,3 +125,102 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authEnabled reports whether API requests are authenticated, with bearer tokens or client certificates.
+func (s *Server) authEnabled() bool {
+	clientCerts := s.tlsConfig != nil && s.tlsConfig.ClientAuth >= tls.VerifyClientCertIfGiven
+	return s.users != nil || clientCerts
+}
+
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Authentication is optional, and monitoring endpoints are always public
+		if !s.authEnabled() || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Clients that failed to authenticate too often are rejected before their credentials are checked, so
+		// that a correct guess is not told apart from a wrong one
+		host := remoteHost(r)
+		if s.authLimiter != nil {
+			if ok, retryAfter := s.authLimiter.Check(host); !ok {
+				tooManyRequests(w, retryAfter, "too many failed authentications")
+				return
+			}
+		}
+		fail := func(message string) {
+			if s.authLimiter != nil {
+				s.authLimiter.Allow(host)
+			}
+			unauthorized(w, message)
+		}
+
+		if actor, ok := auth.CertificateActor(r.TLS, s.users); ok {
+			next.ServeHTTP(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+			return
+		}
+		if s.users == nil {
+			fail("missing client certificate")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			fail("missing bearer token")
+			return
+		}
+		actor, ok := s.users.Authenticate(token)
+		if !ok {
+			fail("invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithActor(r.Context(), actor)))
+	})
+}
+
+// rateLimitMiddleware limits API requests per actor, or per IP address for anonymous requests.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.readLimiter == nil || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := "actor:" + auth.ActorFrom(r.Context()).Name
+		if key == "actor:"+auth.Anonymous.Name {
+			key = "ip:" + remoteHost(r)
+		}
+		limiter := s.writeLimiter
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			limiter = s.readLimiter
+		}
+
+		if ok, retryAfter := limiter.Allow(key); !ok {
+			tooManyRequests(w, retryAfter, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remoteHost returns the IP address of the client of a request.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// bodyLimitMiddleware rejects request bodies larger than the maximum size before they are read.
+func (s *Server) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > s.maxBodySize {
+			payloadTooLarge(w, fmt.Sprintf("request body exceeds %d bytes", s.maxBodySize))
+			return
+		}
+		// Bodies of unknown length fail when the limit is reached while reading
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodySize)
+		next.ServeHTTP(w, r)
+	})
+}
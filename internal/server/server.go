@@ -1,35 +1,228 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/getkin/kin-openapi/routers"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/change"
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/dhcp"
+	"net-admin-api/internal/dns"
+	"net-admin-api/internal/eventlog"
+	"net-admin-api/internal/idempotency"
+	"net-admin-api/internal/ratelimit"
+	"net-admin-api/internal/reconcile"
 	"net-admin-api/internal/vlan"
+	"net-admin-api/internal/vrf"
+	"net-admin-api/internal/webhook"
+)
+
+const (
+	DefaultEventLogSize   = 1000
+	DefaultEventHeartbeat = 15 * time.Second
+	DefaultMaxBodySize    = 1 << 20
+	DefaultReadTimeout    = 10 * time.Second
+	DefaultWriteTimeout   = 30 * time.Second
+	DefaultIdleTimeout    = time.Minute
 )
 
 // Network Administration API server.
 type Server struct {
-	port      int
-	vlanStore *vlan.Store
+	port           int
+	vlanStore      *vlan.Store
+	reconciler     *reconcile.Reconciler
+	users          *auth.Users
+	tlsConfig      *tls.Config
+	changeRequests *change.Store
+
+	webhooks          *webhook.Store
+	webhookDispatcher *webhook.Dispatcher
+
+	dhcpScopes *dhcp.Store
+	dns        *dns.Options
+	vrfs       *vrf.Store
+
+	customFields *customfield.Store
+
+	idempotencyKeys *idempotency.Store
+
+	perVLANMetrics bool
+
+	eventLogSize   int
+	eventHeartbeat time.Duration
+	events         *eventlog.Log
+
+	specRouter routers.Router
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	readLimiter  *ratelimit.Limiter
+	writeLimiter *ratelimit.Limiter
+	// Failed authentications per IP address.
+	authLimiter *ratelimit.Limiter
+	maxBodySize int64
 }
 
-func NewServer(port int, vlanStorePath string) (*http.Server, error) {
-	vlanStore, err := vlan.NewStore(vlanStorePath)
-	if err != nil {
-		return nil, err
+// Option configures optional Server features.
+type Option func(*Server)
+
+// WithReconciler exposes the status of a reconciler running against the server's VLAN store.
+func WithReconciler(reconciler *reconcile.Reconciler) Option {
+	return func(s *Server) {
+		s.reconciler = reconciler
+	}
+}
+
+// WithUsers requires API requests to be authenticated with a bearer token of one of the users.
+func WithUsers(users *auth.Users) Option {
+	return func(s *Server) {
+		s.users = users
+	}
+}
+
+// WithTLS serves the API over TLS. If the configuration verifies client certificates, API requests must
+// be authenticated with either a client certificate or a bearer token.
+func WithTLS(config *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = config
+	}
+}
+
+// WithChangeRequests enables the change request approval workflow.
+func WithChangeRequests(changeRequests *change.Store) Option {
+	return func(s *Server) {
+		s.changeRequests = changeRequests
+	}
+}
+
+// WithWebhooks enables management of webhook subscriptions delivered by the dispatcher.
+func WithWebhooks(webhooks *webhook.Store, dispatcher *webhook.Dispatcher) Option {
+	return func(s *Server) {
+		s.webhooks = webhooks
+		s.webhookDispatcher = dispatcher
+	}
+}
+
+// WithDHCP enables management of the DHCP scopes of VLANs, and their export as Kea configuration.
+func WithDHCP(scopes *dhcp.Store) Option {
+	return func(s *Server) {
+		s.dhcpScopes = scopes
+	}
+}
+
+// WithDNS enables the generation of DNS zones of the VLANs, which include the hostnames of DHCP reservations
+// if DHCP is enabled.
+func WithDNS(opts dns.Options) Option {
+	return func(s *Server) {
+		s.dns = &opts
 	}
+}
+
+// WithVRFs enables management of VRFs. The VLAN store must check VLANs against the same VRFs, see
+// vlan.WithVRFs.
+func WithVRFs(vrfs *vrf.Store) Option {
+	return func(s *Server) {
+		s.vrfs = vrfs
+	}
+}
+
+// WithCustomFields enables management of the custom field schema. The VLAN store must check VLANs against the
+// same schema, see vlan.WithFieldSchema.
+func WithCustomFields(customFields *customfield.Store) Option {
+	return func(s *Server) {
+		s.customFields = customFields
+	}
+}
+
+// WithIdempotencyKeys replays the responses of POST requests that are retried with the same Idempotency-Key
+// header.
+func WithIdempotencyKeys(keys *idempotency.Store) Option {
+	return func(s *Server) {
+		s.idempotencyKeys = keys
+	}
+}
+
+// WithPerVLANMetrics adds the series of every single VLAN to the metrics, labeled with its ID and name.
+func WithPerVLANMetrics() Option {
+	return func(s *Server) {
+		s.perVLANMetrics = true
+	}
+}
+
+// WithEventStream sets how many events are kept for resuming event streams, and how often idle
+// streams send heartbeats.
+func WithEventStream(logSize int, heartbeat time.Duration) Option {
+	return func(s *Server) {
+		s.eventLogSize = logSize
+		s.eventHeartbeat = heartbeat
+	}
+}
+
+// WithRateLimits limits the API requests of each client, identified by its actor or IP address. Reads
+// (GET requests) and writes have separate limits. Failed authentications are limited per IP address like
+// writes, so that tokens cannot be guessed.
+func WithRateLimits(limiters *ratelimit.Limiters) Option {
+	return func(s *Server) {
+		s.readLimiter = limiters.Reads
+		s.writeLimiter = limiters.Writes
+		s.authLimiter = limiters.FailedAuth
+	}
+}
+
+// WithMaxBodySize sets the maximum size of request bodies in bytes.
+func WithMaxBodySize(size int64) Option {
+	return func(s *Server) {
+		s.maxBodySize = size
+	}
+}
+
+// WithTimeouts sets the timeouts of the underlying http.Server.
+func WithTimeouts(read, write, idle time.Duration) Option {
+	return func(s *Server) {
+		s.readTimeout = read
+		s.writeTimeout = write
+		s.idleTimeout = idle
+	}
+}
+
+func NewServer(port int, vlanStore *vlan.Store, opts ...Option) (*http.Server, error) {
 	server := &Server{
-		port:      port,
-		vlanStore: vlanStore,
+		port:           port,
+		vlanStore:      vlanStore,
+		eventLogSize:   DefaultEventLogSize,
+		eventHeartbeat: DefaultEventHeartbeat,
+		maxBodySize:    DefaultMaxBodySize,
+		readTimeout:    DefaultReadTimeout,
+		writeTimeout:   DefaultWriteTimeout,
+		idleTimeout:    DefaultIdleTimeout,
+	}
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	_, specRouter, err := loadOpenAPISpec()
+	if err != nil {
+		return nil, err
 	}
+	server.specRouter = specRouter
+
+	server.events = eventlog.New(server.eventLogSize, vlanStore.Epoch())
+	vlanStore.Subscribe(server.events.Append)
 
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf(":%d", server.port),
 		Handler:      server.RegisterRoutes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		TLSConfig:    server.tlsConfig,
+		IdleTimeout:  server.idleTimeout,
+		ReadTimeout:  server.readTimeout,
+		WriteTimeout: server.writeTimeout,
 	}
 
 	return httpServer, nil
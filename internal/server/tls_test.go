@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/auth"
+	"net-admin-api/internal/change"
+	"net-admin-api/internal/tlsconfig"
+	"net-admin-api/internal/tlsconfig/tlstest"
+	"net-admin-api/internal/vlan"
+)
+
+func TestClientCertificates(t *testing.T) {
+	t.Parallel()
+	ca := tlstest.NewCA(t, "test-ca")
+	users, err := auth.NewUsers([]auth.User{
+		{Name: "alice", Token: tokenAlice},
+		{Name: "bob", Roles: []string{auth.RoleAdmin}},
+	})
+	require.NoError(t, err)
+	server := newTLSServer(t, ca, WithUsers(users))
+
+	// Monitoring endpoints are public
+	resp, err := tlsClient(ca).Get(server.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// API requests need a client certificate or a bearer token
+	resp, err = tlsClient(ca).Get(server.URL + "/api/v1/vlans")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, http.StatusOK, doRequest(t, server, "GET", "/api/v1/vlans", tokenAlice, nil).StatusCode)
+
+	// The common name of the certificate is the actor, also if it is not in the users file
+	carol := tlsClient(ca, ca.Issue(t, "carol"))
+	op := TransactionOperation{Op: vlan.OperationCreate, VLAN: newVLAN(t, 1, "test1", "192.168.1.0/24", "192.168.1.1")}
+	resp = doTLSRequest(t, carol, "POST", server.URL+"/api/v1/change-requests", op)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	id := uuid.MustParse(path.Base(resp.Header.Get("Location")))
+	require.Equal(t, "carol", readChangeRequest(t, server, id).SubmittedBy)
+
+	// Certificate actors have the roles of the user with the same name
+	bob := tlsClient(ca, ca.Issue(t, "bob"))
+	resp = doTLSRequest(t, bob, "POST", server.URL+"/api/v1/change-requests/"+id.String()+"/approve", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "bob", readChangeRequest(t, server, id).ReviewedBy)
+
+	// Certificates of other CAs are not accepted
+	mallory := tlsClient(ca, tlstest.NewCA(t, "other-ca").Issue(t, "bob"))
+	_, err = mallory.Get(server.URL + "/api/v1/vlans")
+	require.Error(t, err)
+}
+
+func TestClientCertificates_WithoutUsers(t *testing.T) {
+	t.Parallel()
+	ca := tlstest.NewCA(t, "test-ca")
+	server := newTLSServer(t, ca)
+
+	resp, err := tlsClient(ca).Get(server.URL + "/api/v1/vlans")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = doTLSRequest(t, tlsClient(ca, ca.Issue(t, "alice")), "GET", server.URL+"/api/v1/vlans", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// newTLSServer starts a server requiring client certificates issued by ca, and change requests enabled.
+func newTLSServer(t *testing.T, ca *tlstest.CA, opts ...Option) *httptest.Server {
+	t.Helper()
+	certPath, keyPath, caPath := ca.WriteFiles(t, t.TempDir(), "localhost")
+	reloader, err := tlsconfig.New(tlsconfig.Options{
+		CertFile:       certPath,
+		KeyFile:        keyPath,
+		ClientCAFile:   caPath,
+		ReloadInterval: time.Minute,
+	})
+	require.NoError(t, err)
+	vlanStore, err := vlan.NewStore(filepath.Join(t.TempDir(), "vlans.json"))
+	require.NoError(t, err)
+	changeRequests, err := change.NewStore(filepath.Join(t.TempDir(), "change-requests.json"))
+	require.NoError(t, err)
+
+	opts = append(opts, WithTLS(reloader.Config()), WithChangeRequests(changeRequests))
+	apiServer, err := NewServer(0, vlanStore, opts...)
+	require.NoError(t, err)
+	testServer := httptest.NewUnstartedServer(apiServer.Handler)
+	testServer.TLS = apiServer.TLSConfig
+	testServer.StartTLS()
+	t.Cleanup(testServer.Close)
+
+	// The default test client, used by doRequest, trusts the CA but has no certificate
+	testServer.Client().Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: ca.Pool()}
+	return testServer
+}
+
+// tlsClient returns a client trusting ca, with an optional client certificate. The certificate is sent
+// even if it is not issued by one of the CAs accepted by the server.
+func tlsClient(ca *tlstest.CA, certs ...tls.Certificate) *http.Client {
+	config := &tls.Config{RootCAs: ca.Pool()}
+	if len(certs) > 0 {
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &certs[0], nil
+		}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func doTLSRequest(t *testing.T, client *http.Client, method, url string, body any) *http.Response {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if body != nil {
+		require.NoError(t, json.NewEncoder(buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
@@ -3,11 +3,20 @@ package server
 import (
 	"encoding/json"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 const (
 	ErrCodeInvalidInput  = "INVALID_INPUT"
+	ErrCodeUnauthorized  = "UNAUTHORIZED"
+	ErrCodeForbidden     = "FORBIDDEN"
+	ErrCodeNotFound      = "NOT_FOUND"
+	ErrCodeConflict      = "CONFLICT"
+	ErrCodeTooLarge      = "PAYLOAD_TOO_LARGE"
+	ErrCodeRateLimited   = "RATE_LIMITED"
 	ErrCodeInternalError = "INTERNAL_ERROR"
 )
 
@@ -20,6 +29,29 @@ func invalidInput(respWriter http.ResponseWriter, message string) {
 	writeError(respWriter, http.StatusBadRequest, ErrCodeInvalidInput, message)
 }
 
+func unauthorized(respWriter http.ResponseWriter, message string) {
+	respWriter.Header().Set("WWW-Authenticate", "Bearer")
+	writeError(respWriter, http.StatusUnauthorized, ErrCodeUnauthorized, message)
+}
+
+func forbidden(respWriter http.ResponseWriter, message string) {
+	writeError(respWriter, http.StatusForbidden, ErrCodeForbidden, message)
+}
+
+func conflict(respWriter http.ResponseWriter, message string) {
+	writeError(respWriter, http.StatusConflict, ErrCodeConflict, message)
+}
+
+func payloadTooLarge(respWriter http.ResponseWriter, message string) {
+	writeError(respWriter, http.StatusRequestEntityTooLarge, ErrCodeTooLarge, message)
+}
+
+func tooManyRequests(respWriter http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := max(1, math.Ceil(retryAfter.Seconds()))
+	respWriter.Header().Set("Retry-After", strconv.FormatFloat(seconds, 'f', 0, 64))
+	writeError(respWriter, http.StatusTooManyRequests, ErrCodeRateLimited, message)
+}
+
 func internalError(respWriter http.ResponseWriter, message string) {
 	writeError(respWriter, http.StatusInternalServerError, ErrCodeInternalError, message)
 }
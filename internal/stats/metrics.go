@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Metrics renders the stats in the Prometheus text exposition format, as gauges. The series of single VLANs are
+// only included with perVLAN, since there are as many of them as VLANs and their labels change with the VLANs.
+func (s *Stats) Metrics(perVLAN bool) []byte {
+	m := &metrics{}
+
+	m.family("netadmin_vlans", "Number of VLANs by status.")
+	for _, status := range slices.Sorted(maps.Keys(s.ByStatus)) {
+		m.sample("netadmin_vlans", float64(s.ByStatus[status]), "status", status)
+	}
+
+	m.family("netadmin_vids_used", "Number of VLAN IDs of a range that are used by VLANs.")
+	for _, usage := range s.VIDRanges {
+		m.sample("netadmin_vids_used", float64(usage.Used), "range", vidRange(usage))
+	}
+	m.family("netadmin_vids_total", "Number of VLAN IDs of a range.")
+	for _, usage := range s.VIDRanges {
+		m.sample("netadmin_vids_total", float64(usage.Total), "range", vidRange(usage))
+	}
+
+	m.family("netadmin_addresses", "Number of addresses in the subnets of VLANs by address family.")
+	m.sample("netadmin_addresses", s.IPv4.Addresses, "family", "ipv4")
+	m.sample("netadmin_addresses", s.IPv6.Addresses, "family", "ipv6")
+	m.family("netadmin_usable_addresses", "Number of addresses in the subnets of VLANs that hosts can use, by address family.")
+	m.sample("netadmin_usable_addresses", s.IPv4.Usable, "family", "ipv4")
+	m.sample("netadmin_usable_addresses", s.IPv6.Usable, "family", "ipv6")
+
+	if !perVLAN {
+		return m.Bytes()
+	}
+	m.family("netadmin_vlan_addresses", "Number of addresses in the subnet of a VLAN.")
+	for _, v := range s.PerVLAN {
+		m.sample("netadmin_vlan_addresses", v.Addresses, "id", v.ID.String(), "vid", strconv.Itoa(int(v.VID)), "name", v.Name, "vrf", v.VRF)
+	}
+	m.family("netadmin_vlan_usable_addresses", "Number of addresses in the subnet of a VLAN that hosts can use.")
+	for _, v := range s.PerVLAN {
+		m.sample("netadmin_vlan_usable_addresses", v.Usable, "id", v.ID.String(), "vid", strconv.Itoa(int(v.VID)), "name", v.Name, "vrf", v.VRF)
+	}
+	return m.Bytes()
+}
+
+// labelEscaper escapes label values, which may contain any characters except for these.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metrics struct {
+	bytes.Buffer
+}
+
+func (m *metrics) family(name, help string) {
+	fmt.Fprintf(m, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// sample writes a sample with labels given as name and value pairs.
+func (m *metrics) sample(name string, value float64, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	fmt.Fprintf(m, "%s{%s} %s\n", name, strings.Join(pairs, ","), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func vidRange(usage VIDUsage) string {
+	return fmt.Sprintf("%d-%d", usage.From, usage.To)
+}
@@ -0,0 +1,116 @@
+// Package stats summarizes the inventory of VLANs: their status, the utilization of VLAN IDs, and the address
+// capacity of their subnets.
+package stats
+
+import (
+	"cmp"
+	"math"
+	"net/netip"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/freespace"
+	"net-admin-api/internal/vlan"
+)
+
+// DefaultVIDRanges are the normal and the extended range of VLAN IDs, as switches commonly divide them.
+var DefaultVIDRanges = []freespace.VIDRange{{From: 1, To: 1005}, {From: 1006, To: 4094}}
+
+// Stats of a set of VLANs. Address counts are floats, because IPv6 subnets have more addresses than fit in an
+// integer.
+type Stats struct {
+	VLANs     int            `json:"vlans"`
+	ByStatus  map[string]int `json:"byStatus"`
+	VIDRanges []VIDUsage     `json:"vidRanges"`
+	IPv4      Capacity       `json:"ipv4"`
+	IPv6      Capacity       `json:"ipv6"`
+	PerVLAN   []VLANCapacity `json:"perVlan"`
+}
+
+// VIDUsage is the number of VLAN IDs of a range that are used by at least one VLAN.
+type VIDUsage struct {
+	freespace.VIDRange
+	Used        int     `json:"used"`
+	Total       int     `json:"total"`
+	PercentUsed float64 `json:"percentUsed"`
+}
+
+// Capacity is the number of addresses in the subnets of the VLANs of an address family. Usable addresses exclude
+// the gateway and, for IPv4, the network and broadcast addresses.
+type Capacity struct {
+	VLANs     int     `json:"vlans"`
+	Addresses float64 `json:"addresses"`
+	Usable    float64 `json:"usable"`
+}
+
+// VLANCapacity is the number of addresses in the subnet of a VLAN.
+type VLANCapacity struct {
+	ID        uuid.UUID    `json:"id"`
+	VID       uint16       `json:"vid"`
+	Name      string       `json:"name"`
+	VRF       string       `json:"vrf,omitempty"`
+	Subnet    netip.Prefix `json:"subnet"`
+	Addresses float64      `json:"addresses"`
+	Usable    float64      `json:"usable"`
+}
+
+// Compute summarizes VLANs, with the utilization of the given VID ranges. VLANs are listed by VID and name.
+func Compute(vlans []vlan.VLAN, vidRanges []freespace.VIDRange) Stats {
+	stats := Stats{
+		VLANs:     len(vlans),
+		ByStatus:  map[string]int{},
+		VIDRanges: make([]VIDUsage, len(vidRanges)),
+		PerVLAN:   make([]VLANCapacity, len(vlans)),
+	}
+
+	used := map[uint16]bool{}
+	for i, v := range vlans {
+		stats.ByStatus[v.Status]++
+		used[v.VID] = true
+
+		addresses, usable := capacity(v)
+		stats.PerVLAN[i] = VLANCapacity{
+			ID:        v.ID,
+			VID:       v.VID,
+			Name:      v.Name,
+			VRF:       v.VRF,
+			Subnet:    v.Subnet,
+			Addresses: addresses,
+			Usable:    usable,
+		}
+		family := &stats.IPv4
+		if v.Subnet.Addr().Is6() {
+			family = &stats.IPv6
+		}
+		family.VLANs++
+		family.Addresses += addresses
+		family.Usable += usable
+	}
+	slices.SortFunc(stats.PerVLAN, func(a, b VLANCapacity) int {
+		return cmp.Or(cmp.Compare(a.VID, b.VID), cmp.Compare(a.Name, b.Name))
+	})
+
+	for i, vidRange := range vidRanges {
+		usage := VIDUsage{VIDRange: vidRange, Total: int(vidRange.To) - int(vidRange.From) + 1}
+		for vid := range used {
+			if vid >= vidRange.From && vid <= vidRange.To {
+				usage.Used++
+			}
+		}
+		usage.PercentUsed = math.Round(float64(usage.Used)/float64(usage.Total)*10000) / 100
+		stats.VIDRanges[i] = usage
+	}
+	return stats
+}
+
+// capacity returns the number of addresses in the subnet of a VLAN, and how many of them are usable.
+func capacity(v vlan.VLAN) (float64, float64) {
+	bits := v.Subnet.Addr().BitLen() - v.Subnet.Bits()
+	addresses := math.Exp2(float64(bits))
+	reserved := 1.0
+	if v.Subnet.Addr().Is4() && bits > 1 {
+		reserved += 2
+	}
+	return addresses, max(addresses-reserved, 0)
+}
@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"bytes"
+	"flag"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/freespace"
+	"net-admin-api/internal/vlan"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCompute(t *testing.T) {
+	t.Parallel()
+	users := newVLAN(10, "users", "192.168.10.0/24", "active")
+	p2p := newVLAN(1010, "p2p", "10.0.0.0/31", "active")
+	red := newVLAN(10, "users", "192.168.10.0/25", "disabled")
+	red.VRF = "red"
+	lab := newVLAN(20, "lab", "2001:db8:20::/64", "active")
+
+	stats := Compute([]vlan.VLAN{users, p2p, red, lab}, DefaultVIDRanges)
+	require.Equal(t, 4, stats.VLANs)
+	require.Equal(t, map[string]int{"active": 3, "disabled": 1}, stats.ByStatus)
+
+	// VIDs that are used by several VLANs count once
+	require.Equal(t, []VIDUsage{
+		{VIDRange: freespace.VIDRange{From: 1, To: 1005}, Used: 2, Total: 1005, PercentUsed: 0.2},
+		{VIDRange: freespace.VIDRange{From: 1006, To: 4094}, Used: 1, Total: 3089, PercentUsed: 0.03},
+	}, stats.VIDRanges)
+
+	require.Equal(t, Capacity{VLANs: 3, Addresses: 256 + 2 + 128, Usable: 253 + 1 + 125}, stats.IPv4)
+	require.Equal(t, Capacity{VLANs: 1, Addresses: 1 << 64, Usable: 1<<64 - 1}, stats.IPv6)
+	require.Len(t, stats.PerVLAN, 4)
+	require.Equal(t, VLANCapacity{
+		ID:        red.ID,
+		VID:       10,
+		Name:      "users",
+		VRF:       "red",
+		Subnet:    red.Subnet,
+		Addresses: 128,
+		Usable:    125,
+	}, stats.PerVLAN[1])
+	require.Equal(t, []uint16{10, 10, 20, 1010}, []uint16{stats.PerVLAN[0].VID, stats.PerVLAN[1].VID, stats.PerVLAN[2].VID, stats.PerVLAN[3].VID})
+
+	empty := Compute([]vlan.VLAN{}, []freespace.VIDRange{{From: 100, To: 199}})
+	require.Equal(t, []VIDUsage{{VIDRange: freespace.VIDRange{From: 100, To: 199}, Total: 100}}, empty.VIDRanges)
+	require.Empty(t, empty.PerVLAN)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	users := newVLAN(10, "users", "192.168.10.0/24", "active")
+	users.ID = uuid.MustParse("00000000-0000-0000-0000-000000000010")
+	lab := newVLAN(20, `lab "new"`, "2001:db8:20::/64", "planned")
+	lab.ID = uuid.MustParse("00000000-0000-0000-0000-000000000020")
+	lab.VRF = "red"
+
+	stats := Compute([]vlan.VLAN{lab, users}, DefaultVIDRanges)
+	requireGolden(t, filepath.Join("testdata", "metrics.txt"), stats.Metrics(true))
+	require.NotContains(t, string(stats.Metrics(false)), "netadmin_vlan_")
+	require.True(t, bytes.HasPrefix(stats.Metrics(true), stats.Metrics(false)))
+}
+
+func newVLAN(vid uint16, name, subnet, status string) vlan.VLAN {
+	prefix := netip.MustParsePrefix(subnet)
+	return vlan.VLAN{
+		ID:      uuid.New(),
+		VID:     vid,
+		Name:    name,
+		Subnet:  prefix,
+		Gateway: prefix.Addr().Next(),
+		Status:  status,
+	}
+}
+
+func requireGolden(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if *update {
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	golden, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(golden), string(data))
+}
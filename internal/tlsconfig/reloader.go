@@ -0,0 +1,146 @@
+// Package tlsconfig provides server TLS configurations that pick up rotated certificate files.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// PEM bundle of CAs that client certificates are verified against, mTLS is disabled when empty.
+	ClientCAFile string
+	// How often the files are checked for changes.
+	ReloadInterval time.Duration
+}
+
+// Reloader keeps the certificate and client CAs loaded from files, and reloads them when the files change.
+type Reloader struct {
+	opts Options
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	clientCAs   *x509.CertPool
+	fingerprint string
+}
+
+// New loads the files, failing if they are missing or invalid.
+func New(opts Options) (*Reloader, error) {
+	r := &Reloader{opts: opts}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config returns a server TLS configuration that always uses the most recently loaded files. Client
+// certificates are optional, but verified when given if a client CA bundle is configured.
+func (r *Reloader) Config() *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r.opts.ClientCAFile != "" {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	// Also tells http.Server.ServeTLS that the configuration has a certificate
+	config.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.cert, nil
+	}
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return &tls.Config{
+			MinVersion:   config.MinVersion,
+			Certificates: []tls.Certificate{*r.cert},
+			ClientAuth:   config.ClientAuth,
+			ClientCAs:    r.clientCAs,
+			// Replaces the protocols that the HTTP and gRPC servers set on the returned config
+			NextProtos: []string{"h2", "http/1.1"},
+		}, nil
+	}
+	return config
+}
+
+// Run checks the files for changes on every interval until ctx is cancelled. Files that fail to load,
+// e.g. because only one of the certificate and key has been rotated so far, are retried.
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := r.Reload()
+		if err != nil {
+			log.Printf("failed to reload TLS certificate: %v", err)
+		} else if reloaded {
+			log.Printf("reloaded TLS certificate %v", r.opts.CertFile)
+		}
+	}
+}
+
+// Reload loads the files if they have changed since they were last loaded, and reports whether they were.
+// The previously loaded files stay in use if loading fails.
+func (r *Reloader) Reload() (bool, error) {
+	fingerprint, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := fingerprint == r.fingerprint
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load %v: %w", r.opts.CertFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %v: %w", r.opts.ClientCAFile, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return false, fmt.Errorf("no certificates found in %v", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.fingerprint = fingerprint
+	return true, nil
+}
+
+// stat identifies the current versions of the files by their modification times and sizes.
+func (r *Reloader) stat() (string, error) {
+	versions := []string{}
+	for _, path := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		versions = append(versions, fmt.Sprintf("%s:%d:%d", path, info.ModTime().UnixNano(), info.Size()))
+	}
+	return strings.Join(versions, ","), nil
+}
@@ -0,0 +1,144 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/tlsconfig/tlstest"
+)
+
+func TestReloader_Reload(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, "test-ca")
+	certPath, keyPath, _ := ca.WriteFiles(t, dir, "server-1")
+
+	reloader, err := New(Options{CertFile: certPath, KeyFile: keyPath, ReloadInterval: time.Minute})
+	require.NoError(t, err)
+	addr := serveTLS(t, reloader.Config())
+	clientConfig := &tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"}
+	require.Equal(t, "server-1", serverName(t, addr, clientConfig))
+
+	// Nothing to reload while the files are unchanged
+	reloaded, err := reloader.Reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	// Rotated files are used for new connections
+	ca.WriteFiles(t, dir, "server-2")
+	touch(t, certPath, keyPath)
+	reloaded, err = reloader.Reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.Equal(t, "server-2", serverName(t, addr, clientConfig))
+
+	// A certificate not matching the key is not loaded, the previous one stays in use
+	certPEM, _ := ca.IssuePEM(t, "server-3")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+	touch(t, certPath)
+	_, err = reloader.Reload()
+	require.Error(t, err)
+	require.Equal(t, "server-2", serverName(t, addr, clientConfig))
+}
+
+func TestReloader_ClientCertificates(t *testing.T) {
+	t.Parallel()
+	ca := tlstest.NewCA(t, "test-ca")
+	certPath, keyPath, caPath := ca.WriteFiles(t, t.TempDir(), "server")
+
+	reloader, err := New(Options{CertFile: certPath, KeyFile: keyPath, ClientCAFile: caPath, ReloadInterval: time.Minute})
+	require.NoError(t, err)
+	config := reloader.Config()
+	require.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+	addr := serveTLS(t, config)
+
+	// Client certificates are optional
+	require.Equal(t, "server", serverName(t, addr, &tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"}))
+
+	// Client certificates of the CA are accepted
+	require.Equal(t, "server", serverName(t, addr, &tls.Config{
+		RootCAs:      ca.Pool(),
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{ca.Issue(t, "alice")},
+	}))
+
+	// Client certificates of other CAs are rejected, clients would not even send them by default
+	otherCert := tlstest.NewCA(t, "other-ca").Issue(t, "mallory")
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:    ca.Pool(),
+		ServerName: "localhost",
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &otherCert, nil
+		},
+	})
+	if err == nil {
+		// TLS 1.3 clients learn about rejected certificates on the first read
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	require.Error(t, err)
+}
+
+func TestNew_NOK(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, "test-ca")
+	certPath, keyPath, _ := ca.WriteFiles(t, dir, "server")
+
+	_, err := New(Options{CertFile: certPath, KeyFile: dir + "/missing.key"})
+	require.Error(t, err)
+	_, err = New(Options{CertFile: certPath, KeyFile: keyPath, ClientCAFile: keyPath})
+	require.ErrorContains(t, err, "no certificates found")
+}
+
+// serveTLS accepts TLS connections until the test ends, completing the handshake and writing a byte.
+func serveTLS(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if conn.(*tls.Conn).Handshake() == nil {
+					_, _ = conn.Write([]byte{0})
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// serverName returns the common name of the certificate presented by the server.
+func serverName(t *testing.T, addr string, config *tls.Config) string {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, config)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Read(make([]byte, 1))
+	require.NoError(t, err)
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+// touch moves the modification times of files forward, so that rewrites within the timestamp
+// resolution of the file system are noticed.
+func touch(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		modTime := info.ModTime().Add(time.Second)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+}
@@ -0,0 +1,72 @@
+package vlan
+
+import (
+	"encoding/json"
+	"errors"
+
+	"net-admin-api/internal/encryption"
+)
+
+// Additional data of sealed store files, so that a log record cannot be passed off as a snapshot.
+var (
+	snapshotAAD = []byte("net-admin-api vlan snapshot")
+	logAAD      = []byte("net-admin-api vlan log record")
+)
+
+// ErrNotEncrypted is returned on startup for plaintext store files of an encrypted store, unless they are
+// migrated with WithPlaintextMigration.
+var ErrNotEncrypted = errors.New("not encrypted")
+
+// WithEncryption encrypts the snapshot and every log record with the current key of the keyring. Files
+// that are not encrypted with the current key, e.g. after a key rotation, are re-encrypted on startup.
+// Plaintext files are rejected, so that a file swapped in on a shared volume cannot pass for the store.
+func WithEncryption(keyring *encryption.Keyring) StoreOption {
+	return func(s *Store) {
+		s.keyring = keyring
+	}
+}
+
+// WithPlaintextMigration makes an encrypted store accept plaintext files on startup, and encrypt them right away.
+// It is meant to be set once, when encryption is enabled for an existing store.
+func WithPlaintextMigration() StoreOption {
+	return func(s *Store) {
+		s.migratePlaintext = true
+	}
+}
+
+// seal encrypts store data if the store is encrypted.
+func (s *Store) seal(plaintext, additionalData []byte) ([]byte, error) {
+	if s.keyring == nil {
+		return plaintext, nil
+	}
+	sealed, err := s.keyring.Seal(plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed)
+}
+
+// unseal returns the plaintext of store data, which may be encrypted with any key of the keyring. It also
+// reports whether the data is stale, i.e. not encrypted with the current key and to be re-encrypted. Plaintext
+// data of an encrypted store is only accepted with WithPlaintextMigration.
+func (s *Store) unseal(data, additionalData []byte) ([]byte, bool, error) {
+	if !encryption.IsSealed(data) {
+		if s.keyring != nil && !s.migratePlaintext {
+			return nil, false, ErrNotEncrypted
+		}
+		return data, s.keyring != nil, nil
+	}
+	if s.keyring == nil {
+		return nil, false, errors.New("encrypted, but no encryption key is configured")
+	}
+
+	sealed := encryption.Sealed{}
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, false, err
+	}
+	plaintext, err := s.keyring.Open(sealed, additionalData)
+	if err != nil {
+		return nil, false, err
+	}
+	return plaintext, sealed.KeyID != s.keyring.CurrentKeyID(), nil
+}
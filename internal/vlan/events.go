@@ -0,0 +1,74 @@
+package vlan
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventCreated EventType = "vlan.created"
+	EventUpdated EventType = "vlan.updated"
+	EventDeleted EventType = "vlan.deleted"
+)
+
+var EventTypes = []EventType{EventCreated, EventUpdated, EventDeleted}
+
+// Event describes a change persisted by the Store. Deleted events carry the VLAN as it was before deletion.
+type Event struct {
+	// Sequence number of the event, increasing by one for every event of a Store.
+	ID uint64 `json:"id"`
+	// Epoch of the Store that numbered the event. Sequence numbers restart at 1 with a new epoch whenever the
+	// store is opened, so an ID is only meaningful along with its epoch.
+	Epoch string    `json:"epoch"`
+	Type  EventType `json:"type"`
+	Time  time.Time `json:"time"`
+	VLAN  VLAN      `json:"vlan"`
+}
+
+// Subscribe registers fn to be called for every change persisted by the store, in the order of the changes.
+// fn is called with the store locked, so it must not block or call back into the store.
+func (s *Store) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscribers == nil {
+		s.subscribers = map[int]func(Event){}
+	}
+	id := s.nextSubscriberID
+	s.nextSubscriberID++
+	s.subscribers[id] = fn
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// publish notifies subscribers of a persisted change, the caller must hold the write lock.
+func (s *Store) publish(eventType EventType, vlan VLAN) {
+	s.lastEventID++
+	event := Event{
+		ID:    s.lastEventID,
+		Epoch: s.epoch,
+		Type:  eventType,
+		Time:  time.Now().UTC(),
+		VLAN:  vlan,
+	}
+	for _, fn := range s.subscribers {
+		fn(event)
+	}
+}
+
+// Epoch returns the epoch of the events of the store, see Event.
+func (s *Store) Epoch() string {
+	return s.epoch
+}
+
+// newEpoch returns a random epoch, which differs from the epochs of earlier runs.
+func newEpoch() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
@@ -0,0 +1,55 @@
+package vlan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"net-admin-api/internal/customfield"
+)
+
+// WithFieldSchema makes the store check the custom fields of VLANs against the schema that the function returns.
+// Without it, VLANs cannot have custom fields.
+func WithFieldSchema(schema func() customfield.Schema) StoreOption {
+	return func(s *Store) {
+		s.fieldSchema = schema
+	}
+}
+
+// ChangeFieldSchema calls save to change the custom field schema, unless VLANs do not match the new schema, e.g.
+// because a field becomes required that they do not have. save is called with the store locked, so that no VLAN
+// is stored in the meantime, and must not call back into the store.
+func (s *Store) ChangeFieldSchema(schema customfield.Schema, save func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, vlan := range s.vlansByID {
+		if errors := schema.Check(vlan.Fields); len(errors) > 0 {
+			return fmt.Errorf("%w: vlan %s does not match the custom fields: %s", ErrInvalidFields, vlan.Name, strings.Join(errors, ", "))
+		}
+	}
+	return save()
+}
+
+// checkFields checks the custom field values of a VLAN against the current schema.
+func (s *Store) checkFields(vlan VLAN) error {
+	schema := customfield.Schema{}
+	if s.fieldSchema != nil {
+		schema = s.fieldSchema()
+	}
+	if errors := schema.Check(vlan.Fields); len(errors) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidFields, strings.Join(errors, ", "))
+	}
+	return nil
+}
+
+// normalized returns a copy of a VLAN that does not share its tags and custom fields with the caller, in the
+// form they have after they were read from the store files.
+func (v VLAN) normalized() VLAN {
+	v.Tags = slices.Clone(v.Tags)
+	if len(v.Tags) == 0 {
+		v.Tags = nil
+	}
+	v.Fields = customfield.Normalize(v.Fields)
+	return v
+}
@@ -0,0 +1,231 @@
+package vlan
+
+import (
+	"math/bits"
+	"net/netip"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Lookup returns the VLANs of a VRF, or of the global routing table if vrf is empty, with the longest subnet that
+// contains the address. There is more than one only if VLANs have the same subnet, which older store files may
+// contain.
+func (s *Store) Lookup(vrf string, addr netip.Addr) []VLAN {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.vlansOf(s.index.lookup(vrf, addr))
+}
+
+// LookupAll returns the VLANs with the longest subnet that contains the address in every VRF, ordered by VRF with
+// the global routing table first.
+func (s *Store) LookupAll(addr netip.Addr) []VLAN {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	vlans := []VLAN{}
+	for _, vrf := range s.index.vrfs(addr) {
+		vlans = append(vlans, s.vlansOf(s.index.lookup(vrf, addr))...)
+	}
+	return vlans
+}
+
+func (s *Store) vlansOf(ids []uuid.UUID) []VLAN {
+	vlans := make([]VLAN, len(ids))
+	for i, id := range ids {
+		vlans[i] = s.vlansByID[id]
+	}
+	return vlans
+}
+
+// prefixIndex indexes the subnets of VLANs for longest-prefix-match lookups, with a path-compressed binary trie
+// (PATRICIA trie) per VRF and address family. A lookup visits at most one node per prefix length, no matter how
+// many VLANs there are.
+type prefixIndex struct {
+	tries map[indexKey]*trieNode
+}
+
+type indexKey struct {
+	vrf  string
+	ipv6 bool
+}
+
+// trieNode is a prefix in the trie. Nodes without VLANs only join the subtrees of their children, whose
+// prefixes differ in the bit after the node prefix.
+type trieNode struct {
+	prefix   netip.Prefix
+	ids      []uuid.UUID
+	children [2]*trieNode
+}
+
+func newPrefixIndex(vlansByID map[uuid.UUID]VLAN) *prefixIndex {
+	index := &prefixIndex{tries: map[indexKey]*trieNode{}}
+	for _, vlan := range vlansByID {
+		index.add(vlan)
+	}
+	return index
+}
+
+func (x *prefixIndex) add(vlan VLAN) {
+	key := indexKey{vrf: vlan.VRF, ipv6: vlan.Subnet.Addr().Is6()}
+	x.tries[key] = x.tries[key].insert(vlan.Subnet.Masked(), vlan.ID)
+}
+
+func (x *prefixIndex) remove(vlan VLAN) {
+	key := indexKey{vrf: vlan.VRF, ipv6: vlan.Subnet.Addr().Is6()}
+	if root := x.tries[key].remove(vlan.Subnet.Masked(), vlan.ID); root != nil {
+		x.tries[key] = root
+	} else {
+		delete(x.tries, key)
+	}
+}
+
+// lookup returns the VLANs of a VRF with the longest subnet that contains the address. There is more than one
+// only if VLANs have the same subnet, which older store files may contain.
+func (x *prefixIndex) lookup(vrf string, addr netip.Addr) []uuid.UUID {
+	var match []uuid.UUID
+	for node := x.tries[indexKey{vrf: vrf, ipv6: addr.Is6()}]; node != nil && node.prefix.Contains(addr); {
+		if len(node.ids) > 0 {
+			match = node.ids
+		}
+		if node.prefix.Bits() == addr.BitLen() {
+			break
+		}
+		node = node.children[bitAt(addr, node.prefix.Bits())]
+	}
+	return match
+}
+
+// overlapping returns a VLAN of a VRF whose subnet overlaps the prefix, ignoring the VLANs for which skip returns
+// true. It only visits the nodes on the path to the prefix, and the subtree of the prefix up to the first VLAN that
+// is not skipped.
+func (x *prefixIndex) overlapping(vrf string, prefix netip.Prefix, skip func(uuid.UUID) bool) (uuid.UUID, bool) {
+	if !prefix.IsValid() {
+		return uuid.Nil, false
+	}
+	prefix = prefix.Masked()
+	for node := x.tries[indexKey{vrf: vrf, ipv6: prefix.Addr().Is6()}]; node != nil; {
+		common := commonBits(node.prefix, prefix)
+		switch {
+		case common == prefix.Bits():
+			// The node prefix is inside the prefix, and so are all prefixes of its subtree
+			return node.find(skip)
+		case common < node.prefix.Bits():
+			return uuid.Nil, false
+		}
+		// The node prefix contains the prefix
+		for _, id := range node.ids {
+			if !skip(id) {
+				return id, true
+			}
+		}
+		node = node.children[bitAt(prefix.Addr(), node.prefix.Bits())]
+	}
+	return uuid.Nil, false
+}
+
+// vrfs returns the VRFs that have VLANs of the address family of addr.
+func (x *prefixIndex) vrfs(addr netip.Addr) []string {
+	vrfs := []string{}
+	for key := range x.tries {
+		if key.ipv6 == addr.Is6() {
+			vrfs = append(vrfs, key.vrf)
+		}
+	}
+	slices.Sort(vrfs)
+	return vrfs
+}
+
+// insert adds a VLAN to the subtree of the node, and returns the new root of the subtree.
+func (n *trieNode) insert(prefix netip.Prefix, id uuid.UUID) *trieNode {
+	if n == nil {
+		return &trieNode{prefix: prefix, ids: []uuid.UUID{id}}
+	}
+	common := commonBits(n.prefix, prefix)
+	switch {
+	case common == n.prefix.Bits() && common == prefix.Bits():
+		n.ids = append(n.ids, id)
+		return n
+	case common == n.prefix.Bits():
+		// The prefix is inside the node prefix
+		child := bitAt(prefix.Addr(), common)
+		n.children[child] = n.children[child].insert(prefix, id)
+		return n
+	case common == prefix.Bits():
+		// The node prefix is inside the prefix
+		parent := &trieNode{prefix: prefix, ids: []uuid.UUID{id}}
+		parent.children[bitAt(n.prefix.Addr(), common)] = n
+		return parent
+	default:
+		// The prefixes diverge, a new node joins them
+		branch := &trieNode{prefix: netip.PrefixFrom(prefix.Addr(), common).Masked()}
+		branch.children[bitAt(n.prefix.Addr(), common)] = n
+		branch.children[bitAt(prefix.Addr(), common)] = &trieNode{prefix: prefix, ids: []uuid.UUID{id}}
+		return branch
+	}
+}
+
+// remove removes a VLAN from the subtree of the node, and returns the new root of the subtree, which is nil if
+// the subtree is empty.
+func (n *trieNode) remove(prefix netip.Prefix, id uuid.UUID) *trieNode {
+	if n == nil || commonBits(n.prefix, prefix) < n.prefix.Bits() {
+		return n
+	}
+	if n.prefix.Bits() < prefix.Bits() {
+		child := bitAt(prefix.Addr(), n.prefix.Bits())
+		n.children[child] = n.children[child].remove(prefix, id)
+	} else {
+		n.ids = slices.DeleteFunc(n.ids, func(other uuid.UUID) bool { return other == id })
+	}
+
+	// Nodes without VLANs are only kept while they join two subtrees
+	switch {
+	case len(n.ids) > 0:
+		return n
+	case n.children[0] == nil:
+		return n.children[1]
+	case n.children[1] == nil:
+		return n.children[0]
+	}
+	return n
+}
+
+// find returns a VLAN of the subtree of the node for which skip returns false.
+func (n *trieNode) find(skip func(uuid.UUID) bool) (uuid.UUID, bool) {
+	if n == nil {
+		return uuid.Nil, false
+	}
+	for _, id := range n.ids {
+		if !skip(id) {
+			return id, true
+		}
+	}
+	if id, ok := n.children[0].find(skip); ok {
+		return id, true
+	}
+	return n.children[1].find(skip)
+}
+
+// bitAt returns bit i of an address, counting from the most significant bit.
+func bitAt(addr netip.Addr, i int) int {
+	if addr.Is4() {
+		i += 96
+	}
+	bytes := addr.As16()
+	return int(bytes[i/8]>>(7-i%8)) & 1
+}
+
+// commonBits returns the length of the longest prefix that contains both prefixes.
+func commonBits(a, b netip.Prefix) int {
+	limit := min(a.Bits(), b.Bits())
+	offset := 0
+	if a.Addr().Is4() {
+		offset = 96
+	}
+	aBytes, bBytes := a.Addr().As16(), b.Addr().As16()
+	for i := offset / 8; i < len(aBytes); i++ {
+		if diff := aBytes[i] ^ bBytes[i]; diff != 0 {
+			return min(limit, i*8-offset+bits.LeadingZeros8(diff))
+		}
+	}
+	return limit
+}
@@ -0,0 +1,243 @@
+package vlan
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixIndex(t *testing.T) {
+	t.Parallel()
+	index := newPrefixIndex(nil)
+	vlans := map[uuid.UUID]VLAN{}
+	add := func(subnet string) VLAN {
+		vlan := VLAN{ID: uuid.New(), Subnet: netip.MustParsePrefix(subnet)}
+		vlans[vlan.ID] = vlan
+		index.add(vlan)
+		return vlan
+	}
+	remove := func(vlan VLAN) {
+		delete(vlans, vlan.ID)
+		index.remove(vlan)
+	}
+	requireLookup := func(addr string, expected ...VLAN) {
+		t.Helper()
+		ids := []uuid.UUID{}
+		for _, vlan := range expected {
+			ids = append(ids, vlan.ID)
+		}
+		require.ElementsMatch(t, ids, index.lookup("", netip.MustParseAddr(addr)), addr)
+	}
+
+	// Nested prefixes, prefixes that need a branch node, and subnets that are not masked
+	slash8 := add("10.0.0.0/8")
+	slash24 := add("10.1.2.0/24")
+	sibling := add("10.1.3.0/24")
+	host := add("10.1.2.7/32")
+	unmasked := add("192.168.1.1/24")
+	all := add("0.0.0.0/0")
+	v6 := add("2001:db8::/32")
+
+	requireLookup("10.1.2.7", host)
+	requireLookup("10.1.2.8", slash24)
+	requireLookup("10.1.3.1", sibling)
+	requireLookup("10.200.0.1", slash8)
+	requireLookup("192.168.1.200", unmasked)
+	requireLookup("172.16.0.1", all)
+	requireLookup("2001:db8::1", v6)
+	requireLookup("2001:db9::1")
+
+	// Removing prefixes does not affect the others
+	remove(slash24)
+	remove(all)
+	requireLookup("10.1.2.7", host)
+	requireLookup("10.1.2.8", slash8)
+	requireLookup("10.1.3.1", sibling)
+	requireLookup("172.16.0.1")
+	duplicate := add("10.1.3.0/24")
+	requireLookup("10.1.3.1", sibling, duplicate)
+	remove(sibling)
+	requireLookup("10.1.3.1", duplicate)
+	remove(v6)
+	require.NotContains(t, index.tries, indexKey{ipv6: true})
+
+	// Random prefixes give the same results as a linear search
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		add(randomPrefix(r).String())
+	}
+	for _, vlan := range vlans {
+		if r.IntN(2) == 0 {
+			remove(vlan)
+		}
+	}
+	for range 10000 {
+		addr := randomAddr(r)
+		var expected []VLAN
+		for _, vlan := range vlans {
+			if !vlan.Subnet.Contains(addr) {
+				continue
+			}
+			if len(expected) == 0 || vlan.Subnet.Bits() > expected[0].Subnet.Bits() {
+				expected = []VLAN{vlan}
+			} else if vlan.Subnet.Bits() == expected[0].Subnet.Bits() {
+				expected = append(expected, vlan)
+			}
+		}
+		requireLookup(addr.String(), expected...)
+	}
+}
+
+func TestPrefixIndex_Overlapping(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(3, 4))
+	vlansByID := map[uuid.UUID]VLAN{}
+	for range 1000 {
+		vlan := VLAN{ID: uuid.New(), Subnet: randomPrefix(r)}
+		if r.IntN(4) == 0 {
+			vlan.VRF = "red"
+		}
+		vlansByID[vlan.ID] = vlan
+	}
+	index := newPrefixIndex(vlansByID)
+	noneSkipped := func(uuid.UUID) bool { return false }
+
+	_, ok := index.overlapping("blue", netip.MustParsePrefix("10.0.0.0/8"), noneSkipped)
+	require.False(t, ok)
+	_, ok = index.overlapping("", netip.MustParsePrefix("2001:db8::/32"), noneSkipped)
+	require.False(t, ok)
+	_, ok = index.overlapping("", netip.Prefix{}, noneSkipped)
+	require.False(t, ok)
+
+	// Random prefixes, with random VLANs skipped, give the same results as a linear search
+	for range 10000 {
+		vrf := ""
+		if r.IntN(2) == 0 {
+			vrf = "red"
+		}
+		prefix := netip.PrefixFrom(randomAddr(r), 8+r.IntN(25))
+		skipped := map[uuid.UUID]bool{}
+		expected := map[uuid.UUID]bool{}
+		for id, vlan := range vlansByID {
+			if vlan.VRF != vrf || !vlan.Subnet.Overlaps(prefix.Masked()) {
+				continue
+			}
+			if r.IntN(2) == 0 {
+				skipped[id] = true
+			} else {
+				expected[id] = true
+			}
+		}
+		id, ok := index.overlapping(vrf, prefix, func(id uuid.UUID) bool { return skipped[id] })
+		require.Equal(t, len(expected) > 0, ok, prefix)
+		if ok {
+			require.True(t, expected[id], prefix)
+		}
+	}
+}
+
+// BenchmarkPrefixIndex_Lookup compares longest-prefix-match lookups in the trie to a linear search of the subnets.
+func BenchmarkPrefixIndex_Lookup(b *testing.B) {
+	for _, size := range []int{100, 10000, 100000} {
+		r := rand.New(rand.NewPCG(1, 2))
+		vlansByID := make(map[uuid.UUID]VLAN, size)
+		for range size {
+			vlan := VLAN{ID: uuid.New(), Subnet: randomPrefix(r)}
+			vlansByID[vlan.ID] = vlan
+		}
+		index := newPrefixIndex(vlansByID)
+		addrs := make([]netip.Addr, 1024)
+		for i := range addrs {
+			addrs[i] = randomAddr(r)
+		}
+
+		b.Run(fmt.Sprintf("trie/prefixes=%d", size), func(b *testing.B) {
+			for i := range b.N {
+				index.lookup("", addrs[i%len(addrs)])
+			}
+		})
+		b.Run(fmt.Sprintf("linear/prefixes=%d", size), func(b *testing.B) {
+			for i := range b.N {
+				addr := addrs[i%len(addrs)]
+				var match *VLAN
+				for _, vlan := range vlansByID {
+					if vlan.Subnet.Contains(addr) && (match == nil || vlan.Subnet.Bits() > match.Subnet.Bits()) {
+						match = &vlan
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPrefixIndex_Overlapping compares the overlap check of new subnets that overlap none of the subnets, as most
+// new subnets do, in the trie to a linear search of the subnets.
+func BenchmarkPrefixIndex_Overlapping(b *testing.B) {
+	for _, size := range []int{100, 10000, 100000} {
+		r := rand.New(rand.NewPCG(1, 2))
+		vlansByID := make(map[uuid.UUID]VLAN, size)
+		for range size {
+			vlan := VLAN{ID: uuid.New(), Subnet: randomPrefix(r)}
+			vlansByID[vlan.ID] = vlan
+		}
+		index := newPrefixIndex(vlansByID)
+		prefixes := make([]netip.Prefix, 1024)
+		for i := range prefixes {
+			addr := randomAddr(r).As4()
+			addr[0] = 11
+			prefixes[i] = netip.PrefixFrom(netip.AddrFrom4(addr), 24).Masked()
+		}
+		noneSkipped := func(uuid.UUID) bool { return false }
+
+		b.Run(fmt.Sprintf("trie/prefixes=%d", size), func(b *testing.B) {
+			for i := range b.N {
+				index.overlapping("", prefixes[i%len(prefixes)], noneSkipped)
+			}
+		})
+		b.Run(fmt.Sprintf("linear/prefixes=%d", size), func(b *testing.B) {
+			for i := range b.N {
+				prefix := prefixes[i%len(prefixes)]
+				for _, vlan := range vlansByID {
+					if vlan.Subnet.Overlaps(prefix) {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPrefixIndex_Update measures keeping the index up to date, by removing and adding VLANs again.
+func BenchmarkPrefixIndex_Update(b *testing.B) {
+	for _, size := range []int{100, 10000, 100000} {
+		b.Run(fmt.Sprintf("prefixes=%d", size), func(b *testing.B) {
+			r := rand.New(rand.NewPCG(1, 2))
+			vlans := make([]VLAN, size)
+			vlansByID := make(map[uuid.UUID]VLAN, size)
+			for i := range vlans {
+				vlans[i] = VLAN{ID: uuid.New(), Subnet: randomPrefix(r)}
+				vlansByID[vlans[i].ID] = vlans[i]
+			}
+			index := newPrefixIndex(vlansByID)
+			b.ResetTimer()
+			for i := range b.N {
+				vlan := vlans[i%len(vlans)]
+				index.remove(vlan)
+				index.add(vlan)
+			}
+		})
+	}
+}
+
+// randomPrefix returns a random prefix of 8 to 32 bits in 10.0.0.0/14, where prefixes often nest.
+func randomPrefix(r *rand.Rand) netip.Prefix {
+	return netip.PrefixFrom(randomAddr(r), 8+r.IntN(25)).Masked()
+}
+
+func randomAddr(r *rand.Rand) netip.Addr {
+	return netip.AddrFrom4([4]byte{10, byte(r.IntN(4)), byte(r.IntN(256)), byte(r.IntN(256))})
+}
@@ -2,9 +2,21 @@ package vlan
 
 import (
 	"fmt"
+	"maps"
 	"net/netip"
+	"regexp"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
+
+	"net-admin-api/internal/customfield"
+)
+
+// Range of valid VLAN IDs, 0 and 4095 are reserved by IEEE 802.1Q.
+const (
+	MinVID = 1
+	MaxVID = 4094
 )
 
 type VLAN struct {
@@ -14,12 +26,32 @@ type VLAN struct {
 	Subnet  netip.Prefix `json:"subnet"`
 	Gateway netip.Addr   `json:"gateway"`
 	Status  string       `json:"status"`
+	// VRF is the name of the VRF the VLAN is bound to, the global routing table if empty.
+	VRF  string   `json:"vrf,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// Fields are the values of custom fields, which the store checks against the custom field schema.
+	Fields map[string]any `json:"fields,omitempty"`
+
+	// ManagedBy names the component that owns the VLAN, e.g. the reconciler.
+	// Managed VLANs are read-only through the REST API.
+	ManagedBy string `json:"managedBy,omitempty"`
+	// Deleted is set on VLANs in the trash.
+	Deleted *Deletion `json:"deleted,omitempty"`
 }
 
+// Deletion records when and by whom a VLAN was moved to the trash.
+type Deletion struct {
+	At time.Time `json:"at"`
+	By string    `json:"by"`
+}
+
+// Validate returns the errors of the VLAN on its own. Custom field values are only checked for valid names and
+// types here: the store checks them against the schema while it holds its lock, since the schema can change
+// between validating a VLAN and saving it.
 func (v *VLAN) Validate() []string {
 	errors := make([]string, 0)
-	if v.VID < 1 || v.VID > 4094 {
-		errors = append(errors, fmt.Sprintf("invalid VLAN ID %v (expected range 1..4094)", v.VID))
+	if v.VID < MinVID || v.VID > MaxVID {
+		errors = append(errors, fmt.Sprintf("invalid VLAN ID %v (expected range %d..%d)", v.VID, MinVID, MaxVID))
 	}
 	if v.Name == "" {
 		errors = append(errors, "name must not be empty")
@@ -27,5 +59,45 @@ func (v *VLAN) Validate() []string {
 	if !v.Subnet.Contains(v.Gateway) {
 		errors = append(errors, fmt.Sprintf("gateway %s must belong to subnet %s", v.Gateway, v.Subnet))
 	}
+	for i, tag := range v.Tags {
+		if !tagPattern.MatchString(tag) {
+			errors = append(errors, fmt.Sprintf("invalid tag %q (expected up to 64 letters, digits, '_', '.', ':', '/' or '-')", tag))
+		} else if slices.Contains(v.Tags[:i], tag) {
+			errors = append(errors, fmt.Sprintf("duplicate tag %q", tag))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(v.Fields)) {
+		if !customfield.ValidName(name) {
+			errors = append(errors, fmt.Sprintf("invalid custom field name %q", name))
+		}
+		switch v.Fields[name].(type) {
+		case string, bool, float64, int, int64:
+		default:
+			errors = append(errors, fmt.Sprintf("custom field %s must be a string, number or boolean", name))
+		}
+	}
 	return errors
 }
+
+var tagPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.:/-]{0,63}$`)
+
+// HasTags reports whether the VLAN has all of the given tags.
+func (v *VLAN) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(v.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasFields reports whether the VLAN has the given custom field values, which are compared in the format of
+// customfield.Format.
+func (v *VLAN) HasFields(fields map[string]string) bool {
+	for name, value := range fields {
+		if fieldValue, ok := v.Fields[name]; !ok || customfield.Format(fieldValue) != value {
+			return false
+		}
+	}
+	return true
+}
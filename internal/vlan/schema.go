@@ -0,0 +1,106 @@
+package vlan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the store files written by this version of the server. A change to the
+// persisted VLANs increments it, and adds a migration from the previous version.
+const SchemaVersion = 2
+
+// ErrUnsupportedVersion is returned for store files written by a newer version of the server.
+var ErrUnsupportedVersion = errors.New("unsupported store version")
+
+// snapshot is the envelope of the VLANs in the snapshot file.
+type snapshot struct {
+	Version  int              `json:"version"`
+	Metadata snapshotMetadata `json:"metadata"`
+	VLANs    []VLAN           `json:"vlans"`
+	// Deleted VLANs, which are not counted in the metadata.
+	Trash []VLAN `json:"trash,omitempty"`
+}
+
+type snapshotMetadata struct {
+	WrittenAt time.Time `json:"writtenAt"`
+	// Number of VLANs, to detect truncated files.
+	Count int `json:"count"`
+	// Version the store was migrated from when it was loaded, if any.
+	MigratedFrom int `json:"migratedFrom,omitempty"`
+}
+
+// migration upgrades a store document from version-1 to version.
+type migration struct {
+	version     int
+	description string
+	migrate     func(doc json.RawMessage) (json.RawMessage, error)
+}
+
+// migrations upgrade store documents to SchemaVersion, in order of their versions.
+var migrations = []migration{
+	{version: 2, description: "wrap the VLAN array in a versioned envelope", migrate: wrapVLANArray},
+}
+
+// documentVersion returns the version of a store document. Documents before versioning are a bare array
+// of VLANs, they are version 1.
+func documentVersion(doc json.RawMessage) (int, error) {
+	doc = bytes.TrimSpace(doc)
+	if bytes.HasPrefix(doc, []byte("[")) || bytes.Equal(doc, []byte("null")) {
+		return 1, nil
+	}
+	header := struct {
+		Version int `json:"version"`
+	}{}
+	if err := json.Unmarshal(doc, &header); err != nil {
+		return 0, err
+	}
+	if header.Version < 1 {
+		return 0, errors.New("missing version")
+	}
+	return header.Version, nil
+}
+
+// migrate upgrades a store document from the given version to SchemaVersion.
+func migrate(version int, doc json.RawMessage) (json.RawMessage, error) {
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("%w %d, the latest supported version is %d", ErrUnsupportedVersion, version, SchemaVersion)
+	}
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		var err error
+		if doc, err = m.migrate(doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate to version %d (%s): %w", m.version, m.description, err)
+		}
+	}
+	return doc, nil
+}
+
+// versionDocument returns a store document of the given version that contains the VLANs, which are
+// encoded in that version. It lets the VLANs of log records reuse the migrations of snapshots.
+func versionDocument(version int, vlans []json.RawMessage) (json.RawMessage, error) {
+	if version == 1 {
+		return json.Marshal(vlans)
+	}
+	return json.Marshal(map[string]any{
+		"version":  version,
+		"metadata": map[string]any{"count": len(vlans)},
+		"vlans":    vlans,
+	})
+}
+
+func wrapVLANArray(doc json.RawMessage) (json.RawMessage, error) {
+	vlans := []json.RawMessage{}
+	if err := json.Unmarshal(doc, &vlans); err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{
+		"version":  2,
+		"metadata": map[string]any{"count": len(vlans)},
+		"vlans":    vlans,
+	})
+}
@@ -1,47 +1,183 @@
 package vlan
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"maps"
 	"os"
-	"path/filepath"
 	"slices"
-	"strings"
 	"sync"
 
 	"github.com/google/uuid"
+
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/encryption"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrOverlap is returned for VLANs whose subnet overlaps the subnet of another VLAN in the same VRF.
+	ErrOverlap = errors.New("overlapping subnet")
+	// ErrDuplicateVID is returned for VLANs whose VID another VLAN in the same VRF uses.
+	ErrDuplicateVID = errors.New("duplicate vid")
+	// ErrUnknownVRF is returned for VLANs bound to a VRF that does not exist.
+	ErrUnknownVRF = errors.New("unknown vrf")
+	// ErrVRFInUse is returned for VRFs that cannot be deleted because VLANs are bound to them.
+	ErrVRFInUse = errors.New("vrf in use")
+	// ErrInvalidFields is returned for VLANs whose custom fields do not match the custom field schema.
+	ErrInvalidFields = errors.New("invalid custom fields")
+	// ErrManaged is returned for changes to VLANs that another component owns, see ExpectUnmanaged.
+	ErrManaged = errors.New("vlan is read-only")
 )
 
-var ErrNotFound = errors.New("not found")
+type OperationType string
+
+const (
+	OperationCreate OperationType = "create"
+	OperationUpdate OperationType = "update"
+	OperationDelete OperationType = "delete"
+)
+
+// Operation is a single change applied by Store.Apply. Delete operations only use VLAN.ID, and By as the
+// actor who deleted the VLAN.
+type Operation struct {
+	Type OperationType
+	VLAN VLAN
+	By   string
+}
+
+// OperationError identifies the operation that caused Store.Apply to fail.
+type OperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
 
-// Store manages a JSON file to persist VLANs.
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// Store persists VLANs in a snapshot file and an append-only log of the changes since the snapshot.
 type Store struct {
 	path      string
 	vlansByID map[uuid.UUID]VLAN
+	// Deleted VLANs, which are hidden from List and Get until they are restored or purged.
+	trashByID map[uuid.UUID]VLAN
+	index     *prefixIndex
+	idsByVID  map[vidKey][]uuid.UUID
 	mu        sync.RWMutex
+
+	log          *os.File
+	logSize      int64
+	logRecords   int
+	compactAfter int
+	// Version the store files were migrated from on startup, if any.
+	migratedFrom int
+	keyring      *encryption.Keyring
+	// Whether plaintext files of an encrypted store are accepted, see WithPlaintextMigration.
+	migratePlaintext bool
+	vrfExists        func(name string) bool
+	fieldSchema      func() customfield.Schema
+	// Whether the store files are not encrypted with the current key, and are rewritten on startup.
+	reencrypt bool
+
+	subscribers      map[int]func(Event)
+	nextSubscriberID int
+	epoch            string
+	lastEventID      uint64
 }
 
-func NewStore(path string) (*Store, error) {
+type StoreOption func(*Store)
+
+// WithCompactAfter sets the number of log records after which the log is compacted into a new snapshot.
+func WithCompactAfter(records int) StoreOption {
+	return func(s *Store) {
+		s.compactAfter = records
+	}
+}
+
+// WriteOption changes the checks of the store methods that change existing VLANs.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	unmanaged bool
+}
+
+// ExpectUnmanaged makes changes to VLANs that another component owns, e.g. the reconciler, fail with ErrManaged.
+// The owner is checked with the store locked, so that the component cannot take over the VLAN in the meantime.
+func ExpectUnmanaged() WriteOption {
+	return func(o *writeOptions) {
+		o.unmanaged = true
+	}
+}
+
+// checkWrite checks that a write with the options may change an existing VLAN.
+func checkWrite(existing VLAN, opts []WriteOption) error {
+	o := writeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.unmanaged && existing.ManagedBy != "" {
+		return fmt.Errorf("%w, managed by %s", ErrManaged, existing.ManagedBy)
+	}
+	return nil
+}
+
+// NewStore opens the store with its snapshot at path and its log next to it, replaying the log after
+// the snapshot. Both files are created if they do not exist.
+func NewStore(path string, opts ...StoreOption) (*Store, error) {
 	store := &Store{
-		path: path,
+		path:         path,
+		compactAfter: DefaultCompactAfter,
+		epoch:        newEpoch(),
+	}
+	for _, opt := range opts {
+		opt(store)
 	}
 
 	// store file does not exist
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		store.vlansByID = make(map[uuid.UUID]VLAN, 0)
-		store.writeVLANs()
-		return store, nil
+		store.trashByID = make(map[uuid.UUID]VLAN, 0)
+		if err := store.writeSnapshot(); err != nil {
+			return nil, err
+		}
+	} else if err := store.readSnapshot(); err != nil {
+		return nil, err
 	}
 
-	// store file exists
-	if err := store.readVLANs(); err != nil {
+	if err := store.openLog(); err != nil {
 		return nil, err
 	}
+	store.buildIndexes()
+	// Migrated files are rewritten in the current version and with the current key right away
+	if store.logRecords >= store.compactAfter || store.migratedFrom > 0 || store.reencrypt {
+		if err := store.compact(); err != nil {
+			_ = store.log.Close()
+			return nil, err
+		}
+	}
+	if store.migratedFrom > 0 {
+		log.Printf("migrated %v from version %d to %d", path, store.migratedFrom, SchemaVersion)
+	}
+	if store.reencrypt {
+		log.Printf("encrypted %v with key %s", path, store.keyring.CurrentKeyID())
+	}
 	return store, nil
 }
 
+// Close closes the log, the store must not be used afterwards.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
+
 func (s *Store) List() ([]VLAN, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -64,81 +200,171 @@ func (s *Store) Get(id uuid.UUID) *VLAN {
 func (s *Store) Save(vlan VLAN) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	_, err := s.save(vlan)
+	return err
+}
 
+// save creates or replaces a VLAN and returns it as stored, the caller must hold the write lock.
+func (s *Store) save(vlan VLAN) (VLAN, error) {
+	// VLANs are only deleted by moving them to the trash
+	vlan = vlan.normalized()
+	vlan.Deleted = nil
+
+	eventType := EventCreated
+	var previous *VLAN
+	existing, exists := s.vlansByID[vlan.ID]
+	if exists {
+		eventType, previous = EventUpdated, &existing
+	}
+	if err := s.checkPut(vlan, previous, nil); err != nil {
+		return VLAN{}, err
+	}
+	if err := s.appendLog(putOp(vlan)); err != nil {
+		return VLAN{}, err
+	}
+	if exists {
+		s.removeFromIndexes(existing)
+	}
+	delete(s.trashByID, vlan.ID)
 	s.vlansByID[vlan.ID] = vlan
-	return s.writeVLANs()
+	s.addToIndexes(vlan)
+	s.publish(eventType, vlan)
+	s.compactIfNeeded()
+	return vlan, nil
 }
 
-func (s *Store) Update(vlan VLAN) error {
+func (s *Store) Update(vlan VLAN, opts ...WriteOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.vlansByID[vlan.ID]; !ok {
+	vlan = vlan.normalized()
+	vlan.Deleted = nil
+
+	existing, ok := s.vlansByID[vlan.ID]
+	if !ok {
 		return ErrNotFound
 	}
+	if err := checkWrite(existing, opts); err != nil {
+		return err
+	}
+	if err := s.checkPut(vlan, &existing, nil); err != nil {
+		return err
+	}
 
+	if err := s.appendLog(putOp(vlan)); err != nil {
+		return err
+	}
+	s.removeFromIndexes(existing)
 	s.vlansByID[vlan.ID] = vlan
-	return s.writeVLANs()
+	s.addToIndexes(vlan)
+	s.publish(EventUpdated, vlan)
+	s.compactIfNeeded()
+	return nil
 }
 
-func (s *Store) Delete(id uuid.UUID) error {
+// Delete moves a VLAN to the trash, recording the actor who deleted it. Deleted VLANs can be restored until
+// they are purged.
+func (s *Store) Delete(id uuid.UUID, by string, opts ...WriteOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.vlansByID[id]; !ok {
+	vlan, ok := s.vlansByID[id]
+	if !ok {
 		return ErrNotFound
 	}
-
-	delete(s.vlansByID, id)
-	return s.writeVLANs()
-}
-
-func (s *Store) readVLANs() error {
-	vlansFile, err := os.Open(s.path)
-	if err != nil {
-		return fmt.Errorf("failed to open %v: %w", s.path, err)
+	if err := checkWrite(vlan, opts); err != nil {
+		return err
 	}
-	defer vlansFile.Close()
 
-	vlans := []VLAN{}
-	if err := json.NewDecoder(vlansFile).Decode(&vlans); err != nil {
-		return fmt.Errorf("failed to decode %v: %w", s.path, err)
+	trashed := trash(vlan, by)
+	if err := s.appendLog(trashOp(trashed)); err != nil {
+		return err
 	}
-
-	vlansByID := make(map[uuid.UUID]VLAN, len(vlans))
-	for _, vlan := range vlans {
-		if errors := vlan.Validate(); len(errors) > 0 {
-			return fmt.Errorf("invalid VLAN in %s: %s", s.path, strings.Join(errors, ", "))
-		}
-		vlansByID[vlan.ID] = vlan
-	}
-	s.vlansByID = vlansByID
+	delete(s.vlansByID, id)
+	s.trashByID[id] = trashed
+	s.removeFromIndexes(vlan)
+	s.publish(EventDeleted, vlan)
+	s.compactIfNeeded()
 	return nil
 }
 
-func (s *Store) writeVLANs() error {
-	vlansFileDir := filepath.Dir(s.path)
-	vlansFileTmp, err := os.CreateTemp(vlansFileDir, "vlans-*.json")
-	if err != nil {
-		return fmt.Errorf("failed to create temp file in %v: %w", vlansFileDir, err)
-	}
-	defer func() {
-		_ = os.Remove(vlansFileTmp.Name())
-	}()
+// Apply applies all operations with a single log record, or none of them if any operation fails. The options apply
+// to every update and delete.
+func (s *Store) Apply(ops []Operation, opts ...WriteOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	vlans := slices.Collect(maps.Values(s.vlansByID))
-	if err := json.NewEncoder(vlansFileTmp).Encode(vlans); err != nil {
-		_ = vlansFileTmp.Close()
-		return fmt.Errorf("failed to encode %v: %w", s.path, err)
+	// The final state of the VLANs that the operations change, with VLANs moved to the trash marked as deleted.
+	// It is only applied to the store once all operations succeeded.
+	changed := map[uuid.UUID]VLAN{}
+	events := make([]Event, 0, len(ops))
+	logOps := make([]logOp, 0, len(ops))
+	for i, op := range ops {
+		existing, exists := s.vlansByID[op.VLAN.ID]
+		_, trashed := s.trashByID[op.VLAN.ID]
+		if vlan, ok := changed[op.VLAN.ID]; ok {
+			existing, exists, trashed = vlan, vlan.Deleted == nil, vlan.Deleted != nil
+		}
+		op.VLAN = op.VLAN.normalized()
+		op.VLAN.Deleted = nil
+		switch op.Type {
+		case OperationCreate:
+			if exists || trashed {
+				return &OperationError{Index: i, Err: ErrAlreadyExists}
+			}
+			if err := s.checkPut(op.VLAN, nil, changed); err != nil {
+				return &OperationError{Index: i, Err: err}
+			}
+			changed[op.VLAN.ID] = op.VLAN
+			events = append(events, Event{Type: EventCreated, VLAN: op.VLAN})
+			logOps = append(logOps, putOp(op.VLAN))
+		case OperationUpdate:
+			if !exists {
+				return &OperationError{Index: i, Err: ErrNotFound}
+			}
+			if err := checkWrite(existing, opts); err != nil {
+				return &OperationError{Index: i, Err: err}
+			}
+			if err := s.checkPut(op.VLAN, &existing, changed); err != nil {
+				return &OperationError{Index: i, Err: err}
+			}
+			changed[op.VLAN.ID] = op.VLAN
+			events = append(events, Event{Type: EventUpdated, VLAN: op.VLAN})
+			logOps = append(logOps, putOp(op.VLAN))
+		case OperationDelete:
+			if !exists {
+				return &OperationError{Index: i, Err: ErrNotFound}
+			}
+			if err := checkWrite(existing, opts); err != nil {
+				return &OperationError{Index: i, Err: err}
+			}
+			trashed := trash(existing, op.By)
+			changed[op.VLAN.ID] = trashed
+			events = append(events, Event{Type: EventDeleted, VLAN: existing})
+			logOps = append(logOps, trashOp(trashed))
+		default:
+			return &OperationError{Index: i, Err: fmt.Errorf("unknown operation %q", op.Type)}
+		}
 	}
 
-	if err := vlansFileTmp.Close(); err != nil {
-		return fmt.Errorf("failed to close temp file: %w", err)
+	if err := s.appendLog(logOps...); err != nil {
+		return err
 	}
-
-	if err := os.Rename(vlansFileTmp.Name(), s.path); err != nil {
-		return fmt.Errorf("failed to replace %v: %w", s.path, err)
+	for id, vlan := range changed {
+		if existing, ok := s.vlansByID[id]; ok {
+			s.removeFromIndexes(existing)
+			delete(s.vlansByID, id)
+		}
+		if vlan.Deleted != nil {
+			s.trashByID[id] = vlan
+		} else {
+			s.vlansByID[id] = vlan
+			s.addToIndexes(vlan)
+		}
 	}
-
+	for _, event := range events {
+		s.publish(event.Type, event.VLAN)
+	}
+	s.compactIfNeeded()
 	return nil
 }
@@ -0,0 +1,835 @@
+package vlan
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/customfield"
+	"net-admin-api/internal/encryption"
+)
+
+func TestStore_Recover(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path)
+
+	vlan10, vlan20, vlan30 := newVLAN(10), newVLAN(20), newVLAN(30)
+	require.NoError(t, store.Save(vlan10))
+	require.NoError(t, store.Save(vlan20))
+	vlan10.Name = "renamed"
+	require.NoError(t, store.Update(vlan10))
+	require.NoError(t, store.Delete(vlan20.ID, "alice"))
+	require.NoError(t, store.Apply([]Operation{
+		{Type: OperationCreate, VLAN: vlan30},
+		{Type: OperationDelete, VLAN: vlan30, By: "bob"},
+	}))
+	_, err := store.Restore(vlan20.ID)
+	require.NoError(t, err)
+	// Failed operations are not logged
+	require.ErrorIs(t, store.Update(newVLAN(40)), ErrNotFound)
+	require.ErrorIs(t, store.Apply([]Operation{{Type: OperationCreate, VLAN: vlan10}}), ErrAlreadyExists)
+	require.NoError(t, store.Close())
+
+	// Changes are only in the log until it is compacted
+	require.Empty(t, readSnapshot(t, path).VLANs)
+	require.Len(t, strings.Split(strings.TrimSpace(readFile(t, path+".log")), "\n"), 6)
+
+	reopened := openStore(t, path)
+	requireVLANs(t, reopened, vlan10, vlan20)
+	// Event IDs restart in a new epoch
+	require.NotEqual(t, store.Epoch(), reopened.Epoch())
+	trash := reopened.Trash()
+	require.Len(t, trash, 1)
+	require.Equal(t, vlan30.ID, trash[0].ID)
+	require.Equal(t, "bob", trash[0].Deleted.By)
+}
+
+func TestStore_Compaction(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path, WithCompactAfter(3))
+
+	vlans := []VLAN{}
+	for vid := range uint16(5) {
+		vlans = append(vlans, newVLAN(vid+1))
+		require.NoError(t, store.Save(vlans[vid]))
+	}
+	require.NoError(t, store.Close())
+
+	// The first three changes are in the snapshot, and the rest in the log
+	require.Len(t, readSnapshot(t, path).VLANs, 3)
+	require.Len(t, strings.Split(strings.TrimSpace(readFile(t, path+".log")), "\n"), 2)
+
+	reopened := openStore(t, path, WithCompactAfter(3))
+	requireVLANs(t, reopened, vlans...)
+
+	// A log that is long enough is compacted on startup
+	require.NoError(t, reopened.Close())
+	reopened = openStore(t, path, WithCompactAfter(2))
+	require.Empty(t, readFile(t, path+".log"))
+	requireVLANs(t, reopened, vlans...)
+}
+
+func TestStore_CrashDuringCompaction(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path)
+
+	vlan10, vlan20 := newVLAN(10), newVLAN(20)
+	require.NoError(t, store.Save(vlan10))
+	require.NoError(t, store.Save(vlan20))
+	require.NoError(t, store.Delete(vlan20.ID, "alice"))
+	require.NoError(t, store.Save(vlan20))
+	require.NoError(t, store.Delete(vlan10.ID, "alice"))
+
+	// The snapshot is written, but the log is not truncated
+	store.mu.Lock()
+	require.NoError(t, store.writeSnapshot())
+	store.mu.Unlock()
+	require.NoError(t, store.Close())
+
+	reopened := openStore(t, path)
+	requireVLANs(t, reopened, vlan20)
+}
+
+func TestStore_IncompleteRecord(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path)
+	vlan10 := newVLAN(10)
+	require.NoError(t, store.Save(vlan10))
+	require.NoError(t, store.Close())
+	empty, complete := readFile(t, path), readFile(t, path+".log")
+
+	tests := map[string]string{
+		"partial line":     `{"ops":[{"type":"put","id":"`,
+		"missing newline":  strings.TrimSpace(complete),
+		"zeroed tail":      "\x00\x00\x00\x00\n",
+		"zeroed tail only": "\x00\x00\x00\x00",
+	}
+	for name, tail := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vlans.json")
+			writeFile(t, path, empty)
+			writeFile(t, path+".log", complete+tail)
+
+			store := openStore(t, path)
+			requireVLANs(t, store, vlan10)
+			require.Equal(t, complete, readFile(t, path+".log"))
+
+			// New records are appended after the truncated one
+			vlan20 := newVLAN(20)
+			require.NoError(t, store.Save(vlan20))
+			require.NoError(t, store.Close())
+			requireVLANs(t, openStore(t, path), vlan10, vlan20)
+		})
+	}
+}
+
+func TestStore_CorruptLog(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path)
+	require.NoError(t, store.Save(newVLAN(10)))
+	require.NoError(t, store.Close())
+	empty, complete := readFile(t, path), readFile(t, path+".log")
+
+	tests := map[string]struct {
+		log   string
+		error string
+	}{
+		"corrupt record":    {log: "garbage\n" + complete, error: "vlans.json.log at offset 0"},
+		"invalid VLAN":      {log: `{"ops":[{"type":"put","vlan":{"vid":0}}]}` + "\n" + complete, error: "invalid VLAN ID 0"},
+		"unknown operation": {log: `{"ops":[{"type":"patch"}]}` + "\n" + complete, error: `unknown operation "patch"`},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vlans.json")
+			writeFile(t, path, empty)
+			writeFile(t, path+".log", test.log)
+			_, err := NewStore(path)
+			require.ErrorContains(t, err, test.error)
+		})
+	}
+}
+
+func TestStore_MigrateVersion1(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	vlan10, vlan20 := newVLAN(10), newVLAN(20)
+	data, err := json.Marshal([]VLAN{vlan10})
+	require.NoError(t, err)
+	writeFile(t, path, string(data))
+	// Log records before versioning
+	vlan10.Name = "renamed"
+	data, err = json.Marshal(map[string]any{"ops": []any{
+		map[string]any{"type": "put", "id": vlan10.ID, "vlan": vlan10},
+		map[string]any{"type": "put", "id": vlan20.ID, "vlan": vlan20},
+	}})
+	require.NoError(t, err)
+	writeFile(t, path+".log", string(data)+"\n")
+
+	store := openStore(t, path)
+	requireVLANs(t, store, vlan10, vlan20)
+
+	// The store is rewritten in the current version
+	snapshot := readSnapshot(t, path)
+	require.Equal(t, SchemaVersion, snapshot.Version)
+	require.Equal(t, 2, snapshot.Metadata.Count)
+	require.Equal(t, 1, snapshot.Metadata.MigratedFrom)
+	require.ElementsMatch(t, []VLAN{vlan10, vlan20}, snapshot.VLANs)
+	require.Empty(t, readFile(t, path+".log"))
+
+	require.NoError(t, store.Close())
+	requireVLANs(t, openStore(t, path), vlan10, vlan20)
+}
+
+func TestStore_UnsupportedVersion(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		snapshot    string
+		log         string
+		error       string
+		unsupported bool
+	}{
+		"newer snapshot": {
+			snapshot:    `{"version": 99, "metadata": {"count": 0}, "vlans": [], "vrfs": []}`,
+			error:       "unsupported store version 99, the latest supported version is 2",
+			unsupported: true,
+		},
+		"newer log record": {
+			snapshot:    `{"version": 2, "metadata": {"count": 0}, "vlans": []}`,
+			log:         `{"version": 3, "ops": []}` + "\n",
+			error:       "vlans.json.log at offset 0: unsupported store version 3",
+			unsupported: true,
+		},
+		"missing version": {
+			snapshot: `{"metadata": {"count": 0}, "vlans": []}`,
+			error:    "missing version",
+		},
+		"truncated snapshot": {
+			snapshot: `{"version": 2, "metadata": {"count": 2}, "vlans": []}`,
+			error:    "expected 2 VLANs, found 0",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "vlans.json")
+			writeFile(t, path, test.snapshot)
+			writeFile(t, path+".log", test.log)
+
+			_, err := NewStore(path)
+			require.ErrorContains(t, err, test.error)
+			require.Equal(t, test.unsupported, errors.Is(err, ErrUnsupportedVersion))
+			// Files of newer versions are left untouched
+			require.Equal(t, test.snapshot, readFile(t, path))
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+	// Every version has a migration from the previous one
+	for i, m := range migrations {
+		require.Equal(t, i+2, m.version, m.description)
+	}
+	require.Equal(t, SchemaVersion, migrations[len(migrations)-1].version)
+}
+
+func TestStore_Encryption(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	oldKey, newKey := newKey(t), newKey(t)
+
+	// A plaintext store is rejected once encryption is enabled, unless it is migrated
+	store := openStore(t, path)
+	vlan10, vlan20 := newVLAN(10), newVLAN(20)
+	require.NoError(t, store.Save(vlan10))
+	require.NoError(t, store.Close())
+	_, err := NewStore(path, WithEncryption(encryption.NewKeyring(oldKey)))
+	require.ErrorIs(t, err, ErrNotEncrypted)
+	require.ErrorContains(t, err, "failed to decrypt "+path+": not encrypted")
+	store = openStore(t, path, WithEncryption(encryption.NewKeyring(oldKey)), WithPlaintextMigration())
+	require.Equal(t, oldKey.ID, readSealed(t, readFile(t, path)).KeyID)
+	require.Empty(t, readFile(t, path+".log"))
+
+	require.NoError(t, store.Save(vlan20))
+	require.NoError(t, store.Close())
+	for _, file := range []string{path, path + ".log"} {
+		require.NotContains(t, readFile(t, file), vlan10.Name)
+		require.Equal(t, oldKey.ID, readSealed(t, readFile(t, file)).KeyID)
+	}
+	requireVLANs(t, openStore(t, path, WithEncryption(encryption.NewKeyring(oldKey))), vlan10, vlan20)
+
+	// Without the key the store cannot be opened
+	_, err = NewStore(path)
+	require.ErrorContains(t, err, "vlans.json: encrypted, but no encryption key is configured")
+	_, err = NewStore(path, WithEncryption(encryption.NewKeyring(newKey)))
+	require.ErrorIs(t, err, encryption.ErrUnknownKey)
+	require.ErrorContains(t, err, "failed to decrypt "+path+": unknown encryption key "+oldKey.ID)
+
+	// After a key rotation both files are re-encrypted with the new key
+	store = openStore(t, path, WithEncryption(encryption.NewKeyring(newKey, oldKey)))
+	requireVLANs(t, store, vlan10, vlan20)
+	require.Equal(t, newKey.ID, readSealed(t, readFile(t, path)).KeyID)
+	require.Empty(t, readFile(t, path+".log"))
+	require.NoError(t, store.Close())
+	requireVLANs(t, openStore(t, path, WithEncryption(encryption.NewKeyring(newKey))), vlan10, vlan20)
+}
+
+func TestStore_EncryptedLog(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	key := newKey(t)
+	store := openStore(t, path, WithEncryption(encryption.NewKeyring(key)))
+	vlan10 := newVLAN(10)
+	require.NoError(t, store.Save(vlan10))
+	require.NoError(t, store.Close())
+	complete := readFile(t, path+".log")
+
+	// An incomplete encrypted record is truncated
+	writeFile(t, path+".log", complete+complete[:len(complete)/2])
+	store = openStore(t, path, WithEncryption(encryption.NewKeyring(key)))
+	requireVLANs(t, store, vlan10)
+	require.Equal(t, complete, readFile(t, path+".log"))
+	require.NoError(t, store.Close())
+
+	// A complete record that fails authentication is not
+	sealed := readSealed(t, complete)
+	sealed.Ciphertext[0] ^= 1
+	tampered, err := json.Marshal(sealed)
+	require.NoError(t, err)
+	writeFile(t, path+".log", complete+string(tampered)+"\n")
+	_, err = NewStore(path, WithEncryption(encryption.NewKeyring(key)))
+	require.ErrorIs(t, err, encryption.ErrDecrypt)
+	require.ErrorContains(t, err, "vlans.json.log at offset "+fmt.Sprint(len(complete)))
+
+	// Neither is a plaintext record slipped into the log
+	plaintext, err := json.Marshal(logRecord{Version: SchemaVersion, Ops: []logOp{putOp(newVLAN(20))}})
+	require.NoError(t, err)
+	writeFile(t, path+".log", complete+string(plaintext)+"\n")
+	_, err = NewStore(path, WithEncryption(encryption.NewKeyring(key)))
+	require.ErrorIs(t, err, ErrNotEncrypted)
+	require.ErrorContains(t, err, "vlans.json.log at offset "+fmt.Sprint(len(complete)))
+}
+
+func TestStore_VRFs(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path, WithVRFs(func(name string) bool { return name == "red" || name == "blue" }))
+
+	global := newVLAN(10)
+	red, blue := newVLANInSubnet(11, 10), newVLANInSubnet(12, 10)
+	red.VRF, blue.VRF = "red", "blue"
+	require.NoError(t, store.Save(global))
+	require.NoError(t, store.Save(red))
+	require.NoError(t, store.Apply([]Operation{{Type: OperationCreate, VLAN: blue}}))
+
+	// Subnets may only overlap across VRFs
+	overlapping := newVLANInSubnet(13, 10)
+	overlapping.Subnet = netip.MustParsePrefix("10.0.0.0/16")
+	overlapping.VRF = "red"
+	require.ErrorIs(t, store.Save(overlapping), ErrOverlap)
+	require.ErrorContains(t, store.Save(overlapping), "10.0.0.0/16 overlaps 10.0.10.0/24 of vlan vlan-11")
+	err := store.Apply([]Operation{{Type: OperationCreate, VLAN: newVLAN(20)}, {Type: OperationCreate, VLAN: overlapping}})
+	require.ErrorIs(t, err, ErrOverlap)
+	require.Equal(t, 1, err.(*OperationError).Index)
+	moved := global
+	moved.VRF = "red"
+	require.ErrorIs(t, store.Update(moved), ErrOverlap)
+
+	// Operations are checked against the VLANs left by the previous operations
+	require.NoError(t, store.Apply([]Operation{
+		{Type: OperationDelete, VLAN: red},
+		{Type: OperationCreate, VLAN: overlapping},
+	}))
+	nested, sibling := newVLANInSubnet(14, 10), newVLANInSubnet(15, 10)
+	nested.VRF, sibling.VRF = "blue", "blue"
+	nested.Subnet = netip.MustParsePrefix("10.0.10.128/25")
+	err = store.Apply([]Operation{
+		{Type: OperationDelete, VLAN: blue},
+		{Type: OperationCreate, VLAN: sibling},
+		{Type: OperationCreate, VLAN: nested},
+	})
+	require.ErrorIs(t, err, ErrOverlap)
+	require.Equal(t, 2, err.(*OperationError).Index)
+	require.ErrorContains(t, err, "10.0.10.128/25 overlaps 10.0.10.0/24 of vlan vlan-15")
+
+	unknown := newVLAN(20)
+	unknown.VRF = "green"
+	require.ErrorIs(t, store.Save(unknown), ErrUnknownVRF)
+	requireVLANs(t, store, global, overlapping, blue)
+
+	// VRFs are only deleted once no VLAN is bound to them, including deleted VLANs
+	deleted := []string{}
+	deleteVRF := func(name string) error {
+		return store.DeleteVRF(name, func() error {
+			deleted = append(deleted, name)
+			return nil
+		})
+	}
+	require.ErrorContains(t, deleteVRF("blue"), "vrf in use: vrf blue is used by vlan vlan-12")
+	require.ErrorIs(t, deleteVRF("red"), ErrVRFInUse)
+	require.NoError(t, store.Delete(overlapping.ID, "alice"))
+	require.ErrorContains(t, deleteVRF("red"), "vrf red is used by deleted vlan")
+	_, err = store.Purge(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, deleteVRF("red"))
+	require.Equal(t, []string{"red"}, deleted)
+
+	// Older files may contain overlapping subnets, whose VLANs can still be changed as long as their subnet stays
+	require.NoError(t, store.Close())
+	writeFile(t, path+".log", "")
+	nested.VRF, nested.Gateway = "", netip.MustParseAddr("10.0.10.129")
+	require.NoError(t, writeVLANs(path, map[uuid.UUID]VLAN{global.ID: global, nested.ID: nested}))
+	store = openStore(t, path)
+	require.ErrorIs(t, CheckConflicts(listVLANs(t, store)), ErrOverlap)
+	nested.Tags = []string{"legacy"}
+	require.NoError(t, store.Update(nested))
+	nested.Subnet, nested.Gateway = netip.MustParsePrefix("10.0.10.192/26"), netip.MustParseAddr("10.0.10.193")
+	require.ErrorIs(t, store.Update(nested), ErrOverlap)
+	nested.Subnet, nested.Gateway = netip.MustParsePrefix("10.0.11.0/24"), netip.MustParseAddr("10.0.11.1")
+	require.NoError(t, store.Update(nested))
+	require.NoError(t, CheckConflicts(listVLANs(t, store)))
+}
+
+func TestCheckConflicts(t *testing.T) {
+	t.Parallel()
+	users, guests := newVLAN(10), newVLAN(11)
+	require.NoError(t, CheckConflicts([]VLAN{users, guests}))
+
+	duplicate := newVLANInSubnet(10, 12)
+	require.ErrorContains(t, CheckConflicts([]VLAN{users, guests, duplicate}), "duplicate vid: vlans vlan-10 and vlan-10 have vid 10")
+	duplicate.VRF = "red"
+	require.NoError(t, CheckConflicts([]VLAN{users, guests, duplicate}))
+
+	// Overlaps are found between VLANs that are not next to each other in address order
+	wide, between := newVLAN(20), newVLANInSubnet(21, 9)
+	wide.Subnet = netip.MustParsePrefix("10.0.0.0/16")
+	between.Subnet = netip.MustParsePrefix("9.0.0.0/8")
+	err := CheckConflicts([]VLAN{between, users, wide})
+	require.ErrorIs(t, err, ErrOverlap)
+	require.ErrorContains(t, err, "10.0.0.0/16 of vlan vlan-20 overlaps 10.0.10.0/24 of vlan vlan-10")
+	wide.VRF = "red"
+	require.NoError(t, CheckConflicts([]VLAN{between, users, wide}))
+}
+
+func TestStore_VIDs(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path)
+	users := newVLAN(10)
+	require.NoError(t, store.Save(users))
+
+	// VIDs are unique per VRF
+	duplicate := newVLANInSubnet(10, 11)
+	require.ErrorIs(t, store.Save(duplicate), ErrDuplicateVID)
+	require.ErrorContains(t, store.Save(duplicate), "vid 10 is used by vlan vlan-10")
+	guests := newVLAN(11)
+	require.NoError(t, store.Save(guests))
+	moved := guests
+	moved.VID = 10
+	require.ErrorIs(t, store.Update(moved), ErrDuplicateVID)
+	err := store.Apply([]Operation{{Type: OperationCreate, VLAN: newVLAN(20)}, {Type: OperationCreate, VLAN: newVLANInSubnet(20, 21)}})
+	require.ErrorIs(t, err, ErrDuplicateVID)
+	require.Equal(t, 1, err.(*OperationError).Index)
+	duplicate.VRF = "red"
+	require.NoError(t, store.Save(duplicate))
+
+	// VIDs are free again once their VLAN moved to another VID or was deleted
+	users.VID = 12
+	require.NoError(t, store.Apply([]Operation{{Type: OperationUpdate, VLAN: users}, {Type: OperationUpdate, VLAN: moved}}))
+	require.Equal(t, []VLAN{moved}, store.GetByVID("", 10))
+	require.Equal(t, []VLAN{duplicate}, store.GetByVID("red", 10))
+	require.Empty(t, store.GetByVID("blue", 10))
+	require.NoError(t, store.Delete(users.ID, "alice"))
+	require.NoError(t, store.Save(newVLANInSubnet(12, 13)))
+	// Deleted VLANs cannot be restored while another VLAN uses their VID
+	_, err = store.Restore(users.ID)
+	require.ErrorIs(t, err, ErrDuplicateVID)
+
+	// Older files may contain duplicate VIDs, which are not hidden
+	require.NoError(t, store.Close())
+	writeFile(t, path+".log", "")
+	users.VID = guests.VID
+	require.NoError(t, writeVLANs(path, map[uuid.UUID]VLAN{users.ID: users, guests.ID: guests}))
+	store = openStore(t, path)
+	require.ElementsMatch(t, []VLAN{users, guests}, store.GetByVID("", guests.VID))
+
+	// Their VLANs can still be changed as long as their VID stays the same
+	users.Name = "staff"
+	require.NoError(t, store.Update(users))
+	require.NoError(t, store.Apply([]Operation{{Type: OperationUpdate, VLAN: users}}))
+	users.VID = 14
+	require.NoError(t, store.Update(users))
+	users.VID = guests.VID
+	require.ErrorIs(t, store.Update(users), ErrDuplicateVID)
+}
+
+func TestStore_SaveByVID(t *testing.T) {
+	t.Parallel()
+	store := openStore(t, filepath.Join(t.TempDir(), "vlans.json"))
+
+	// The first save creates the VLAN, later saves replace it and keep its ID
+	users := newVLAN(10)
+	saved, created, err := store.SaveByVID(users, func(existing *VLAN) error {
+		require.Nil(t, existing)
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, created)
+	require.NotEqual(t, users.ID, saved.ID)
+	users.ID = saved.ID
+	require.Equal(t, users, saved)
+	renamed := newVLAN(10)
+	renamed.Name = "staff"
+	saved, created, err = store.SaveByVID(renamed, func(existing *VLAN) error {
+		require.Equal(t, users, *existing)
+		return nil
+	})
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, users.ID, saved.ID)
+	requireVLANs(t, store, saved)
+
+	// The check can reject the change
+	_, _, err = store.SaveByVID(newVLAN(10), func(*VLAN) error { return ErrNotFound })
+	require.ErrorIs(t, err, ErrNotFound)
+	requireVLANs(t, store, saved)
+
+	// Concurrent saves of a new VID create one VLAN
+	var wg sync.WaitGroup
+	var createdCount atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, created, err := store.SaveByVID(newVLAN(20), nil)
+			if err != nil {
+				t.Error(err)
+			} else if created {
+				createdCount.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), createdCount.Load())
+	require.Len(t, store.GetByVID("", 20), 1)
+}
+
+func TestStore_ExpectUnmanaged(t *testing.T) {
+	t.Parallel()
+	store := openStore(t, filepath.Join(t.TempDir(), "vlans.json"))
+	managed, unmanaged := newVLAN(10), newVLAN(11)
+	managed.ManagedBy = "reconciler"
+	require.NoError(t, store.Save(managed))
+	require.NoError(t, store.Save(unmanaged))
+
+	// Managed VLANs can only be changed without the option, by their owner
+	renamed := managed
+	renamed.Name, renamed.ManagedBy = "staff", ""
+	require.ErrorIs(t, store.Update(renamed, ExpectUnmanaged()), ErrManaged)
+	require.ErrorContains(t, store.Delete(managed.ID, "alice", ExpectUnmanaged()), "vlan is read-only, managed by reconciler")
+	err := store.Apply([]Operation{
+		{Type: OperationUpdate, VLAN: unmanaged},
+		{Type: OperationDelete, VLAN: managed},
+	}, ExpectUnmanaged())
+	require.ErrorIs(t, err, ErrManaged)
+	require.Equal(t, 1, err.(*OperationError).Index)
+	requireVLANs(t, store, managed, unmanaged)
+
+	unmanaged.Name = "guests"
+	require.NoError(t, store.Update(unmanaged, ExpectUnmanaged()))
+	require.NoError(t, store.Delete(unmanaged.ID, "alice", ExpectUnmanaged()))
+	managed.Name = "staff"
+	require.NoError(t, store.Update(managed))
+	requireVLANs(t, store, managed)
+
+	// Deleted managed VLANs are only restored by their owner too
+	require.NoError(t, store.Delete(managed.ID, "reconciler"))
+	_, err = store.Restore(managed.ID, ExpectUnmanaged())
+	require.ErrorIs(t, err, ErrManaged)
+	_, err = store.Restore(unmanaged.ID, ExpectUnmanaged())
+	require.NoError(t, err)
+	_, err = store.Restore(managed.ID)
+	require.NoError(t, err)
+	requireVLANs(t, store, managed, unmanaged)
+}
+
+func TestStore_Lookup(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path)
+	addr := netip.MustParseAddr("10.0.10.20")
+
+	global, red, v6 := newVLAN(10), newVLAN(10), newVLAN(20)
+	red.VRF = "red"
+	v6.Subnet, v6.Gateway = netip.MustParsePrefix("2001:db8:20::/64"), netip.MustParseAddr("2001:db8:20::1")
+	require.NoError(t, store.Save(global))
+	require.NoError(t, store.Save(red))
+	require.NoError(t, store.Save(v6))
+
+	require.Equal(t, []VLAN{global}, store.Lookup("", addr))
+	require.Equal(t, []VLAN{red}, store.Lookup("red", addr))
+	require.Empty(t, store.Lookup("blue", addr))
+	require.Empty(t, store.Lookup("", netip.MustParseAddr("10.0.11.1")))
+	require.Equal(t, []VLAN{global, red}, store.LookupAll(addr))
+	require.Equal(t, []VLAN{v6}, store.LookupAll(netip.MustParseAddr("2001:db8:20::abcd")))
+
+	// The index follows every change of the store
+	red.Subnet, red.Gateway = netip.MustParsePrefix("10.0.0.0/16"), netip.MustParseAddr("10.0.0.1")
+	require.NoError(t, store.Update(red))
+	require.Equal(t, []VLAN{red}, store.Lookup("red", netip.MustParseAddr("10.0.11.1")))
+	moved := global
+	moved.VRF = "blue"
+	require.NoError(t, store.Apply([]Operation{
+		{Type: OperationDelete, VLAN: red},
+		{Type: OperationUpdate, VLAN: moved},
+	}))
+	require.Empty(t, store.Lookup("", addr))
+	require.Equal(t, []VLAN{moved}, store.LookupAll(addr))
+	require.NoError(t, store.Delete(moved.ID, "alice"))
+	require.Empty(t, store.LookupAll(addr))
+
+	// The index is rebuilt on startup, also from older files with duplicate subnets
+	require.NoError(t, store.Close())
+	duplicate := newVLAN(11)
+	duplicate.Subnet, duplicate.Gateway = global.Subnet, global.Gateway
+	require.NoError(t, writeVLANs(path, map[uuid.UUID]VLAN{global.ID: global, duplicate.ID: duplicate}))
+	writeFile(t, path+".log", "")
+	require.ElementsMatch(t, []VLAN{global, duplicate}, openStore(t, path).Lookup("", addr))
+}
+
+// BenchmarkStore_Update measures a write to the log, including the amortized cost of compaction.
+func BenchmarkStore_Update(b *testing.B) {
+	for _, size := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("vlans=%d", size), func(b *testing.B) {
+			store, vlans := newBenchmarkStore(b, size)
+			b.ResetTimer()
+			for i := range b.N {
+				vlan := vlans[i%len(vlans)]
+				vlan.Name = fmt.Sprintf("vlan-%d", i)
+				if err := store.Update(vlan); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWriteSnapshot measures rewriting all VLANs durably, as compaction does.
+func BenchmarkWriteSnapshot(b *testing.B) {
+	for _, size := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("vlans=%d", size), func(b *testing.B) {
+			store, _ := newBenchmarkStore(b, size)
+			b.ResetTimer()
+			for range b.N {
+				if err := store.writeSnapshot(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWriteVLANs measures rewriting all VLANs without fsync, as every write did before the log.
+func BenchmarkWriteVLANs(b *testing.B) {
+	for _, size := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("vlans=%d", size), func(b *testing.B) {
+			store, _ := newBenchmarkStore(b, size)
+			b.ResetTimer()
+			for range b.N {
+				if err := writeVLANs(store.path, store.vlansByID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// writeVLANs is the previous implementation of the store, which rewrote the file on every write.
+func writeVLANs(path string, vlansByID map[uuid.UUID]VLAN) error {
+	vlansFileTmp, err := os.CreateTemp(filepath.Dir(path), "vlans-*.json")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(vlansFileTmp.Name())
+	}()
+
+	vlans := slices.Collect(maps.Values(vlansByID))
+	if err := json.NewEncoder(vlansFileTmp).Encode(vlans); err != nil {
+		_ = vlansFileTmp.Close()
+		return err
+	}
+	if err := vlansFileTmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(vlansFileTmp.Name(), path)
+}
+
+func newBenchmarkStore(b *testing.B, size int) (*Store, []VLAN) {
+	b.Helper()
+	store, err := NewStore(filepath.Join(b.TempDir(), "vlans.json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = store.Close() })
+
+	ops := make([]Operation, size)
+	vlans := make([]VLAN, size)
+	for i := range size {
+		vlans[i] = newVLANInSubnet(uint16(i%4094+1), uint16(i))
+		// VIDs are unique per VRF
+		if i >= 4094 {
+			vlans[i].VRF = fmt.Sprintf("vrf-%d", i/4094)
+		}
+		ops[i] = Operation{Type: OperationCreate, VLAN: vlans[i]}
+	}
+	if err := store.Apply(ops); err != nil {
+		b.Fatal(err)
+	}
+	return store, vlans
+}
+
+func TestStore_Fields(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	schema := customfield.Schema{Fields: []customfield.Field{
+		{Name: "site", Type: customfield.TypeEnum, Values: []string{"ber1", "fra1"}, Required: true},
+		{Name: "rack", Type: customfield.TypeInt},
+	}}
+	store := openStore(t, path, WithFieldSchema(func() customfield.Schema { return schema }))
+
+	// Values are stored the way they are decoded from JSON
+	rack := newVLAN(10)
+	rack.Tags = []string{"prod", "site:ber1"}
+	rack.Fields = map[string]any{"site": "ber1", "rack": 12}
+	require.NoError(t, store.Save(rack))
+	rack.Fields["rack"] = float64(12)
+	requireVLANs(t, store, rack)
+
+	missing := newVLAN(11)
+	require.ErrorIs(t, store.Save(missing), ErrInvalidFields)
+	require.ErrorContains(t, store.Save(missing), "custom field site is required")
+	invalid := rack
+	invalid.Fields = map[string]any{"site": "muc1", "rack": 12}
+	require.ErrorIs(t, store.Update(invalid), ErrInvalidFields)
+	err := store.Apply([]Operation{{Type: OperationCreate, VLAN: missing}})
+	require.ErrorIs(t, err, ErrInvalidFields)
+	require.Equal(t, 0, err.(*OperationError).Index)
+
+	// The schema only changes if the stored VLANs match it
+	required := customfield.Schema{Fields: []customfield.Field{
+		{Name: "site", Type: customfield.TypeEnum, Values: []string{"ber1", "fra1"}, Required: true},
+		{Name: "rack", Type: customfield.TypeInt, Required: true},
+		{Name: "row", Type: customfield.TypeInt, Required: true},
+	}}
+	save := func() error {
+		schema = required
+		return nil
+	}
+	err = store.ChangeFieldSchema(required, save)
+	require.ErrorIs(t, err, ErrInvalidFields)
+	require.ErrorContains(t, err, "vlan vlan-10 does not match the custom fields: custom field row is required")
+	required.Fields = required.Fields[:2]
+	require.NoError(t, store.ChangeFieldSchema(required, save))
+	require.Equal(t, required, schema)
+
+	// Stored VLANs compare equal after a restart
+	require.NoError(t, store.Close())
+	requireVLANs(t, openStore(t, path), rack)
+
+	// Without a schema, VLANs cannot have custom fields
+	require.ErrorContains(t, openStore(t, filepath.Join(t.TempDir(), "vlans.json")).Save(rack), "unknown custom field")
+}
+
+func openStore(t *testing.T, path string, opts ...StoreOption) *Store {
+	t.Helper()
+	store, err := NewStore(path, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func requireVLANs(t *testing.T, store *Store, expected ...VLAN) {
+	t.Helper()
+	require.ElementsMatch(t, expected, listVLANs(t, store))
+}
+
+func listVLANs(t *testing.T, store *Store) []VLAN {
+	t.Helper()
+	vlans, err := store.List()
+	require.NoError(t, err)
+	return vlans
+}
+
+// newVLAN returns a VLAN with the subnet 10.<vid/256>.<vid%256>.0/24, which does not overlap other VLANs.
+func newVLAN(vid uint16) VLAN {
+	return newVLANInSubnet(vid, vid)
+}
+
+func newVLANInSubnet(vid, subnet uint16) VLAN {
+	addr := netip.AddrFrom4([4]byte{10, byte(subnet >> 8), byte(subnet), 0})
+	return VLAN{
+		ID:      uuid.New(),
+		VID:     vid,
+		Name:    fmt.Sprintf("vlan-%d", vid),
+		Subnet:  netip.PrefixFrom(addr, 24),
+		Gateway: addr.Next(),
+		Status:  "enabled",
+	}
+}
+
+func newKey(t *testing.T) encryption.Key {
+	t.Helper()
+	key := make([]byte, encryption.KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	parsed, err := encryption.ParseKey(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	return parsed
+}
+
+func readSealed(t *testing.T, data string) encryption.Sealed {
+	t.Helper()
+	sealed := encryption.Sealed{}
+	require.NoError(t, json.Unmarshal([]byte(data), &sealed))
+	return sealed
+}
+
+func readSnapshot(t *testing.T, path string) snapshot {
+	t.Helper()
+	snapshot := snapshot{}
+	require.NoError(t, json.Unmarshal([]byte(readFile(t, path)), &snapshot))
+	return snapshot
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
@@ -0,0 +1,103 @@
+package vlan
+
+import (
+	"cmp"
+	"context"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTrashRetention is the default time after which deleted VLANs are purged.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// trash returns a deleted copy of a VLAN.
+func trash(vlan VLAN, by string) VLAN {
+	vlan.Deleted = &Deletion{At: time.Now().UTC(), By: by}
+	return vlan
+}
+
+// Trash returns the deleted VLANs, the most recently deleted first.
+func (s *Store) Trash() []VLAN {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vlans := slices.Collect(maps.Values(s.trashByID))
+	slices.SortFunc(vlans, func(a, b VLAN) int {
+		return cmp.Or(b.Deleted.At.Compare(a.Deleted.At), cmp.Compare(a.VID, b.VID))
+	})
+	return vlans
+}
+
+// Restore moves a deleted VLAN back from the trash. It fails the same way as Save if the VLAN no longer fits
+// in, e.g. because another VLAN took its subnet in the meantime.
+func (s *Store) Restore(id uuid.UUID, opts ...WriteOption) (VLAN, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vlan, ok := s.trashByID[id]
+	if !ok {
+		return VLAN{}, ErrNotFound
+	}
+	if err := checkWrite(vlan, opts); err != nil {
+		return VLAN{}, err
+	}
+	vlan.Deleted = nil
+	if err := s.checkPut(vlan, nil, nil); err != nil {
+		return VLAN{}, err
+	}
+
+	if err := s.appendLog(putOp(vlan)); err != nil {
+		return VLAN{}, err
+	}
+	delete(s.trashByID, id)
+	s.vlansByID[id] = vlan
+	s.addToIndexes(vlan)
+	s.publish(EventCreated, vlan)
+	s.compactIfNeeded()
+	return vlan, nil
+}
+
+// Purge removes the VLANs deleted before the given time for good, and returns how many it removed.
+func (s *Store) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ops := []logOp{}
+	for id, vlan := range s.trashByID {
+		if vlan.Deleted.At.Before(before) {
+			ops = append(ops, deleteOp(id))
+		}
+	}
+	if err := s.appendLog(ops...); err != nil {
+		return 0, err
+	}
+	for _, op := range ops {
+		delete(s.trashByID, op.ID)
+	}
+	s.compactIfNeeded()
+	return len(ops), nil
+}
+
+// RunPurge purges VLANs that have been in the trash for longer than retention until the context is done.
+func (s *Store) RunPurge(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(min(retention, time.Hour))
+	defer ticker.Stop()
+	for {
+		purged, err := s.Purge(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("failed to purge deleted VLANs: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted VLANs", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
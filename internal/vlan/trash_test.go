@@ -0,0 +1,75 @@
+package vlan
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Trash(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "vlans.json")
+	store := openStore(t, path, WithCompactAfter(3))
+	events := []Event{}
+	store.Subscribe(func(event Event) { events = append(events, event) })
+
+	vlan10, vlan20 := newVLAN(10), newVLAN(20)
+	require.NoError(t, store.Save(vlan10))
+	require.NoError(t, store.Save(vlan20))
+	require.NoError(t, store.Delete(vlan10.ID, "alice"))
+	require.ErrorIs(t, store.Delete(vlan10.ID, "alice"), ErrNotFound)
+
+	// Deleted VLANs are only in the trash
+	requireVLANs(t, store, vlan20)
+	require.Nil(t, store.Get(vlan10.ID))
+	trash := store.Trash()
+	require.Len(t, trash, 1)
+	require.Equal(t, "alice", trash[0].Deleted.By)
+	require.WithinDuration(t, time.Now(), trash[0].Deleted.At, time.Minute)
+	require.ErrorIs(t, store.Apply([]Operation{{Type: OperationCreate, VLAN: vlan10}}), ErrAlreadyExists)
+
+	// The trash survives compaction and restarts
+	require.NoError(t, store.Close())
+	require.Len(t, readSnapshot(t, path).Trash, 1)
+	require.Equal(t, []EventType{EventCreated, EventCreated, EventDeleted}, eventTypes(events))
+	store = openStore(t, path)
+	require.Equal(t, trash, store.Trash())
+	events = []Event{}
+	store.Subscribe(func(event Event) { events = append(events, event) })
+
+	// A VLAN is restored only if it still fits in
+	taken := newVLANInSubnet(11, 10)
+	require.NoError(t, store.Save(taken))
+	_, err := store.Restore(vlan10.ID)
+	require.ErrorIs(t, err, ErrOverlap)
+	require.NoError(t, store.Delete(taken.ID, "bob"))
+	restored, err := store.Restore(vlan10.ID)
+	require.NoError(t, err)
+	require.Equal(t, vlan10, restored)
+	requireVLANs(t, store, vlan10, vlan20)
+	require.Equal(t, []EventType{EventCreated, EventDeleted, EventCreated}, eventTypes(events))
+	_, err = store.Restore(vlan10.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Purged VLANs are gone for good
+	require.NoError(t, store.Delete(vlan20.ID, "bob"))
+	purged, err := store.Purge(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, purged)
+	purged, err = store.Purge(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+	require.Empty(t, store.Trash())
+	require.NoError(t, store.Close())
+	require.Empty(t, openStore(t, path).Trash())
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
@@ -0,0 +1,94 @@
+package vlan
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// vidKey identifies the VLANs of a VID in a VRF, VIDs are unique per VRF.
+type vidKey struct {
+	vrf string
+	vid uint16
+}
+
+// GetByVID returns the VLANs of a VRF, or of the global routing table if vrf is empty, with the given VID. There is
+// more than one only in older store files, from before VIDs were unique per VRF.
+func (s *Store) GetByVID(vrf string, vid uint16) []VLAN {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.vlansOf(s.idsByVID[vidKey{vrf, vid}])
+}
+
+// SaveByVID creates the VLAN of its VID in its VRF, or replaces it if it exists, keeping its ID. check is called
+// with the VLAN that is replaced, or nil, and the VLAN is not saved if it returns an error. It returns the saved
+// VLAN and whether it was created.
+func (s *Store) SaveByVID(vlan VLAN, check func(existing *VLAN) error) (VLAN, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing *VLAN
+	switch ids := s.idsByVID[vidKey{vlan.VRF, vlan.VID}]; len(ids) {
+	case 0:
+		vlan.ID = uuid.New()
+	case 1:
+		found := s.vlansByID[ids[0]]
+		existing, vlan.ID = &found, found.ID
+	default:
+		return VLAN{}, false, fmt.Errorf("%w: %d vlans have vid %d, use their ids", ErrDuplicateVID, len(ids), vlan.VID)
+	}
+	if check != nil {
+		if err := check(existing); err != nil {
+			return VLAN{}, false, err
+		}
+	}
+	vlan, err := s.save(vlan)
+	return vlan, existing == nil, err
+}
+
+// checkVID checks that no other VLAN in the same VRF uses the VID of a VLAN, see checkPut.
+func (s *Store) checkVID(vlan VLAN, changed map[uuid.UUID]VLAN) error {
+	duplicate := func(other VLAN) error {
+		return fmt.Errorf("%w: vid %d is used by vlan %s", ErrDuplicateVID, vlan.VID, other.Name)
+	}
+	for _, id := range s.idsByVID[vidKey{vlan.VRF, vlan.VID}] {
+		if _, ok := changed[id]; !ok && id != vlan.ID {
+			return duplicate(s.vlansByID[id])
+		}
+	}
+	for _, other := range changed {
+		if other.ID != vlan.ID && other.Deleted == nil && other.VRF == vlan.VRF && other.VID == vlan.VID {
+			return duplicate(other)
+		}
+	}
+	return nil
+}
+
+// addToIndexes adds a stored VLAN to the prefix and VID indexes, the caller must hold the write lock.
+func (s *Store) addToIndexes(vlan VLAN) {
+	s.index.add(vlan)
+	key := vidKey{vlan.VRF, vlan.VID}
+	s.idsByVID[key] = append(s.idsByVID[key], vlan.ID)
+}
+
+// removeFromIndexes removes a VLAN that is no longer stored from the prefix and VID indexes, the caller must hold
+// the write lock.
+func (s *Store) removeFromIndexes(vlan VLAN) {
+	s.index.remove(vlan)
+	key := vidKey{vlan.VRF, vlan.VID}
+	s.idsByVID[key] = slices.DeleteFunc(s.idsByVID[key], func(id uuid.UUID) bool { return id == vlan.ID })
+	if len(s.idsByVID[key]) == 0 {
+		delete(s.idsByVID, key)
+	}
+}
+
+// buildIndexes builds the prefix and VID indexes of the stored VLANs.
+func (s *Store) buildIndexes() {
+	s.index = newPrefixIndex(s.vlansByID)
+	s.idsByVID = make(map[vidKey][]uuid.UUID, len(s.vlansByID))
+	for _, vlan := range s.vlansByID {
+		key := vidKey{vlan.VRF, vlan.VID}
+		s.idsByVID[key] = append(s.idsByVID[key], vlan.ID)
+	}
+}
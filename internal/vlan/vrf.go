@@ -0,0 +1,107 @@
+package vlan
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// WithVRFs makes the store reject VLANs bound to a VRF for which exists returns false. Without it, VRF
+// names are not checked.
+func WithVRFs(exists func(name string) bool) StoreOption {
+	return func(s *Store) {
+		s.vrfExists = exists
+	}
+}
+
+// DeleteVRF calls deleteVRF to delete a VRF unless VLANs are bound to it, including deleted VLANs, which could no
+// longer be restored. deleteVRF is called with the store locked, so that no VLAN is bound to the VRF in the
+// meantime, and must not call back into the store.
+func (s *Store) DeleteVRF(name string, deleteVRF func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, vlan := range s.vlansByID {
+		if vlan.VRF == name {
+			return fmt.Errorf("%w: vrf %s is used by vlan %s", ErrVRFInUse, name, vlan.Name)
+		}
+	}
+	for _, vlan := range s.trashByID {
+		if vlan.VRF == name {
+			return fmt.Errorf("%w: vrf %s is used by deleted vlan %s", ErrVRFInUse, name, vlan.Name)
+		}
+	}
+	return deleteVRF()
+}
+
+// checkPut checks that a VLAN may be stored: its custom fields must match the schema, its VRF must exist, and its
+// VID and subnet must not be used by another VLAN in the same VRF. VLANs in different VRFs may use the same VIDs
+// and addresses. previous is the VLAN that is replaced, or nil, and its VID and subnet are only checked if they
+// change, so that VLANs of older store files that share a VID or overlap can still be updated. changed holds the VLANs of a batch that are not
+// applied yet, with VLANs moved to the trash marked as deleted, and takes precedence over the stored VLANs. The
+// caller must hold the lock.
+func (s *Store) checkPut(vlan VLAN, previous *VLAN, changed map[uuid.UUID]VLAN) error {
+	if err := s.checkFields(vlan); err != nil {
+		return err
+	}
+	if vlan.VRF != "" && s.vrfExists != nil && !s.vrfExists(vlan.VRF) {
+		return fmt.Errorf("%w %q", ErrUnknownVRF, vlan.VRF)
+	}
+	if previous == nil || previous.VRF != vlan.VRF || previous.VID != vlan.VID {
+		if err := s.checkVID(vlan, changed); err != nil {
+			return err
+		}
+	}
+	if previous != nil && previous.VRF == vlan.VRF && previous.Subnet.Masked() == vlan.Subnet.Masked() {
+		return nil
+	}
+	overlaps := func(other VLAN) error {
+		return fmt.Errorf("%w: %s overlaps %s of vlan %s", ErrOverlap, vlan.Subnet, other.Subnet, other.Name)
+	}
+	id, ok := s.index.overlapping(vlan.VRF, vlan.Subnet, func(id uuid.UUID) bool {
+		_, ok := changed[id]
+		return ok || id == vlan.ID
+	})
+	if ok {
+		return overlaps(s.vlansByID[id])
+	}
+	for _, other := range changed {
+		if other.ID != vlan.ID && other.Deleted == nil && other.VRF == vlan.VRF &&
+			other.Subnet.Masked().Overlaps(vlan.Subnet.Masked()) {
+			return overlaps(other)
+		}
+	}
+	return nil
+}
+
+// CheckConflicts returns an ErrDuplicateVID or ErrOverlap error if VLANs in the same VRF share a VID or have
+// overlapping subnets, which the store only accepts from older store files. Exports that need unique VIDs and
+// addresses, like DHCP and DNS configuration, check their VLANs with it.
+func CheckConflicts(vlans []VLAN) error {
+	byVID := map[vidKey]VLAN{}
+	for _, vlan := range vlans {
+		key := vidKey{vlan.VRF, vlan.VID}
+		if other, ok := byVID[key]; ok {
+			return fmt.Errorf("%w: vlans %s and %s have vid %d", ErrDuplicateVID, other.Name, vlan.Name, vlan.VID)
+		}
+		byVID[key] = vlan
+	}
+
+	// In address order, a subnet that contains others overlaps the one right after it
+	vlans = slices.SortedFunc(slices.Values(vlans), func(a, b VLAN) int {
+		return cmp.Or(
+			cmp.Compare(a.VRF, b.VRF),
+			a.Subnet.Masked().Addr().Compare(b.Subnet.Masked().Addr()),
+			cmp.Compare(a.Subnet.Bits(), b.Subnet.Bits()),
+		)
+	})
+	for i := 1; i < len(vlans); i++ {
+		a, b := vlans[i-1], vlans[i]
+		if a.VRF == b.VRF && a.Subnet.Masked().Overlaps(b.Subnet.Masked()) {
+			return fmt.Errorf("%w: %s of vlan %s overlaps %s of vlan %s", ErrOverlap, a.Subnet, a.Name, b.Subnet, b.Name)
+		}
+	}
+	return nil
+}
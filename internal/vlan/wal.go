@@ -0,0 +1,392 @@
+package vlan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The store is persisted as a snapshot of all VLANs at path, and a log of the changes since the snapshot
+// at path+".log". Every change is appended to the log as a single JSON line and fsynced before it is
+// applied, so a write costs the size of the change rather than of the store. The log is compacted into a
+// new snapshot every DefaultCompactAfter records by default.
+//
+// Log records contain the full VLANs rather than the operations, so replaying records that are already
+// part of the snapshot has no effect. This makes a crash between writing a snapshot and truncating the
+// log harmless.
+
+// DefaultCompactAfter is the default number of log records after which the log is compacted.
+const DefaultCompactAfter = 1000
+
+type logOpType string
+
+const (
+	logPut logOpType = "put"
+	// logTrash moves a VLAN to the trash, logDelete removes it for good, from the trash as well.
+	logTrash  logOpType = "trash"
+	logDelete logOpType = "delete"
+)
+
+type logOp struct {
+	Type logOpType `json:"type"`
+	ID   uuid.UUID `json:"id"`
+	VLAN *VLAN     `json:"vlan,omitempty"`
+}
+
+// logRecord is a line of the log, its operations are applied together. Records are migrated like
+// snapshots, records without a version are from version 1.
+type logRecord struct {
+	Version int     `json:"version,omitempty"`
+	Ops     []logOp `json:"ops"`
+}
+
+func putOp(vlan VLAN) logOp {
+	return logOp{Type: logPut, ID: vlan.ID, VLAN: &vlan}
+}
+
+func trashOp(vlan VLAN) logOp {
+	return logOp{Type: logTrash, ID: vlan.ID, VLAN: &vlan}
+}
+
+func deleteOp(id uuid.UUID) logOp {
+	return logOp{Type: logDelete, ID: id}
+}
+
+func (s *Store) logPath() string {
+	return s.path + ".log"
+}
+
+// openLog opens the log for appending and applies its records to the snapshot. An incomplete last record
+// was never acknowledged, because the process stopped while writing it, so it is truncated.
+func (s *Store) openLog() error {
+	logFile, err := os.OpenFile(s.logPath(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", s.logPath(), err)
+	}
+	if err := syncDir(filepath.Dir(s.logPath())); err != nil {
+		_ = logFile.Close()
+		return err
+	}
+
+	reader := bufio.NewReader(logFile)
+	offset, records := int64(0), 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) == 0 && readErr == io.EOF {
+			break
+		}
+		if readErr != nil && readErr != io.EOF {
+			_ = logFile.Close()
+			return fmt.Errorf("failed to read %v: %w", s.logPath(), readErr)
+		}
+
+		// Only a record that is cut short is incomplete, other errors are those of complete records
+		record, err := s.decodeLogRecord(line)
+		syntaxErr := &json.SyntaxError{}
+		if err != nil && readErr != io.EOF && !errors.As(err, &syntaxErr) {
+			_ = logFile.Close()
+			return fmt.Errorf("failed to load %v at offset %d: %w", s.logPath(), offset, err)
+		}
+		if err != nil || readErr == io.EOF {
+			if _, err := reader.Peek(1); err != io.EOF {
+				_ = logFile.Close()
+				return fmt.Errorf("corrupt record in %v at offset %d", s.logPath(), offset)
+			}
+			log.Printf("truncating incomplete record in %v at offset %d", s.logPath(), offset)
+			if err := logFile.Truncate(offset); err != nil {
+				_ = logFile.Close()
+				return fmt.Errorf("failed to truncate %v: %w", s.logPath(), err)
+			}
+			break
+		}
+		if err := s.replay(record); err != nil {
+			_ = logFile.Close()
+			return fmt.Errorf("invalid record in %v at offset %d: %w", s.logPath(), offset, err)
+		}
+		offset += int64(len(line))
+		records++
+	}
+
+	s.log = logFile
+	s.logSize = offset
+	s.logRecords = records
+	return nil
+}
+
+// decodeLogRecord decrypts and decodes a log record, migrating its VLANs from older versions.
+func (s *Store) decodeLogRecord(line []byte) (logRecord, error) {
+	line, stale, err := s.unseal(line, logAAD)
+	if err != nil {
+		return logRecord{}, err
+	}
+	record, err := parseLogRecord(line)
+	if err == nil && stale {
+		s.reencrypt = true
+	}
+	return record, err
+}
+
+// parseLogRecord decodes a plaintext log record, migrating its VLANs from older versions.
+func parseLogRecord(line []byte) (logRecord, error) {
+	current := logRecord{}
+	if err := json.Unmarshal(line, &current); err != nil {
+		return logRecord{}, err
+	}
+	version := max(current.Version, 1)
+	if version == SchemaVersion {
+		return current, nil
+	}
+	if version > SchemaVersion {
+		return logRecord{}, fmt.Errorf("%w %d, the latest supported version is %d", ErrUnsupportedVersion, version, SchemaVersion)
+	}
+
+	record := struct {
+		Ops []struct {
+			Type logOpType       `json:"type"`
+			ID   uuid.UUID       `json:"id"`
+			VLAN json.RawMessage `json:"vlan"`
+		} `json:"ops"`
+	}{}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return logRecord{}, err
+	}
+	vlans := []json.RawMessage{}
+	for _, op := range record.Ops {
+		if op.VLAN != nil {
+			vlans = append(vlans, op.VLAN)
+		}
+	}
+	doc, err := versionDocument(version, vlans)
+	if err != nil {
+		return logRecord{}, err
+	}
+	if doc, err = migrate(version, doc); err != nil {
+		return logRecord{}, err
+	}
+	migrated := snapshot{}
+	if err := json.Unmarshal(doc, &migrated); err != nil {
+		return logRecord{}, err
+	}
+
+	decoded := logRecord{Version: SchemaVersion, Ops: make([]logOp, len(record.Ops))}
+	for i, op := range record.Ops {
+		decoded.Ops[i] = logOp{Type: op.Type, ID: op.ID}
+		if op.VLAN != nil {
+			decoded.Ops[i].VLAN = &migrated.VLANs[0]
+			migrated.VLANs = migrated.VLANs[1:]
+		}
+	}
+	return decoded, nil
+}
+
+func (s *Store) replay(record logRecord) error {
+	for _, op := range record.Ops {
+		switch op.Type {
+		case logPut:
+			if op.VLAN == nil {
+				return errors.New("put without VLAN")
+			}
+			if errors := op.VLAN.Validate(); len(errors) > 0 {
+				return fmt.Errorf("invalid VLAN: %s", strings.Join(errors, ", "))
+			}
+			delete(s.trashByID, op.VLAN.ID)
+			s.vlansByID[op.VLAN.ID] = *op.VLAN
+		case logTrash:
+			if op.VLAN == nil || op.VLAN.Deleted == nil {
+				return errors.New("trash without deleted VLAN")
+			}
+			if errors := op.VLAN.Validate(); len(errors) > 0 {
+				return fmt.Errorf("invalid VLAN: %s", strings.Join(errors, ", "))
+			}
+			delete(s.vlansByID, op.VLAN.ID)
+			s.trashByID[op.VLAN.ID] = *op.VLAN
+		case logDelete:
+			delete(s.vlansByID, op.ID)
+			delete(s.trashByID, op.ID)
+		default:
+			return fmt.Errorf("unknown operation %q", op.Type)
+		}
+	}
+	return nil
+}
+
+// appendLog durably appends a record of the operations, the caller must hold the write lock.
+// A failed append is truncated, so that it does not corrupt the records appended after it.
+func (s *Store) appendLog(ops ...logOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(logRecord{Version: SchemaVersion, Ops: ops})
+	if err != nil {
+		return fmt.Errorf("failed to encode log record: %w", err)
+	}
+	if data, err = s.seal(data, logAAD); err != nil {
+		return fmt.Errorf("failed to encrypt log record: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := s.log.Write(data); err != nil {
+		_ = s.log.Truncate(s.logSize)
+		return fmt.Errorf("failed to write %v: %w", s.logPath(), err)
+	}
+	if err := s.log.Sync(); err != nil {
+		_ = s.log.Truncate(s.logSize)
+		return fmt.Errorf("failed to sync %v: %w", s.logPath(), err)
+	}
+	s.logSize += int64(len(data))
+	s.logRecords++
+	return nil
+}
+
+// compactIfNeeded compacts the log once it has enough records. The change that triggered it is already
+// durable, so a failure is only logged and compaction is retried after the next change.
+func (s *Store) compactIfNeeded() {
+	if s.logRecords < s.compactAfter {
+		return
+	}
+	if err := s.compact(); err != nil {
+		log.Printf("failed to compact VLAN store: %v", err)
+	}
+}
+
+// compact writes a snapshot of the current VLANs and truncates the log, the caller must hold the write lock.
+func (s *Store) compact() error {
+	if err := s.writeSnapshot(); err != nil {
+		return err
+	}
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate %v: %w", s.logPath(), err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %v: %w", s.logPath(), err)
+	}
+	s.logSize = 0
+	s.logRecords = 0
+	return nil
+}
+
+// readSnapshot decrypts and loads the snapshot, migrating it from older versions.
+func (s *Store) readSnapshot() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", s.path, err)
+	}
+	data, stale, err := s.unseal(data, snapshotAAD)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %v: %w", s.path, err)
+	}
+
+	version, err := documentVersion(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode %v: %w", s.path, err)
+	}
+	if data, err = migrate(version, data); err != nil {
+		return fmt.Errorf("failed to load %v: %w", s.path, err)
+	}
+
+	snapshot := snapshot{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode %v: %w", s.path, err)
+	}
+	if snapshot.Metadata.Count != len(snapshot.VLANs) {
+		return fmt.Errorf("invalid %v: expected %d VLANs, found %d", s.path, snapshot.Metadata.Count, len(snapshot.VLANs))
+	}
+
+	vlansByID := make(map[uuid.UUID]VLAN, len(snapshot.VLANs))
+	for _, vlan := range snapshot.VLANs {
+		if errors := vlan.Validate(); len(errors) > 0 {
+			return fmt.Errorf("invalid VLAN in %s: %s", s.path, strings.Join(errors, ", "))
+		}
+		vlansByID[vlan.ID] = vlan
+	}
+	trashByID := make(map[uuid.UUID]VLAN, len(snapshot.Trash))
+	for _, vlan := range snapshot.Trash {
+		if errors := vlan.Validate(); len(errors) > 0 || vlan.Deleted == nil {
+			return fmt.Errorf("invalid deleted VLAN in %s: %s", s.path, strings.Join(errors, ", "))
+		}
+		trashByID[vlan.ID] = vlan
+	}
+	s.vlansByID = vlansByID
+	s.trashByID = trashByID
+	s.reencrypt = s.reencrypt || stale
+	if version < SchemaVersion {
+		s.migratedFrom = version
+	}
+	return nil
+}
+
+// writeSnapshot atomically and durably replaces the snapshot with the current VLANs.
+func (s *Store) writeSnapshot() error {
+	vlansFileDir := filepath.Dir(s.path)
+	vlansFileTmp, err := os.CreateTemp(vlansFileDir, "vlans-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file in %v: %w", vlansFileDir, err)
+	}
+	defer func() {
+		_ = os.Remove(vlansFileTmp.Name())
+	}()
+
+	vlans := slices.Collect(maps.Values(s.vlansByID))
+	snapshot := snapshot{
+		Version: SchemaVersion,
+		Metadata: snapshotMetadata{
+			WrittenAt:    time.Now().UTC(),
+			Count:        len(vlans),
+			MigratedFrom: s.migratedFrom,
+		},
+		VLANs: vlans,
+		Trash: slices.Collect(maps.Values(s.trashByID)),
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		_ = vlansFileTmp.Close()
+		return fmt.Errorf("failed to encode %v: %w", s.path, err)
+	}
+	if data, err = s.seal(data, snapshotAAD); err != nil {
+		_ = vlansFileTmp.Close()
+		return fmt.Errorf("failed to encrypt %v: %w", s.path, err)
+	}
+	if _, err := vlansFileTmp.Write(append(data, '\n')); err != nil {
+		_ = vlansFileTmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := vlansFileTmp.Sync(); err != nil {
+		_ = vlansFileTmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+
+	if err := vlansFileTmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(vlansFileTmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace %v: %w", s.path, err)
+	}
+
+	return syncDir(vlansFileDir)
+}
+
+// syncDir makes the creation and renaming of files in a directory durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open %v: %w", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %v: %w", dir, err)
+	}
+	return nil
+}
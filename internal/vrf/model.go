@@ -0,0 +1,63 @@
+// Package vrf manages VRFs, the routing domains that VLANs are bound to so that their address space may overlap.
+package vrf
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Global is the reserved name of the global routing table, which holds VLANs that are not bound to a VRF.
+const Global = "global"
+
+type VRF struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Route distinguisher, e.g. 65000:100 or 192.0.2.1:100.
+	RD          string    `json:"rd,omitempty"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+func (v *VRF) Validate() []string {
+	errors := make([]string, 0)
+	if !namePattern.MatchString(v.Name) {
+		errors = append(errors, fmt.Sprintf("invalid vrf name %q (expected up to 64 letters, digits, '_', '.' or '-')", v.Name))
+	} else if strings.EqualFold(v.Name, Global) {
+		errors = append(errors, fmt.Sprintf("vrf name %q is reserved for the global routing table", Global))
+	}
+	if v.RD != "" && !validRD(v.RD) {
+		errors = append(errors, fmt.Sprintf("invalid route distinguisher %q (expected ASN:number or IPv4:number)", v.RD))
+	}
+	return errors
+}
+
+// validRD reports whether a route distinguisher is of one of the types of RFC 4364: a 2-byte ASN with a
+// 4-byte number, an IPv4 address with a 2-byte number, or a 4-byte ASN with a 2-byte number.
+func validRD(rd string) bool {
+	admin, assigned, ok := strings.Cut(rd, ":")
+	if !ok {
+		return false
+	}
+	if addr, err := netip.ParseAddr(admin); err == nil {
+		_, err := strconv.ParseUint(assigned, 10, 16)
+		return addr.Is4() && err == nil
+	}
+	asn, err := strconv.ParseUint(admin, 10, 32)
+	if err != nil {
+		return false
+	}
+	bits := 32
+	if asn > 0xffff {
+		bits = 16
+	}
+	_, err = strconv.ParseUint(assigned, 10, bits)
+	return err == nil
+}
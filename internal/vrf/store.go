@@ -0,0 +1,137 @@
+package vrf
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/jsonfile"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+// Store manages a JSON file to persist VRFs.
+type Store struct {
+	path     string
+	vrfsByID map[uuid.UUID]VRF
+	mu       sync.RWMutex
+}
+
+func NewStore(path string) (*Store, error) {
+	store := &Store{
+		path: path,
+	}
+
+	// store file does not exist
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		store.vrfsByID = make(map[uuid.UUID]VRF, 0)
+		if err := store.writeVRFs(); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	// store file exists
+	if err := store.readVRFs(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// List returns the VRFs ordered by name.
+func (s *Store) List() []VRF {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.SortedFunc(maps.Values(s.vrfsByID), func(a, b VRF) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+func (s *Store) Get(id uuid.UUID) *VRF {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if vrf, ok := s.vrfsByID[id]; ok {
+		return &vrf
+	}
+	return nil
+}
+
+func (s *Store) GetByName(name string) *VRF {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, vrf := range s.vrfsByID {
+		if vrf.Name == name {
+			return &vrf
+		}
+	}
+	return nil
+}
+
+// Exists reports whether a VRF of the given name exists.
+func (s *Store) Exists(name string) bool {
+	return s.GetByName(name) != nil
+}
+
+// Save saves a VRF, names and route distinguishers must be unique.
+func (s *Store) Save(vrf VRF) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, other := range s.vrfsByID {
+		if other.ID == vrf.ID {
+			continue
+		}
+		if other.Name == vrf.Name {
+			return fmt.Errorf("vrf %s %w", vrf.Name, ErrAlreadyExists)
+		}
+		if vrf.RD != "" && other.RD == vrf.RD {
+			return fmt.Errorf("route distinguisher %s of vrf %s %w", vrf.RD, other.Name, ErrAlreadyExists)
+		}
+	}
+	s.vrfsByID[vrf.ID] = vrf
+	return s.writeVRFs()
+}
+
+func (s *Store) Delete(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.vrfsByID[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.vrfsByID, id)
+	return s.writeVRFs()
+}
+
+func (s *Store) readVRFs() error {
+	vrfs := []VRF{}
+	if err := jsonfile.Read(s.path, &vrfs); err != nil {
+		return err
+	}
+
+	vrfsByID := make(map[uuid.UUID]VRF, len(vrfs))
+	for _, vrf := range vrfs {
+		if errors := vrf.Validate(); len(errors) > 0 {
+			return fmt.Errorf("invalid vrf in %s: %s", s.path, strings.Join(errors, ", "))
+		}
+		vrfsByID[vrf.ID] = vrf
+	}
+	s.vrfsByID = vrfsByID
+	return nil
+}
+
+func (s *Store) writeVRFs() error {
+	return jsonfile.Write(s.path, slices.Collect(maps.Values(s.vrfsByID)))
+}
@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/vlan"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+	// HeaderTimestamp is the Unix time of a delivery attempt in seconds, which the signature covers.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+)
+
+// SignatureTolerance is how far the timestamp of a delivery may be off the time of the receiver, so that a
+// recorded delivery cannot be replayed later.
+const SignatureTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("webhook timestamp is outside the tolerance")
+)
+
+type Options struct {
+	Workers   int
+	QueueSize int
+	// Attempts per delivery before it is moved to the dead-letter list.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout of a single delivery attempt.
+	Timeout time.Duration
+	// Deliveries kept per subscription, and in the dead-letter list.
+	HistorySize    int
+	DeadLetterSize int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Workers:        4,
+		QueueSize:      1000,
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Minute,
+		Timeout:        10 * time.Second,
+		HistorySize:    100,
+		DeadLetterSize: 1000,
+	}
+}
+
+// Dispatcher delivers VLAN events to webhook subscriptions in the background.
+type Dispatcher struct {
+	store  *Store
+	opts   Options
+	client *http.Client
+	queue  chan *Delivery
+	// Clock and timer of retries, replaced in tests.
+	now       func() time.Time
+	afterFunc func(time.Duration, func())
+
+	mu          sync.Mutex
+	history     map[uuid.UUID][]*Delivery
+	deadLetters []*Delivery
+}
+
+func NewDispatcher(store *Store, opts Options) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  make(chan *Delivery, opts.QueueSize),
+		now:    time.Now,
+		afterFunc: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+		history: map[uuid.UUID][]*Delivery{},
+	}
+}
+
+// Sign returns the value of the signature header for a delivery body sent with the value of the timestamp header,
+// the HMAC of the timestamp, a dot and the body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery received at now, and that its timestamp is within SignatureTolerance.
+func Verify(secret, timestamp, signature string, body []byte, now time.Time) error {
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if sent := time.Unix(unix, 0); sent.Before(now.Add(-SignatureTolerance)) || sent.After(now.Add(SignatureTolerance)) {
+		return ErrExpiredSignature
+	}
+	return nil
+}
+
+// Run delivers queued events until ctx is cancelled. It then attempts the deliveries still queued once more,
+// without scheduling retries, and returns when they are done. Attempts in progress are not interrupted.
+func (d *Dispatcher) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for range d.opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					d.drain(ctx)
+					return
+				case delivery := <-d.queue:
+					d.attempt(ctx, delivery)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// drain attempts queued deliveries until the queue is empty.
+func (d *Dispatcher) drain(ctx context.Context) {
+	for {
+		select {
+		case delivery := <-d.queue:
+			d.attempt(ctx, delivery)
+		default:
+			return
+		}
+	}
+}
+
+// Notify queues deliveries of an event to all matching subscriptions, it never blocks.
+func (d *Dispatcher) Notify(event vlan.Event) {
+	for _, subscription := range d.store.List() {
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+
+		delivery := &Delivery{
+			ID:             uuid.New(),
+			SubscriptionID: subscription.ID,
+			Event:          event,
+			Status:         DeliveryPending,
+			CreatedAt:      d.now().UTC(),
+		}
+		d.mu.Lock()
+		history := append(d.history[subscription.ID], delivery)
+		if len(history) > d.opts.HistorySize {
+			history = slices.Delete(history, 0, len(history)-d.opts.HistorySize)
+		}
+		d.history[subscription.ID] = history
+		d.mu.Unlock()
+
+		d.enqueue(delivery)
+	}
+}
+
+// Deliveries returns the most recent deliveries of a subscription, newest first.
+func (d *Dispatcher) Deliveries(subscriptionID uuid.UUID) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return copyDeliveries(d.history[subscriptionID])
+}
+
+// DeadLetters returns deliveries that exhausted their attempts, newest first.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return copyDeliveries(d.deadLetters)
+}
+
+// Retry moves a delivery from the dead-letter list back to the queue.
+func (d *Dispatcher) Retry(deliveryID uuid.UUID) (Delivery, error) {
+	d.mu.Lock()
+	i := slices.IndexFunc(d.deadLetters, func(delivery *Delivery) bool { return delivery.ID == deliveryID })
+	if i < 0 {
+		d.mu.Unlock()
+		return Delivery{}, ErrNotFound
+	}
+	delivery := d.deadLetters[i]
+	d.deadLetters = slices.Delete(d.deadLetters, i, i+1)
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	retried := *delivery
+	d.mu.Unlock()
+
+	d.enqueue(delivery)
+	return retried, nil
+}
+
+// Forget drops the delivery history of a deleted subscription.
+func (d *Dispatcher) Forget(subscriptionID uuid.UUID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.history, subscriptionID)
+}
+
+func (d *Dispatcher) enqueue(delivery *Delivery) {
+	select {
+	case d.queue <- delivery:
+	default:
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.kill(delivery, "delivery queue is full")
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	subscription := d.store.Get(delivery.SubscriptionID)
+	responseStatus, err := 0, errors.New("subscription was deleted")
+	if subscription != nil {
+		// Attempts are bounded by the client timeout, and finish when ctx is cancelled during shutdown
+		responseStatus, err = d.send(context.WithoutCancel(ctx), subscription, delivery)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery.Attempts++
+	delivery.ResponseStatus = responseStatus
+	delivery.NextAttemptAt = nil
+	if err == nil {
+		deliveredAt := d.now().UTC()
+		delivery.Status = DeliveryDelivered
+		delivery.DeliveredAt = &deliveredAt
+		delivery.LastError = ""
+		return
+	}
+
+	if subscription == nil || delivery.Attempts >= d.opts.MaxAttempts {
+		d.kill(delivery, err.Error())
+		return
+	}
+
+	backoff := d.opts.backoff(delivery.Attempts)
+	nextAttemptAt := d.now().UTC().Add(backoff)
+	delivery.LastError = err.Error()
+	delivery.NextAttemptAt = &nextAttemptAt
+	if ctx.Err() != nil {
+		return
+	}
+	d.afterFunc(backoff, func() {
+		if ctx.Err() == nil {
+			d.enqueue(delivery)
+		}
+	})
+}
+
+// backoff returns the delay before the retry after the given number of attempts, which doubles from the initial
+// backoff up to the maximum backoff, without overflowing for any number of attempts.
+func (o Options) backoff(attempts int) time.Duration {
+	backoff := o.InitialBackoff
+	for range attempts - 1 {
+		if backoff >= o.MaxBackoff/2 {
+			return o.MaxBackoff
+		}
+		backoff *= 2
+	}
+	return min(backoff, o.MaxBackoff)
+}
+
+// kill moves a delivery to the dead-letter list, the caller must hold the lock.
+func (d *Dispatcher) kill(delivery *Delivery, reason string) {
+	log.Printf("webhook delivery %v to subscription %v failed: %s", delivery.ID, delivery.SubscriptionID, reason)
+	delivery.Status = DeliveryDead
+	delivery.LastError = reason
+	d.deadLetters = append(d.deadLetters, delivery)
+	if len(d.deadLetters) > d.opts.DeadLetterSize {
+		d.deadLetters = slices.Delete(d.deadLetters, 0, len(d.deadLetters)-d.opts.DeadLetterSize)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, subscription *Subscription, delivery *Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(delivery.Event.Type))
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func copyDeliveries(deliveries []*Delivery) []Delivery {
+	copies := make([]Delivery, len(deliveries))
+	for i, delivery := range deliveries {
+		copies[len(deliveries)-1-i] = *delivery
+	}
+	return copies
+}
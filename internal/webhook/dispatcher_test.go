@@ -0,0 +1,334 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"net-admin-api/internal/vlan"
+)
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"id":1}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1704067200.{"id":1}`))
+
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), Sign("secret", "1704067200", body))
+	require.NotEqual(t, Sign("secret", "1704067200", body), Sign("other", "1704067200", body))
+	require.NotEqual(t, Sign("secret", "1704067200", body), Sign("secret", "1704067201", body))
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"id":1}`)
+	sent := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timestamp := strconv.FormatInt(sent.Unix(), 10)
+	signature := Sign("secret", timestamp, body)
+
+	require.NoError(t, Verify("secret", timestamp, signature, body, sent))
+	require.NoError(t, Verify("secret", timestamp, signature, body, sent.Add(SignatureTolerance)))
+	require.NoError(t, Verify("secret", timestamp, signature, body, sent.Add(-SignatureTolerance)))
+	// Replays outside the tolerance are rejected, as is a timestamp of another delivery
+	require.ErrorIs(t, Verify("secret", timestamp, signature, body, sent.Add(SignatureTolerance+time.Second)), ErrExpiredSignature)
+	require.ErrorIs(t, Verify("secret", "1704067201", signature, body, sent), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("other", timestamp, signature, body, sent), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", timestamp, signature, []byte(`{"id":2}`), sent), ErrInvalidSignature)
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	t.Parallel()
+	transport := &testTransport{}
+	dispatcher, subscription := newTestDispatcher(t, transport, DefaultOptions())
+	event := vlan.Event{ID: 1, Type: vlan.EventCreated, VLAN: vlan.VLAN{ID: uuid.New(), VID: 10}}
+
+	// Deliveries are queued and sent in the background
+	dispatcher.Notify(event)
+	dispatcher.Notify(vlan.Event{ID: 2, Type: vlan.EventDeleted})
+	require.Len(t, dispatcher.Deliveries(subscription.ID), 1)
+	runUntilDrained(dispatcher)
+
+	requests := transport.Requests()
+	require.Len(t, requests, 1)
+	req := requests[0]
+	require.Equal(t, subscription.URL, req.URL)
+	require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	require.Equal(t, string(vlan.EventCreated), req.Header.Get(HeaderEvent))
+	timestamp := req.Header.Get(HeaderTimestamp)
+	require.Equal(t, Sign(subscription.Secret, timestamp, req.Body), req.Header.Get(HeaderSignature))
+	require.NoError(t, Verify(subscription.Secret, timestamp, req.Header.Get(HeaderSignature), req.Body, time.Now()))
+	received := vlan.Event{}
+	require.NoError(t, json.Unmarshal(req.Body, &received))
+	require.Equal(t, event, received)
+
+	deliveries := dispatcher.Deliveries(subscription.ID)
+	require.Equal(t, deliveries[0].ID.String(), req.Header.Get(HeaderDelivery))
+	require.Equal(t, DeliveryDelivered, deliveries[0].Status)
+	require.Equal(t, 1, deliveries[0].Attempts)
+	require.Equal(t, http.StatusNoContent, deliveries[0].ResponseStatus)
+	require.NotNil(t, deliveries[0].DeliveredAt)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	t.Parallel()
+	transport := &testTransport{status: http.StatusInternalServerError}
+	opts := DefaultOptions()
+	opts.MaxAttempts = 5
+	opts.InitialBackoff = time.Second
+	opts.MaxBackoff = 3 * time.Second
+	dispatcher, subscription := newTestDispatcher(t, transport, opts)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+
+	// Retries are scheduled with doubling backoffs up to the maximum, and run once the previous attempt is done
+	backoffs := []time.Duration{}
+	retries := []func(){}
+	dispatcher.afterFunc = func(backoff time.Duration, retry func()) {
+		backoffs = append(backoffs, backoff)
+		retries = append(retries, retry)
+	}
+	dispatcher.Notify(vlan.Event{ID: 1, Type: vlan.EventUpdated})
+	for attempt := 1; attempt < opts.MaxAttempts; attempt++ {
+		attemptNext(dispatcher)
+		delivery := dispatcher.Deliveries(subscription.ID)[0]
+		require.Equal(t, DeliveryPending, delivery.Status)
+		require.Equal(t, attempt, delivery.Attempts)
+		require.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+		require.Contains(t, delivery.LastError, "500")
+		require.Equal(t, now.Add(backoffs[attempt-1]), *delivery.NextAttemptAt)
+		retries[attempt-1]()
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}, backoffs)
+
+	// The last attempt moves the delivery to the dead-letter list
+	attemptNext(dispatcher)
+	require.Len(t, transport.Requests(), opts.MaxAttempts)
+	require.Len(t, backoffs, opts.MaxAttempts-1)
+	deadLetters := dispatcher.DeadLetters()
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, DeliveryDead, deadLetters[0].Status)
+	require.Nil(t, deadLetters[0].NextAttemptAt)
+	require.Equal(t, deadLetters, dispatcher.Deliveries(subscription.ID))
+}
+
+func TestOptions_Backoff(t *testing.T) {
+	t.Parallel()
+	opts := DefaultOptions()
+	require.Equal(t, time.Second, opts.backoff(1))
+	require.Equal(t, 4*time.Second, opts.backoff(3))
+	// Many attempts do not overflow, even with the largest maximum
+	require.Equal(t, opts.MaxBackoff, opts.backoff(100))
+	opts.MaxBackoff = math.MaxInt64
+	require.Equal(t, time.Duration(math.MaxInt64), opts.backoff(1000))
+	require.Equal(t, time.Second<<30, opts.backoff(31))
+}
+
+func TestDispatcher_DeadLetters(t *testing.T) {
+	t.Parallel()
+	transport := &testTransport{err: errors.New("connection refused")}
+	opts := DefaultOptions()
+	opts.MaxAttempts = 1
+	opts.QueueSize = 2
+	opts.DeadLetterSize = 2
+	dispatcher, subscription := newTestDispatcher(t, transport, opts)
+
+	// Deliveries that do not fit in the queue are dead immediately
+	for i := range 3 {
+		dispatcher.Notify(vlan.Event{ID: uint64(i + 1), Type: vlan.EventCreated})
+	}
+	deadLetters := dispatcher.DeadLetters()
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, uint64(3), deadLetters[0].Event.ID)
+	require.Equal(t, "delivery queue is full", deadLetters[0].LastError)
+	require.Zero(t, deadLetters[0].Attempts)
+
+	// The dead-letter list keeps the most recent deliveries, newest first
+	runUntilDrained(dispatcher)
+	deadLetters = dispatcher.DeadLetters()
+	require.Len(t, deadLetters, 2)
+	require.Equal(t, uint64(2), deadLetters[0].Event.ID)
+	require.Equal(t, uint64(1), deadLetters[1].Event.ID)
+	require.Contains(t, deadLetters[0].LastError, "connection refused")
+
+	// Retried deliveries start over
+	_, err := dispatcher.Retry(uuid.New())
+	require.ErrorIs(t, err, ErrNotFound)
+	transport.fail(0, nil)
+	retried, err := dispatcher.Retry(deadLetters[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, DeliveryPending, retried.Status)
+	require.Zero(t, retried.Attempts)
+	require.Len(t, dispatcher.DeadLetters(), 1)
+	runUntilDrained(dispatcher)
+	require.Len(t, dispatcher.DeadLetters(), 1)
+	require.Equal(t, DeliveryDelivered, dispatcher.Deliveries(subscription.ID)[1].Status)
+
+	// Deliveries to deleted subscriptions are dead without further attempts
+	dispatcher.Notify(vlan.Event{ID: 4, Type: vlan.EventCreated})
+	require.NoError(t, dispatcher.store.Delete(subscription.ID))
+	dispatcher.Forget(subscription.ID)
+	runUntilDrained(dispatcher)
+	require.Equal(t, "subscription was deleted", dispatcher.DeadLetters()[0].LastError)
+	require.Empty(t, dispatcher.Deliveries(subscription.ID))
+}
+
+func TestDispatcher_Shutdown(t *testing.T) {
+	t.Parallel()
+	transport := &testTransport{status: http.StatusServiceUnavailable}
+	dispatcher, subscription := newTestDispatcher(t, transport, DefaultOptions())
+	dispatcher.afterFunc = func(time.Duration, func()) {
+		t.Error("retry scheduled after shutdown")
+	}
+	for i := range 10 {
+		dispatcher.Notify(vlan.Event{ID: uint64(i + 1), Type: vlan.EventCreated})
+	}
+
+	// Queued deliveries are attempted once when the dispatcher stops, without retries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dispatcher.Run(ctx)
+	require.Len(t, transport.Requests(), 10)
+	for _, delivery := range dispatcher.Deliveries(subscription.ID) {
+		require.Equal(t, 1, delivery.Attempts)
+		require.Equal(t, DeliveryPending, delivery.Status)
+	}
+}
+
+func TestDispatcher_ShutdownDuringAttempt(t *testing.T) {
+	t.Parallel()
+	transport := &testTransport{}
+	transport.block = make(chan struct{})
+	dispatcher, subscription := newTestDispatcher(t, transport, DefaultOptions())
+	dispatcher.Notify(vlan.Event{ID: 1, Type: vlan.EventCreated})
+
+	// Attempts in progress are not interrupted by the shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return transport.Started() == 1 }, 5*time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("dispatcher stopped during an attempt")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(transport.block)
+	<-done
+	require.Equal(t, DeliveryDelivered, dispatcher.Deliveries(subscription.ID)[0].Status)
+}
+
+// attemptNext attempts the next queued delivery.
+func attemptNext(dispatcher *Dispatcher) {
+	dispatcher.attempt(context.Background(), <-dispatcher.queue)
+}
+
+// runUntilDrained runs the dispatcher until its queue is empty.
+func runUntilDrained(dispatcher *Dispatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dispatcher.Run(ctx)
+}
+
+func newTestDispatcher(t *testing.T, transport *testTransport, opts Options) (*Dispatcher, Subscription) {
+	t.Helper()
+	store, err := NewStore(filepath.Join(t.TempDir(), "webhooks.json"))
+	require.NoError(t, err)
+	subscription := Subscription{
+		ID:        uuid.New(),
+		URL:       "http://receiver.example/hook",
+		Events:    []vlan.EventType{vlan.EventCreated, vlan.EventUpdated},
+		Secret:    "secret",
+		CreatedAt: time.Now().UTC(),
+	}
+	require.NoError(t, store.Save(subscription))
+
+	dispatcher := NewDispatcher(store, opts)
+	dispatcher.client.Transport = transport
+	return dispatcher, subscription
+}
+
+type receivedRequest struct {
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// testTransport records requests and answers them with a fixed status or error.
+type testTransport struct {
+	mu       sync.Mutex
+	status   int
+	err      error
+	block    chan struct{}
+	started  int
+	requests []receivedRequest
+}
+
+func (tt *testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	tt.mu.Lock()
+	tt.started++
+	block := tt.block
+	tt.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.requests = append(tt.requests, receivedRequest{URL: req.URL.String(), Header: req.Header.Clone(), Body: body})
+	if tt.err != nil {
+		return nil, tt.err
+	}
+	status := tt.status
+	if status == 0 {
+		status = http.StatusNoContent
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+// fail changes the response of later requests.
+func (tt *testTransport) fail(status int, err error) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.status = status
+	tt.err = err
+}
+
+func (tt *testTransport) Started() int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.started
+}
+
+func (tt *testTransport) Requests() []receivedRequest {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.requests
+}
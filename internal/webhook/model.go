@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/vlan"
+)
+
+// Subscription registers a URL to receive signed VLAN events.
+type Subscription struct {
+	ID  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+	// Event types delivered to the subscription, all events if empty.
+	Events []vlan.EventType `json:"events,omitempty"`
+	// Key of the HMAC-SHA256 signature sent with each delivery.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (s *Subscription) Validate() []string {
+	errors := make([]string, 0)
+	if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errors = append(errors, fmt.Sprintf("invalid webhook url %q (expected absolute http or https url)", s.URL))
+	}
+	for _, eventType := range s.Events {
+		if !slices.Contains(vlan.EventTypes, eventType) {
+			errors = append(errors, fmt.Sprintf("unknown event type %q (expected one of %v)", eventType, vlan.EventTypes))
+		}
+	}
+	if s.Secret == "" {
+		errors = append(errors, "secret must not be empty")
+	}
+	return errors
+}
+
+// Matches reports whether the subscription receives events of the given type.
+func (s *Subscription) Matches(eventType vlan.EventType) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, eventType)
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// Dead deliveries have exhausted their attempts and are kept in the dead-letter list.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// Delivery is the delivery of a single event to a single subscription.
+type Delivery struct {
+	ID             uuid.UUID      `json:"id"`
+	SubscriptionID uuid.UUID      `json:"subscriptionId"`
+	Event          vlan.Event     `json:"event"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	LastError      string         `json:"lastError,omitempty"`
+	ResponseStatus int            `json:"responseStatus,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	NextAttemptAt  *time.Time     `json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time     `json:"deliveredAt,omitempty"`
+}
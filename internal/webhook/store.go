@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"net-admin-api/internal/jsonfile"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Store manages a JSON file to persist webhook subscriptions.
+type Store struct {
+	path              string
+	subscriptionsByID map[uuid.UUID]Subscription
+	mu                sync.RWMutex
+}
+
+func NewStore(path string) (*Store, error) {
+	store := &Store{
+		path: path,
+	}
+
+	// store file does not exist
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		store.subscriptionsByID = make(map[uuid.UUID]Subscription, 0)
+		if err := store.writeSubscriptions(); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	// store file exists
+	if err := store.readSubscriptions(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// List returns subscriptions ordered by creation time.
+func (s *Store) List() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.SortedFunc(maps.Values(s.subscriptionsByID), func(a, b Subscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+func (s *Store) Get(id uuid.UUID) *Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if subscription, ok := s.subscriptionsByID[id]; ok {
+		return &subscription
+	}
+	return nil
+}
+
+func (s *Store) Save(subscription Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptionsByID[subscription.ID] = subscription
+	return s.writeSubscriptions()
+}
+
+func (s *Store) Delete(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptionsByID[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.subscriptionsByID, id)
+	return s.writeSubscriptions()
+}
+
+func (s *Store) readSubscriptions() error {
+	subscriptions := []Subscription{}
+	if err := jsonfile.Read(s.path, &subscriptions); err != nil {
+		return err
+	}
+
+	subscriptionsByID := make(map[uuid.UUID]Subscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if errors := subscription.Validate(); len(errors) > 0 {
+			return fmt.Errorf("invalid webhook subscription in %s: %s", s.path, strings.Join(errors, ", "))
+		}
+		subscriptionsByID[subscription.ID] = subscription
+	}
+	s.subscriptionsByID = subscriptionsByID
+	return nil
+}
+
+func (s *Store) writeSubscriptions() error {
+	return jsonfile.Write(s.path, slices.Collect(maps.Values(s.subscriptionsByID)))
+}